@@ -1,6 +1,7 @@
 package main
 
 import (
+	"benchutil/internal/history"
 	"benchutil/internal/load"
 	"benchutil/internal/meet"
 	"benchutil/pkg/cli"
@@ -11,12 +12,14 @@ import (
 )
 
 func main() {
-	app, err := cli.NewApp(meet.Command(), load.New())
+	app, err := cli.NewApp(meet.Command(), load.New(), load.NewGrpc(), load.NewWorker(), load.NewCoordinator(), load.NewDaemon(), load.NewSweep(), history.New())
 	if err != nil {
 		log.Fatalf("init app: %v", err)
 	}
 	ctx := context.Background()
 	if err = app.Run(ctx); err != nil {
-		fmt.Fprintf(os.Stderr, "run app: %v", err)
+		fmt.Fprintf(os.Stderr, "run app: %v\n", err)
+		// ненулевой код, чтобы CI падал на ошибке и непройденных порогах
+		os.Exit(1)
 	}
 }
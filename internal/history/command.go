@@ -0,0 +1,335 @@
+package history
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/formatter"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+func historyFlag(dir *string) cli.CmdFlag {
+	return cli.StringFlag{
+		Name:        "history",
+		Destination: dir,
+		Default:     DefaultDir(),
+		Usage:       "Директория с историей нагрузок",
+	}
+}
+
+func idFlag(id *string) cli.CmdFlag {
+	return cli.StringFlag{
+		Name:        "id",
+		Destination: id,
+		Usage:       "Идентификатор нагрузки",
+	}
+}
+
+// New команда history с вложенными командами list, show, tag, delete и trend
+func New() cli.Command {
+	return cli.Command{
+		Name:        "history",
+		Description: "Работает с историей нагрузок",
+		Subcommands: []cli.Command{newList(), newShow(), newTag(), newDelete(), newTrend()},
+	}
+}
+
+func newList() cli.Command {
+	var dir, scenario, tag string
+	return cli.Command{
+		Name:        "list",
+		Description: "Выводит сохранённые нагрузки",
+		Flags: []cli.CmdFlag{
+			historyFlag(&dir),
+			cli.StringFlag{
+				Name:        "scenario",
+				Destination: &scenario,
+				Usage:       "Показать только нагрузки сценария",
+			},
+			cli.StringFlag{
+				Name:        "tag",
+				Destination: &tag,
+				Usage:       "Показать только нагрузки с меткой",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			store, err := Open(dir)
+			if err != nil {
+				return err
+			}
+
+			runs, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			return writeList(os.Stdout, filter(runs, scenario, tag))
+		},
+	}
+}
+
+func newShow() cli.Command {
+	var dir, id, output string
+	return cli.Command{
+		Name:        "show",
+		Description: "Выводит сохранённую нагрузку с отчётом",
+		Flags: []cli.CmdFlag{
+			historyFlag(&dir),
+			idFlag(&id),
+			cli.StringFlag{
+				Name:        "o",
+				Destination: &output,
+				Default:     formatter.Human,
+				Usage:       "Формат вывода отчёта",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			if !formatter.Supported(output) {
+				return fmt.Errorf("invalid output format - %s", output)
+			}
+
+			store, err := Open(dir)
+			if err != nil {
+				return err
+			}
+
+			run, err := store.Get(id)
+			if err != nil {
+				return err
+			}
+
+			return writeRun(os.Stdout, run, output)
+		},
+	}
+}
+
+func newTag() cli.Command {
+	var dir, id, tags string
+	return cli.Command{
+		Name:        "tag",
+		Description: "Добавляет метки сохранённой нагрузке",
+		Flags: []cli.CmdFlag{
+			historyFlag(&dir),
+			idFlag(&id),
+			cli.StringFlag{
+				Name:        "tag",
+				Destination: &tags,
+				Usage:       "Метки через запятую",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			list := splitTags(tags)
+			if len(list) == 0 {
+				return errors.New("empty tags")
+			}
+
+			store, err := Open(dir)
+			if err != nil {
+				return err
+			}
+
+			run, err := store.Tag(id, list...)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%s: %s\n", run.ID, strings.Join(run.Tags, ","))
+			return nil
+		},
+	}
+}
+
+func newDelete() cli.Command {
+	var dir, id string
+	return cli.Command{
+		Name:        "delete",
+		Description: "Удаляет сохранённую нагрузку",
+		Flags:       []cli.CmdFlag{historyFlag(&dir), idFlag(&id)},
+		Action: func(ctx context.Context) error {
+			store, err := Open(dir)
+			if err != nil {
+				return err
+			}
+
+			return store.Delete(id)
+		},
+	}
+}
+
+func newTrend() cli.Command {
+	var (
+		dir, scenario string
+		last          int
+	)
+	return cli.Command{
+		Name:        "trend",
+		Description: "Показывает изменение p99 и rps сценария от нагрузки к нагрузке",
+		Flags: []cli.CmdFlag{
+			historyFlag(&dir),
+			cli.StringFlag{
+				Name:        "scenario",
+				Destination: &scenario,
+				Usage:       "Название сценария",
+			},
+			cli.IntFlag{
+				Name:        "last",
+				Destination: &last,
+				Default:     10,
+				Usage:       "Количество последних нагрузок",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			if scenario == "" {
+				return errors.New("empty scenario")
+			}
+
+			store, err := Open(dir)
+			if err != nil {
+				return err
+			}
+
+			runs, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			return writeTrend(os.Stdout, Trend(runs, scenario, last))
+		},
+	}
+}
+
+// TrendPoint p99 и rps нагрузки сценария и их изменение относительно предыдущей нагрузки в процентах
+type TrendPoint struct {
+	Run       Run
+	P99       float64
+	Rps       float64
+	P99Change float64
+	RpsChange float64
+
+	// First первая нагрузка сценария, изменение не считается
+	First bool
+}
+
+// Trend последние last нагрузок сценария, изменение считается по предыдущей нагрузке в истории
+func Trend(runs []Run, scenario string, last int) []TrendPoint {
+	var points []TrendPoint
+	for _, run := range filter(runs, scenario, "") {
+		point := TrendPoint{Run: run, Rps: run.Report.Rps}
+		if run.Report.Latency != nil {
+			point.P99 = run.Report.Latency.P99
+		}
+
+		if n := len(points); n > 0 {
+			point.P99Change = change(points[n-1].P99, point.P99)
+			point.RpsChange = change(points[n-1].Rps, point.Rps)
+		} else {
+			point.First = true
+		}
+		points = append(points, point)
+	}
+
+	if last > 0 && len(points) > last {
+		points = points[len(points)-last:]
+	}
+
+	return points
+}
+
+func change(prev, cur float64) float64 {
+	if prev == 0 {
+		return 0
+	}
+
+	return (cur - prev) * 100 / prev
+}
+
+func filter(runs []Run, scenario, tag string) []Run {
+	var res []Run
+	for _, run := range runs {
+		if scenario != "" && run.Scenario != scenario {
+			continue
+		}
+		if tag != "" && !run.HasTag(tag) {
+			continue
+		}
+		res = append(res, run)
+	}
+
+	return res
+}
+
+func writeList(w io.Writer, runs []Run) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tДАТА\tСЦЕНАРИЙ\tМЕТКИ\tЗАПРОСОВ\tRPS\tP99, МС")
+	for _, run := range runs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%v\t%v\n",
+			run.ID, run.CreatedAt.Format(timeLayout), run.Scenario, strings.Join(run.Tags, ","),
+			run.Report.All, run.Report.Rps, p99(run.Report))
+	}
+
+	return tw.Flush()
+}
+
+func writeTrend(w io.Writer, points []TrendPoint) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tДАТА\tМЕТКИ\tP99, МС\tИЗМ. P99\tRPS\tИЗМ. RPS")
+	for _, p := range points {
+		p99Change, rpsChange := "", ""
+		if !p.First {
+			p99Change, rpsChange = fmt.Sprintf("%+.1f%%", p.P99Change), fmt.Sprintf("%+.1f%%", p.RpsChange)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%v\t%s\t%v\t%s\n",
+			p.Run.ID, p.Run.CreatedAt.Format(timeLayout), strings.Join(p.Run.Tags, ","),
+			p.P99, p99Change, p.Rps, rpsChange)
+	}
+
+	return tw.Flush()
+}
+
+// writeRun выводит отчёт нагрузки, сведения о нагрузке добавляются только к формату human,
+// остальные форматы должны остаться корректными документами
+func writeRun(w io.Writer, run Run, output string) error {
+	rep, err := formatter.Format(output, run.Report)
+	if err != nil {
+		return err
+	}
+	if output != formatter.Human {
+		_, err = w.Write(rep)
+		return err
+	}
+
+	fmt.Fprintf(w, "ID: %s\nДата: %s\nСценарий: %s\n", run.ID, run.CreatedAt.Format(timeLayout), run.Scenario)
+	if len(run.Tags) > 0 {
+		fmt.Fprintf(w, "Метки: %s\n", strings.Join(run.Tags, ","))
+	}
+	fmt.Fprintf(w, "Аргументы: %s\n", strings.Join(run.Args, " "))
+	fmt.Fprintf(w, "Окружение: %s %s/%s, %d cpu, %s\n\n", run.Env.Hostname, run.Env.OS, run.Env.Arch, run.Env.CPUs, run.Env.GoVersion)
+	_, err = w.Write(rep)
+
+	return err
+}
+
+func p99(rep formatter.Report) float64 {
+	if rep.Latency == nil {
+		return 0
+	}
+
+	return rep.Latency.P99
+}
+
+func splitTags(raw string) []string {
+	var tags []string
+	for _, tag := range strings.Split(raw, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,39 @@
+package history
+
+import (
+	"benchutil/pkg/formatter"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteRun(t *testing.T) {
+	run := Run{
+		ID:        "20260102-150405",
+		CreatedAt: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC),
+		Scenario:  "api",
+		Tags:      []string{"release"},
+		Args:      []string{"-n=10"},
+		Report:    formatter.Report{All: 10, Success: 10, Rps: 5},
+	}
+
+	t.Run("json is a valid document", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeRun(&buf, run, formatter.Json))
+
+		var rep formatter.Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &rep))
+		require.Equal(t, 10, rep.All)
+	})
+
+	t.Run("human with run info", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, writeRun(&buf, run, formatter.Human))
+
+		require.True(t, strings.HasPrefix(buf.String(), "ID: 20260102-150405\nДата: "))
+		require.Contains(t, buf.String(), "Метки: release\nАргументы: -n=10\n")
+	})
+}
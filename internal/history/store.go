@@ -0,0 +1,213 @@
+package history
+
+import (
+	"benchutil/pkg/formatter"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const ext = ".json"
+
+var NotFoundErr = errors.New("run not found")
+
+// Run сохранённая нагрузка
+type Run struct {
+	ID        string           `json:"id"`
+	Scenario  string           `json:"scenario"`
+	Tags      []string         `json:"tags,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Args      []string         `json:"args"`
+	Env       Environment      `json:"env"`
+	Report    formatter.Report `json:"report"`
+}
+
+// Environment машина, с которой запускалась нагрузка
+type Environment struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPUs      int    `json:"cpus"`
+	GoVersion string `json:"goVersion"`
+}
+
+// Store история нагрузок в директории, каждая нагрузка хранится в отдельном json файле
+type Store struct {
+	dir string
+}
+
+// DefaultDir директория истории по умолчанию, пустая строка если домашняя директория неизвестна
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".benchutil", "history")
+}
+
+func CurrentEnvironment() Environment {
+	hostname, _ := os.Hostname()
+	return Environment{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+func Open(dir string) (Store, error) {
+	if dir == "" {
+		return Store{}, errors.New("empty history dir")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Store{}, fmt.Errorf("create history dir: %w", err)
+	}
+
+	return Store{dir: dir}, nil
+}
+
+// Save сохраняет нагрузку, ID и CreatedAt заполняются если не заданы
+func (s Store) Save(run Run) (Run, error) {
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now()
+	}
+
+	if run.ID == "" {
+		id, err := newID(run.CreatedAt)
+		if err != nil {
+			return Run{}, err
+		}
+		run.ID = id
+	}
+
+	if err := s.write(run); err != nil {
+		return Run{}, err
+	}
+
+	return run, nil
+}
+
+// List нагрузки от старых к новым
+func (s Store) List() ([]Run, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read history dir: %w", err)
+	}
+
+	var runs []Run
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ext {
+			continue
+		}
+
+		run, err := s.Get(strings.TrimSuffix(e.Name(), ext))
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.Before(runs[j].CreatedAt)
+	})
+
+	return runs, nil
+}
+
+func (s Store) Get(id string) (Run, error) {
+	raw, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Run{}, fmt.Errorf("%w: %s", NotFoundErr, id)
+	}
+	if err != nil {
+		return Run{}, fmt.Errorf("read run %s: %w", id, err)
+	}
+
+	var run Run
+	if err = json.Unmarshal(raw, &run); err != nil {
+		return Run{}, fmt.Errorf("decode run %s: %w", id, err)
+	}
+
+	return run, nil
+}
+
+// Tag добавляет метки к нагрузке, уже существующие метки не дублируются
+func (s Store) Tag(id string, tags ...string) (Run, error) {
+	run, err := s.Get(id)
+	if err != nil {
+		return Run{}, err
+	}
+
+	for _, tag := range tags {
+		if !run.HasTag(tag) {
+			run.Tags = append(run.Tags, tag)
+		}
+	}
+
+	if err = s.write(run); err != nil {
+		return Run{}, err
+	}
+
+	return run, nil
+}
+
+func (s Store) Delete(id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", NotFoundErr, id)
+	}
+	if err != nil {
+		return fmt.Errorf("delete run %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (r Run) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Store) write(run Run) error {
+	raw, err := json.MarshalIndent(run, "", " ")
+	if err != nil {
+		return fmt.Errorf("encode run: %w", err)
+	}
+
+	if err = os.WriteFile(s.path(run.ID), raw, 0644); err != nil {
+		return fmt.Errorf("write run %s: %w", run.ID, err)
+	}
+
+	return nil
+}
+
+func (s Store) path(id string) string {
+	// id приходит из командной строки, выйти за пределы директории истории нельзя
+	return filepath.Join(s.dir, filepath.Base(id)+ext)
+}
+
+// newID идентификатор вида 20260102-150405-a1b2, сортируется по времени создания
+func newID(created time.Time) (string, error) {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("generate id: %w", err)
+	}
+
+	return created.Format("20060102-150405") + "-" + hex.EncodeToString(suffix), nil
+}
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"benchutil/pkg/formatter"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	start := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	second, err := store.Save(Run{Scenario: "api", CreatedAt: start.Add(time.Hour), Report: formatter.Report{All: 2}})
+	require.NoError(t, err)
+	first, err := store.Save(Run{Scenario: "api", CreatedAt: start, Report: formatter.Report{All: 1}})
+	require.NoError(t, err)
+	require.Regexp(t, `^20260102-150405-[0-9a-f]{4}$`, first.ID)
+
+	runs, err := store.List()
+	require.NoError(t, err)
+	require.Equal(t, []Run{first, second}, runs, "sorted by creation time")
+
+	tagged, err := store.Tag(first.ID, "release", "baseline", "release")
+	require.NoError(t, err)
+	require.Equal(t, []string{"release", "baseline"}, tagged.Tags)
+
+	got, err := store.Get(first.ID)
+	require.NoError(t, err)
+	require.Equal(t, tagged, got)
+
+	require.NoError(t, store.Delete(second.ID))
+	_, err = store.Get(second.ID)
+	require.True(t, errors.Is(err, NotFoundErr))
+	require.True(t, errors.Is(store.Delete(second.ID), NotFoundErr))
+
+	_, err = store.Get("../" + first.ID)
+	require.NoError(t, err, "id can't leave history dir")
+}
+
+func TestTrend(t *testing.T) {
+	run := func(scenario string, p99, rps float64) Run {
+		return Run{Scenario: scenario, Report: formatter.Report{Rps: rps, Latency: &formatter.LatencyStats{P99: p99}}}
+	}
+	runs := []Run{
+		run("api", 100, 50),
+		run("other", 1, 1),
+		run("api", 150, 40),
+		run("api", 75, 80),
+	}
+
+	points := Trend(runs, "api", 2)
+
+	require.Equal(t, []TrendPoint{
+		{Run: runs[2], P99: 150, Rps: 40, P99Change: 50, RpsChange: -20},
+		{Run: runs[3], P99: 75, Rps: 80, P99Change: -50, RpsChange: 100},
+	}, points)
+
+	require.True(t, Trend(runs, "api", 0)[0].First)
+	require.Empty(t, Trend(runs, "unknown", 10))
+}
@@ -0,0 +1,125 @@
+package load
+
+import (
+	"benchutil/pkg/auth"
+	"benchutil/pkg/cli"
+	"benchutil/pkg/httploader"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// способы авторизации -auth
+const (
+	authBasic  = "basic"
+	authBearer = "bearer"
+	authOAuth2 = "oauth2"
+	authHMAC   = "hmac"
+)
+
+// maskedSecret значение секретов в сохранённых аргументах нагрузки
+const maskedSecret = "xxxxx"
+
+// authFlags флаги авторизации запросов http
+func authFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.StringFlag{
+			Name:        "auth",
+			Destination: &cfg.auth,
+			Usage:       "Авторизация запросов: basic, bearer, oauth2 (client credentials) или hmac (подпись запроса HMAC-SHA256)",
+		},
+		cli.StringFlag{
+			Name:        "auth-id",
+			Destination: &cfg.authID,
+			Usage:       "Логин basic, client_id oauth2 или id ключа hmac",
+		},
+		cli.StringFlag{
+			Name:        "auth-secret",
+			Destination: &cfg.authSecret,
+			Usage:       "Пароль basic, токен bearer, client_secret oauth2 или ключ hmac, в историю не сохраняется",
+		},
+		cli.StringFlag{
+			Name:        "auth-token-url",
+			Destination: &cfg.authTokenURL,
+			Usage:       "Адрес получения токена oauth2",
+		},
+		cli.StringFlag{
+			Name:        "auth-scope",
+			Destination: &cfg.authScope,
+			Usage:       "Scope токена oauth2 через пробел",
+		},
+	}
+}
+
+// validateAuth проверяет, что для способа авторизации заданы все флаги
+func validateAuth(cfg config) error {
+	switch cfg.auth {
+	case "":
+		return nil
+	case authBasic:
+		if cfg.authID == "" {
+			return errors.New("basic requires -auth-id")
+		}
+	case authBearer:
+		if cfg.authSecret == "" {
+			return errors.New("bearer requires -auth-secret")
+		}
+	case authOAuth2:
+		if cfg.authID == "" || cfg.authSecret == "" || cfg.authTokenURL == "" {
+			return errors.New("oauth2 requires -auth-id, -auth-secret and -auth-token-url")
+		}
+		u, err := url.Parse(cfg.authTokenURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid token url %q", cfg.authTokenURL)
+		}
+	case authHMAC:
+		if cfg.authID == "" || cfg.authSecret == "" {
+			return errors.New("hmac requires -auth-id and -auth-secret")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q, expected basic, bearer, oauth2 or hmac", cfg.auth)
+	}
+
+	return nil
+}
+
+// authOptions опции httploader для флагов -auth, cfg уже проверен validateConfig
+// токен oauth2 кэшируется на время одной нагрузки, каждая фаза и шаг -sweep получают его заново
+func authOptions(cfg config) []httploader.Option {
+	var a httploader.Authenticator
+	switch cfg.auth {
+	case authBasic:
+		a = auth.Basic{User: cfg.authID, Password: cfg.authSecret}
+	case authBearer:
+		a = auth.Bearer{Token: cfg.authSecret}
+	case authOAuth2:
+		a = &auth.ClientCredentials{
+			TokenURL:     cfg.authTokenURL,
+			ClientID:     cfg.authID,
+			ClientSecret: cfg.authSecret,
+			Scopes:       strings.Fields(cfg.authScope),
+		}
+	case authHMAC:
+		a = auth.HMAC{KeyID: cfg.authID, Secret: []byte(cfg.authSecret)}
+	default:
+		return nil
+	}
+
+	return []httploader.Option{httploader.WithAuth(a)}
+}
+
+// maskSecrets скрывает секрет авторизации и пароль прокси, чтобы они не попали в историю
+func maskSecrets(cfg config) config {
+	if cfg.authSecret != "" {
+		cfg.authSecret = maskedSecret
+	}
+	if u, err := url.Parse(cfg.proxy); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), maskedSecret)
+			cfg.proxy = u.String()
+		}
+	}
+
+	return cfg
+}
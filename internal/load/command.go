@@ -1,14 +1,18 @@
 package load
 
 import (
+	"benchutil/internal/history"
 	"benchutil/pkg/cli"
+	"benchutil/pkg/formatter"
 	"benchutil/pkg/httploader"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -20,8 +24,56 @@ type config struct {
 	method        string
 	bodyPath      string
 	headersPath   string
-	outputFormat  string
+	outputs       []string
 	timeOut       int
+	rawPath       string
+	thresholds    string
+	baselinePath  string
+	scenario      string
+	historyDir    string
+	sloP95        int
+	interval      int
+	warmup        time.Duration
+	warmupN       int
+	thinkTime     string
+	pacing        time.Duration
+
+	retryAttempts   int
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+	retryOn         string
+	retryAfter      bool
+	backpressure    bool
+	wsIDField       string
+	stream          bool
+	streamDuration  time.Duration
+	frame           string
+	graphql         bool
+	graphqlVarsPath string
+
+	resolve    []string
+	dnsServer  string
+	dnsCache   bool
+	proxy      string
+	proxyEnv   bool
+	noProxyEnv bool
+	sourceIPs  []string
+
+	auth         string
+	authID       string
+	authSecret   string
+	authTokenURL string
+	authScope    string
+
+	grpcCall       string
+	protoFiles     []string
+	importPaths    []string
+	grpcTLS        bool
+	streamMessages int
+
+	// тело и заголовки запроса, полученные не из файлов, например от координатора
+	body    []byte
+	headers *http.Header
 }
 
 func New() cli.Command {
@@ -29,51 +81,7 @@ func New() cli.Command {
 	return cli.Command{
 		Name:        "load",
 		Description: "Нагружает сервер и даёт отчёт по нагрузке",
-		Flags: []cli.CmdFlag{
-			cli.IntFlag{
-				Name:        "n",
-				Destination: &cfg.requestsCount,
-				Usage:       "Количество запросов к серверу",
-			},
-			cli.IntFlag{
-				Name:        "c",
-				Destination: &cfg.concurrency,
-				Usage:       "Количество одновременных запросов к серверу в момент времени",
-			},
-			cli.IntFlag{
-				Name:        "t",
-				Destination: &cfg.timeOut,
-				Default:     1,
-				Usage:       "Таймаут для запросов",
-			},
-			cli.StringFlag{
-				Name:        "host",
-				Destination: &cfg.host,
-				Usage:       "Url адрес для отправки запросов",
-			},
-			cli.StringFlag{
-				Name:        "m",
-				Destination: &cfg.method,
-				Default:     http.MethodGet,
-				Usage:       "Http метод запроса",
-			},
-			cli.StringFlag{
-				Name:        "b",
-				Destination: &cfg.bodyPath,
-				Usage:       "Путь до файла с телом запроса",
-			},
-			cli.StringFlag{
-				Name:        "h",
-				Destination: &cfg.headersPath,
-				Usage:       "Путь до файла с заголовками запроса",
-			},
-			cli.StringFlag{
-				Name:        "o",
-				Destination: &cfg.outputFormat,
-				Default:     outputHuman,
-				Usage:       "Формат вывода результатов нагрузки",
-			},
-		},
+		Flags:       loadFlags(&cfg),
 		Action: func(ctx context.Context) error {
 			return action(ctx, cfg)
 		},
@@ -81,23 +89,355 @@ func New() cli.Command {
 
 }
 
+// loadFlags флаги команды load, используются также командами, запускающими нагрузку другим способом
+func loadFlags(cfg *config) []cli.CmdFlag {
+	flags := []cli.CmdFlag{
+		cli.IntFlag{
+			Name:        "n",
+			Destination: &cfg.requestsCount,
+			Usage:       "Количество запросов к серверу",
+		},
+		cli.IntFlag{
+			Name:        "c",
+			Destination: &cfg.concurrency,
+			Usage:       "Количество одновременных запросов к серверу в момент времени",
+		},
+		cli.IntFlag{
+			Name:        "slo",
+			Destination: &cfg.sloP95,
+			Usage:       "Целевой p95 в мс, количество одновременных запросов подстраивается под него, -c задаёт верхнюю границу",
+		},
+	}
+	flags = append(flags, targetFlags(cfg)...)
+	flags = append(flags, dnsFlags(cfg)...)
+	flags = append(flags, proxyFlags(cfg)...)
+	flags = append(flags, sourceFlags(cfg)...)
+	flags = append(flags, authFlags(cfg)...)
+	flags = append(flags, phaseFlags(cfg)...)
+	flags = append(flags,
+		cli.IntFlag{
+			Name:        "retry",
+			Destination: &cfg.retryAttempts,
+			Usage:       "Количество попыток запроса вместе с первой, больше 1 включает повторы",
+		},
+		cli.DurationFlag{
+			Name:        "retry-backoff",
+			Destination: &cfg.retryBackoff,
+			Default:     100 * time.Millisecond,
+			Usage:       "Пауза перед первым повтором, удваивается с каждым следующим, со случайным разбросом до 50%",
+		},
+		cli.DurationFlag{
+			Name:        "retry-max-backoff",
+			Destination: &cfg.retryMaxBackoff,
+			Default:     10 * time.Second,
+			Usage:       "Максимальная пауза перед повтором",
+		},
+		cli.StringFlag{
+			Name:        "retry-on",
+			Destination: &cfg.retryOn,
+			Default:     "429,502,503,504,timeout,network",
+			Usage:       "Коды ответа и классы ошибок (timeout, dns, network, graphql, auth) через запятую, при которых запрос повторяется",
+		},
+		cli.BoolFlag{
+			Name:        "retry-after",
+			Destination: &cfg.retryAfter,
+			Default:     true,
+			Usage:       "Брать паузу перед повтором после 429 и 503 из заголовка Retry-After",
+		},
+		cli.BoolFlag{
+			Name:        "backpressure",
+			Destination: &cfg.backpressure,
+			Usage:       "Ждать после 429 и 503 с Retry-After столько, сколько просит сервер, и выводить статистику ограничения скорости",
+		},
+		cli.BoolFlag{
+			Name:        "stream",
+			Destination: &cfg.stream,
+			Usage:       "Читать ответы как поток событий (SSE, NDJSON): -n потоков, -c открыто одновременно, перцентили по времени до первого события",
+		},
+		cli.DurationFlag{
+			Name:        "stream-duration",
+			Destination: &cfg.streamDuration,
+			Usage:       "Сколько держать открытым каждый поток, 0 - пока его не закроет сервер, поток закрытый раньше считается разорванным",
+		},
+		cli.StringFlag{
+			Name:        "frame",
+			Destination: &cfg.frame,
+			Usage:       "Как выделить ответ TCP: delim:<разделитель>, например delim:\\r\\n, или len:<1|2|4|8> - длина ответа в первых байтах, без флага - одно чтение",
+		},
+		cli.BoolFlag{
+			Name:        "graphql",
+			Destination: &cfg.graphql,
+			Usage:       "Тело -b - операция GraphQL или массив операций с весами в json, либо файл .graphql с текстом операции, ответ 200 с errors считается ошибкой, отчёт разбивается по операциям",
+		},
+		cli.StringFlag{
+			Name:        "graphql-vars",
+			Destination: &cfg.graphqlVarsPath,
+			Usage:       "Путь до файла с переменными операции GraphQL в json для файла .graphql в -b",
+		},
+		cli.StringFlag{
+			Name:        "ws-id",
+			Destination: &cfg.wsIDField,
+			Default:     "id",
+			Usage:       "Поле json ответа WebSocket, по которому ответ сопоставляется с сообщением",
+		},
+	)
+
+	return append(flags, reportFlags(cfg)...)
+}
+
+// phaseFlags флаги прогрева и пауз между запросами
+func phaseFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.DurationFlag{
+			Name:        "warmup",
+			Destination: &cfg.warmup,
+			Usage:       "Длительность прогрева, например 30s, запросы прогрева не попадают в отчёт",
+		},
+		cli.IntFlag{
+			Name:        "warmup-n",
+			Destination: &cfg.warmupN,
+			Usage:       "Количество запросов прогрева, вместе с -warmup прогрев заканчивается по первому из условий",
+		},
+		cli.StringFlag{
+			Name:        "think",
+			Destination: &cfg.thinkTime,
+			Usage:       "Пауза исполнителя между запросами: \"500ms\", \"uniform:100ms-1s\" или \"exp:500ms\" (экспоненциальное со средним)",
+		},
+		cli.DurationFlag{
+			Name:        "pacing",
+			Destination: &cfg.pacing,
+			Usage:       "Интервал между началами запросов одного исполнителя, например 1s",
+		},
+	}
+}
+
+// reportFlags флаги отчёта и истории нагрузки
+func reportFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.IntFlag{
+			Name:        "interval",
+			Destination: &cfg.interval,
+			Usage:       "Ожидаемый интервал между запросами одного исполнителя в мс, включает коррекцию coordinated omission в перцентилях",
+		},
+		cli.StringsFlag{
+			Name:        "o",
+			Destination: &cfg.outputs,
+			Default:     []string{formatter.Human},
+			Usage:       "Формат вывода результатов нагрузки, \"format=path\" пишет в файл, флаг можно указать несколько раз",
+		},
+		cli.StringFlag{
+			Name:        "raw",
+			Destination: &cfg.rawPath,
+			Usage:       "Путь до файла (csv или jsonl) для записи результата каждого запроса",
+		},
+		cli.StringFlag{
+			Name:        "threshold",
+			Destination: &cfg.thresholds,
+			Usage:       "Пороги через запятую, например \"p99<500,errors<1\" (время в мс, ошибки в %)",
+		},
+		cli.StringFlag{
+			Name:        "baseline",
+			Destination: &cfg.baselinePath,
+			Usage:       "Путь до json отчёта предыдущей нагрузки для сравнения в markdown",
+		},
+		cli.StringFlag{
+			Name:        "scenario",
+			Destination: &cfg.scenario,
+			Usage:       "Название сценария в истории нагрузок, по умолчанию адрес нагрузки",
+		},
+		cli.StringFlag{
+			Name:        "history",
+			Destination: &cfg.historyDir,
+			Default:     history.DefaultDir(),
+			Usage:       "Директория с историей нагрузок, пустое значение отключает сохранение",
+		},
+	}
+}
+
+// targetFlags флаги запроса к нагружаемому серверу
+func targetFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.IntFlag{
+			Name:        "t",
+			Destination: &cfg.timeOut,
+			Default:     1,
+			Usage:       "Таймаут для запросов",
+		},
+		cli.StringFlag{
+			Name:        "host",
+			Destination: &cfg.host,
+			Usage:       "Url адрес для отправки запросов",
+		},
+		cli.StringFlag{
+			Name:        "m",
+			Destination: &cfg.method,
+			Default:     http.MethodGet,
+			Usage:       "Http метод запроса",
+		},
+		cli.StringFlag{
+			Name:        "b",
+			Destination: &cfg.bodyPath,
+			Usage:       "Путь до файла с телом запроса",
+		},
+		cli.StringFlag{
+			Name:        "h",
+			Destination: &cfg.headersPath,
+			Usage:       "Путь до файла с заголовками запроса",
+		},
+	}
+}
+
 func action(ctx context.Context, cfg config) error {
 	err := validateConfig(cfg)
 	if err != nil {
 		return err
 	}
 
-	ctx = closer(ctx)
-	loader := httploader.New(time.Duration(cfg.timeOut)*time.Second, cfg.method, cfg.requestsCount, cfg.concurrency)
-	result, err := load(ctx, cfg, loader)
+	rep, err := execute(closer(ctx), cfg, newCollector(time.Now(), cfg.expectedInterval()))
 	if err != nil {
 		return err
 	}
 
-	_, err = os.Stdout.Write(result)
+	return finish(rep, cfg)
+}
+
+// expectedInterval ожидаемый интервал между запросами одного исполнителя, 0 если неизвестен
+// без -interval ожидаемым интервалом считается pacing
+// think time интервала не задаёт: следующий запрос начинается через think time после ответа,
+// интервал равен времени ответа и think time, поэтому медленный ответ не означает пропущенных запросов
+func (cfg config) expectedInterval() time.Duration {
+	if cfg.interval > 0 {
+		return time.Duration(cfg.interval) * time.Millisecond
+	}
+
+	return cfg.pacing
+}
+
+// execute выполняет нагрузку по проверенному cfg и строит отчёт, col собирает статистику по ходу нагрузки
+func execute(ctx context.Context, cfg config, col *collector) (formatter.Report, error) {
+	baseline, err := readConfigBaseline(cfg)
+	if err != nil {
+		return formatter.Report{}, err
+	}
+
+	opts := []httploader.Option{httploader.WithObserver(col.observe)}
+
+	var sink *rawSink
+	if cfg.rawPath != "" {
+		if sink, err = newRawSink(cfg.rawPath); err != nil {
+			return formatter.Report{}, err
+		}
+		// запросы прогрева в raw не пишутся
+		opts = append(opts, httploader.WithObserver(func(s httploader.Sample) {
+			if !col.warming() {
+				sink.observe(s)
+			}
+		}))
+	}
+
+	res, err := runPhases(ctx, cfg, col, opts...)
+	if sink != nil {
+		if closeErr := sink.close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("write raw results: %w", closeErr)
+		}
+	}
+	if err != nil {
+		return formatter.Report{}, err
+	}
+
+	return buildReport(cfg, res, baseline)
+}
+
+func newLoader(cfg config, opts ...httploader.Option) httploader.Loader {
+	timeOut := time.Duration(cfg.timeOut) * time.Second
+
+	// cfg уже проверен validateConfig
+	if think, _ := parseThinkTime(cfg.thinkTime); think != nil {
+		opts = append(opts, httploader.WithThinkTime(think))
+	}
+	if cfg.pacing > 0 {
+		opts = append(opts, httploader.WithPacing(cfg.pacing))
+	}
+	opts = append(opts, dnsOptions(cfg)...)
+	// gRPC соединяется с unix сокетом сам, мимо dialer
+	if !unixHost(cfg.host) {
+		opts = append(opts, proxyOptions(cfg)...)
+		opts = append(opts, sourceOptions(cfg)...)
+	}
+
+	if cfg.grpcCall != "" {
+		if len(cfg.protoFiles) > 0 {
+			opts = append(opts, httploader.WithProtoFiles(cfg.importPaths, cfg.protoFiles...))
+		}
+		if cfg.grpcTLS {
+			opts = append(opts, httploader.WithGRPCTLS(&tls.Config{}))
+		}
+		opts = append(opts, httploader.WithStreamMessages(cfg.streamMessages))
+		return httploader.NewGRPC(timeOut, cfg.grpcCall, cfg.requestsCount, cfg.concurrency, opts...)
+	}
+	opts = append(opts, authOptions(cfg)...)
+
+	if unixHost(cfg.host) {
+		socket, _, _ := parseUnixHost(cfg.host)
+		opts = append(opts, unixDialer(socket))
+	}
+
+	if cfg.stream {
+		if cfg.streamDuration > 0 {
+			opts = append(opts, httploader.WithStreamDuration(cfg.streamDuration))
+		}
+		return httploader.NewStream(timeOut, cfg.method, cfg.requestsCount, cfg.concurrency, opts...)
+	}
+
+	if network := socketNetwork(cfg.host); network != "" {
+		framing, _ := parseFraming(cfg.frame)
+		opts = append(opts, httploader.WithFraming(framing))
+		return httploader.NewSocket(timeOut, network, cfg.requestsCount, cfg.concurrency, opts...)
+	}
+
+	if isWebSocket(cfg.host) {
+		opts = append(opts, httploader.WithIDField(cfg.wsIDField))
+		return httploader.NewWebSocket(timeOut, cfg.requestsCount, cfg.concurrency, opts...)
+	}
+
+	if cfg.sloP95 > 0 {
+		opts = append(opts, httploader.WithLatencyTarget(time.Duration(cfg.sloP95)*time.Millisecond))
+	}
+	if cfg.backpressure {
+		opts = append(opts, httploader.WithBackpressure())
+	}
+	if cfg.graphql {
+		opts = append(opts, httploader.WithGraphQL())
+	}
+	if policy, _ := cfg.retryPolicy(); policy.MaxAttempts > 1 {
+		opts = append(opts, httploader.WithRetry(policy))
+	}
+
+	return httploader.New(timeOut, cfg.method, cfg.requestsCount, cfg.concurrency, opts...)
+}
+
+// isWebSocket нагрузка по WebSocket вместо http, в ней -n - количество сообщений, -c - количество соединений
+func isWebSocket(host string) bool {
+	return strings.HasPrefix(host, "ws://") || strings.HasPrefix(host, "wss://")
+}
+
+// finish сохраняет нагрузку в историю, выводит отчёт во все запрошенные форматы и проверяет пороги
+func finish(rep formatter.Report, cfg config) error {
+	run, err := saveHistory(cfg, rep)
 	if err != nil {
 		return err
 	}
+	if run.ID != "" {
+		fmt.Fprintf(os.Stderr, "saved to history: %s\n", run.ID)
+	}
+
+	if err = writeOutputs(rep, cfg.outputs); err != nil {
+		return err
+	}
+
+	if failed := rep.FailedThresholds(); len(failed) > 0 {
+		return fmt.Errorf("thresholds not passed: %s", strings.Join(failed, ", "))
+	}
 
 	return nil
 }
@@ -133,13 +473,136 @@ func validateConfig(cfg config) error {
 		return fmt.Errorf("invalid timeout value - %d", cfg.timeOut)
 	}
 
-	if _, ok := outputFormats[cfg.outputFormat]; !ok {
-		return fmt.Errorf("invalid output format - %s", cfg.outputFormat)
+	for _, raw := range cfg.outputs {
+		if out := parseOutput(raw); !formatter.Supported(out.format) {
+			return fmt.Errorf("invalid output format - %s", out.format)
+		}
 	}
 
 	if cfg.concurrency < 0 {
 		return fmt.Errorf("invalid concurrency value - %d", cfg.concurrency)
 	}
 
+	if cfg.warmup < 0 {
+		return fmt.Errorf("invalid warmup value - %s", cfg.warmup)
+	}
+
+	if cfg.warmupN < 0 {
+		return fmt.Errorf("invalid warmup requests count value - %d", cfg.warmupN)
+	}
+
+	if cfg.interval < 0 {
+		return fmt.Errorf("invalid interval value - %d", cfg.interval)
+	}
+
+	if _, err := parseThinkTime(cfg.thinkTime); err != nil {
+		return fmt.Errorf("invalid think time - %w", err)
+	}
+
+	if cfg.pacing < 0 {
+		return fmt.Errorf("invalid pacing value - %s", cfg.pacing)
+	}
+
+	if cfg.retryAttempts < 0 {
+		return fmt.Errorf("invalid retry attempts value - %d", cfg.retryAttempts)
+	}
+
+	if cfg.retryBackoff < 0 || cfg.retryMaxBackoff < 0 {
+		return fmt.Errorf("invalid retry backoff value - %s, %s", cfg.retryBackoff, cfg.retryMaxBackoff)
+	}
+
+	if _, err := cfg.retryPolicy(); err != nil {
+		return fmt.Errorf("invalid retry-on - %w", err)
+	}
+
+	if cfg.sloP95 < 0 {
+		return fmt.Errorf("invalid slo value - %d", cfg.sloP95)
+	}
+
+	if isWebSocket(cfg.host) && (cfg.sloP95 > 0 || cfg.retryAttempts > 1 || cfg.backpressure) {
+		return errors.New("slo, retry and backpressure are not supported for websocket")
+	}
+
+	if cfg.stream && (cfg.sloP95 > 0 || cfg.retryAttempts > 1 || cfg.backpressure) {
+		return errors.New("slo, retry and backpressure are not supported for streams")
+	}
+
+	if socketNetwork(cfg.host) != "" && (cfg.sloP95 > 0 || cfg.retryAttempts > 1 || cfg.backpressure || cfg.stream) {
+		return errors.New("slo, retry, backpressure and stream are not supported for tcp and udp")
+	}
+
+	if cfg.graphql && (isWebSocket(cfg.host) || socketNetwork(cfg.host) != "" || cfg.stream) {
+		return errors.New("graphql is supported only for http requests")
+	}
+
+	if cfg.graphqlVarsPath != "" && !(cfg.graphql && isGraphQLQuery(cfg.bodyPath)) {
+		return errors.New("graphql-vars requires graphql and a .graphql query file in b")
+	}
+
+	for _, raw := range cfg.resolve {
+		if _, _, err := parseResolve(raw); err != nil {
+			return fmt.Errorf("invalid resolve - %w", err)
+		}
+	}
+
+	if _, err := parseDNSServer(cfg.dnsServer); err != nil {
+		return fmt.Errorf("invalid dns server - %w", err)
+	}
+
+	if unixHost(cfg.host) {
+		if _, _, err := parseUnixHost(cfg.host); err != nil {
+			return fmt.Errorf("invalid unix host - %w", err)
+		}
+		if len(cfg.resolve) > 0 || cfg.dnsServer != "" || cfg.dnsCache || cfg.proxy != "" || len(cfg.sourceIPs) > 0 {
+			return errors.New("resolve, dns, proxy and source ip flags are not supported for unix sockets")
+		}
+	}
+
+	if _, err := parseSourceIPs(cfg.sourceIPs); err != nil {
+		return fmt.Errorf("invalid source ip - %w", err)
+	}
+
+	if _, err := parseProxy(cfg.proxy); err != nil {
+		return fmt.Errorf("invalid proxy - %w", err)
+	}
+
+	if cfg.proxyEnv && cfg.noProxyEnv {
+		return errors.New("proxy-env and no-proxy-env are mutually exclusive")
+	}
+
+	if cfg.proxy != "" && socketNetwork(cfg.host) == "udp" {
+		return errors.New("proxy is not supported for udp")
+	}
+
+	if err := validateAuth(cfg); err != nil {
+		return fmt.Errorf("invalid auth - %w", err)
+	}
+
+	if cfg.auth != "" && (isWebSocket(cfg.host) || socketNetwork(cfg.host) != "" || cfg.grpcCall != "") {
+		return errors.New("auth is supported only for http requests and streams")
+	}
+
+	if _, err := parseFraming(cfg.frame); err != nil {
+		return fmt.Errorf("invalid frame - %w", err)
+	}
+
+	if cfg.streamDuration < 0 {
+		return fmt.Errorf("invalid stream duration value - %s", cfg.streamDuration)
+	}
+
+	if cfg.grpcCall != "" && cfg.streamMessages < 1 {
+		return fmt.Errorf("invalid stream messages count value - %d", cfg.streamMessages)
+	}
+
+	if _, err := parseThresholds(cfg.thresholds); err != nil {
+		return fmt.Errorf("invalid threshold - %w", err)
+	}
+
+	if cfg.rawPath != "" {
+		if _, ok := rawFormats[rawFormat(cfg.rawPath)]; !ok {
+			return fmt.Errorf("invalid raw output format - %s", rawFormat(cfg.rawPath))
+		}
+	}
+
 	return nil
 }
@@ -2,8 +2,10 @@ package load
 
 import (
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestValidateConfig(t *testing.T) {
@@ -41,25 +43,158 @@ func TestValidateConfig(t *testing.T) {
 		},
 		{
 			name:        "invalid output format",
-			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputFormat: "xml"},
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"xml"}},
+			expectedErr: errors.New("invalid output format - xml"),
+		},
+		{
+			name:        "invalid output format in file output",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json", "xml=report.xml"}},
 			expectedErr: errors.New("invalid output format - xml"),
 		},
 		{
 			name:        "invalid concurrency value (negative)",
-			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputFormat: "json", concurrency: -1},
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, concurrency: -1},
 			expectedErr: errors.New("invalid concurrency value - -1"),
 		},
+		{
+			name:        "invalid slo value (negative)",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, sloP95: -1},
+			expectedErr: errors.New("invalid slo value - -1"),
+		},
+		{
+			name:        "invalid think time",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, thinkTime: "uniform:1s"},
+			expectedErr: fmt.Errorf("invalid think time - %w", errors.New("uniform think time \"uniform:1s\" must be min-max")),
+		},
+		{
+			name:        "invalid pacing value (negative)",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, pacing: -time.Second},
+			expectedErr: errors.New("invalid pacing value - -1s"),
+		},
+		{
+			name:        "invalid retry-on",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, retryAttempts: 3, retryOn: "500,slow"},
+			expectedErr: fmt.Errorf("invalid retry-on - %w", errors.New("unknown retry condition slow")),
+		},
+		{
+			name:        "retries are not supported for websocket",
+			cfg:         config{host: "ws://host", requestsCount: 1, timeOut: 1, retryAttempts: 3},
+			expectedErr: errors.New("slo, retry and backpressure are not supported for websocket"),
+		},
+		{
+			name:        "slo is not supported for streams",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, stream: true, sloP95: 100},
+			expectedErr: errors.New("slo, retry and backpressure are not supported for streams"),
+		},
+		{
+			name:        "invalid stream duration (negative)",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, stream: true, streamDuration: -time.Second},
+			expectedErr: errors.New("invalid stream duration value - -1s"),
+		},
+		{
+			name:        "retries are not supported for tcp",
+			cfg:         config{host: "tcp://host:9000", requestsCount: 1, timeOut: 1, retryAttempts: 3},
+			expectedErr: errors.New("slo, retry, backpressure and stream are not supported for tcp and udp"),
+		},
+		{
+			name:        "graphql is not supported for websocket",
+			cfg:         config{host: "ws://host", requestsCount: 1, timeOut: 1, graphql: true},
+			expectedErr: errors.New("graphql is supported only for http requests"),
+		},
+		{
+			name:        "graphql vars without graphql query file",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, graphql: true, bodyPath: "operations.json", graphqlVarsPath: "variables.json"},
+			expectedErr: errors.New("graphql-vars requires graphql and a .graphql query file in b"),
+		},
+		{
+			name:        "invalid unix host",
+			cfg:         config{host: "unix://", requestsCount: 1, timeOut: 1},
+			expectedErr: fmt.Errorf("invalid unix host - %w", errors.New("empty unix socket path")),
+		},
+		{
+			name:        "dns flags with unix socket",
+			cfg:         config{host: "unix:///tmp/app.sock", requestsCount: 1, timeOut: 1, dnsCache: true},
+			expectedErr: errors.New("resolve, dns, proxy and source ip flags are not supported for unix sockets"),
+		},
+		{
+			name:        "invalid source ip",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, sourceIPs: []string{"10.0.0.1,eth0"}},
+			expectedErr: fmt.Errorf("invalid source ip - %w", errors.New(`"eth0" is not an ip address`)),
+		},
+		{
+			name:        "invalid proxy",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, proxy: "ftp://proxy:3128"},
+			expectedErr: fmt.Errorf("invalid proxy - %w", errors.New(`unsupported scheme "ftp", expected http, https or socks5`)),
+		},
+		{
+			name:        "proxy with udp",
+			cfg:         config{host: "udp://host:53", requestsCount: 1, timeOut: 1, proxy: "socks5://proxy:1080"},
+			expectedErr: errors.New("proxy is not supported for udp"),
+		},
+		{
+			name:        "unknown auth",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, auth: "digest"},
+			expectedErr: fmt.Errorf("invalid auth - %w", errors.New(`unknown auth type "digest", expected basic, bearer, oauth2 or hmac`)),
+		},
+		{
+			name:        "oauth2 without token url",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, auth: "oauth2", authID: "client", authSecret: "secret"},
+			expectedErr: fmt.Errorf("invalid auth - %w", errors.New("oauth2 requires -auth-id, -auth-secret and -auth-token-url")),
+		},
+		{
+			name:        "auth with websocket",
+			cfg:         config{host: "ws://host", requestsCount: 1, timeOut: 1, auth: "bearer", authSecret: "token"},
+			expectedErr: errors.New("auth is supported only for http requests and streams"),
+		},
+		{
+			name:        "invalid resolve",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, resolve: []string{"host:80:10.0.0.1", "host:443"}},
+			expectedErr: fmt.Errorf("invalid resolve - %w", errors.New(`"host:443", expected host:port:addr[,addr]`)),
+		},
+		{
+			name:        "invalid dns server",
+			cfg:         config{host: "http://host", requestsCount: 1, timeOut: 1, dnsServer: "dns.local"},
+			expectedErr: fmt.Errorf("invalid dns server - %w", errors.New(`"dns.local" is not an ip address`)),
+		},
+		{
+			name:        "invalid frame",
+			cfg:         config{host: "tcp://host:9000", requestsCount: 1, timeOut: 1, frame: "len:3"},
+			expectedErr: fmt.Errorf("invalid frame - %w", errors.New(`length prefix size "len:3" must be 1, 2, 4 or 8`)),
+		},
+		{
+			name:        "invalid grpc stream messages count",
+			cfg:         config{host: "localhost:50051", requestsCount: 1, timeOut: 1, grpcCall: "pkg.Service/Method"},
+			expectedErr: errors.New("invalid stream messages count value - 0"),
+		},
+		{
+			name:        "invalid threshold",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"junit"}, thresholds: "p42<1"},
+			expectedErr: fmt.Errorf("invalid threshold - %w", errors.New("unknown metric p42")),
+		},
+		{
+			name:        "invalid raw output format",
+			cfg:         config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, rawPath: "raw.xml"},
+			expectedErr: errors.New("invalid raw output format - xml"),
+		},
+		{
+			name: "OK, raw output to jsonl",
+			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, rawPath: "raw.jsonl"},
+		},
+		{
+			name: "OK, several outputs",
+			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"human", "html=report.html", "junit=junit.xml"}},
+		},
 		{
 			name: "OK, concurrency == 0",
-			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputFormat: "json", concurrency: 0},
+			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, concurrency: 0},
 		},
 		{
 			name: "OK, concurrency > 0",
-			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputFormat: "json", concurrency: 100},
+			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, concurrency: 100},
 		},
 		{
 			name: "OK, with body file and headers file",
-			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputFormat: "json", concurrency: 100, bodyPath: "path/to/body", headersPath: "path/to/headers"},
+			cfg:  config{host: "host", requestsCount: 1, timeOut: 1, outputs: []string{"json"}, concurrency: 100, bodyPath: "path/to/body", headersPath: "path/to/headers"},
 		},
 	}
 
@@ -0,0 +1,251 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/histogram"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 5 * time.Second
+
+func NewCoordinator() cli.Command {
+	var (
+		cfg     config
+		workers string
+		token   string
+	)
+
+	flags := append(loadFlags(&cfg), cli.StringFlag{
+		Name:        "workers",
+		Destination: &workers,
+		Usage:       "Адреса воркеров через запятую, например \"10.0.0.1:7070,10.0.0.2:7070\"",
+	}, cli.StringFlag{
+		Name:        "token",
+		Destination: &token,
+		Usage:       "Токен, заданный воркерам флагом -token",
+	})
+
+	return cli.Command{
+		Name:        "coordinator",
+		Description: "Распределяет нагрузку между воркерами и собирает общий отчёт",
+		Flags:       flags,
+		Action: func(ctx context.Context) error {
+			return coordinate(closer(ctx), cfg, splitAddrs(workers), token)
+		},
+	}
+}
+
+type workerEvent struct {
+	worker int
+	msg    message
+	err    error
+}
+
+func coordinate(ctx context.Context, cfg config, addrs []string, token string) error {
+	if err := validateCoordinatorConfig(cfg, addrs); err != nil {
+		return err
+	}
+
+	baseline, err := readConfigBaseline(cfg)
+	if err != nil {
+		return err
+	}
+
+	res, err := runDistributed(ctx, cfg, addrs, token)
+	if err != nil {
+		return err
+	}
+
+	rep, err := buildReport(cfg, res, baseline)
+	if err != nil {
+		return err
+	}
+
+	return finish(rep, cfg)
+}
+
+func validateCoordinatorConfig(cfg config, addrs []string) error {
+	if len(addrs) == 0 {
+		return errors.New("empty workers")
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return err
+	}
+
+	if cfg.requestsCount < len(addrs) {
+		return fmt.Errorf("requests count %d is less than workers count %d", cfg.requestsCount, len(addrs))
+	}
+
+	if cfg.rawPath != "" {
+		return errors.New("raw output is not supported by coordinator")
+	}
+
+	return nil
+}
+
+// runDistributed подготавливает всех воркеров, одновременно запускает на них нагрузку и объединяет результаты
+// token передаётся воркерам в prepare
+func runDistributed(ctx context.Context, cfg config, addrs []string, token string) (runResult, error) {
+	h, body, err := readInput(cfg)
+	if err != nil {
+		return runResult{}, err
+	}
+
+	peers := make([]*peer, 0, len(addrs))
+	defer func() {
+		for _, p := range peers {
+			p.close()
+		}
+	}()
+
+	parts := splitConfig(cfg, len(addrs))
+	for i, addr := range addrs {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+		if err != nil {
+			return runResult{}, fmt.Errorf("connect worker %s: %w", addr, err)
+		}
+		p := newPeer(conn)
+		peers = append(peers, p)
+
+		if err = p.send(message{Type: msgPrepare, Args: cli.Args(loadFlags(&parts[i])), Headers: h, Body: body, Token: token}); err != nil {
+			return runResult{}, fmt.Errorf("prepare worker %s: %w", addr, err)
+		}
+	}
+
+	for i, p := range peers {
+		msg, err := p.receive()
+		if err != nil {
+			return runResult{}, fmt.Errorf("prepare worker %s: %w", addrs[i], err)
+		}
+		if msg.Type != msgReady {
+			return runResult{}, fmt.Errorf("prepare worker %s: %s", addrs[i], msg.Error)
+		}
+	}
+
+	// все воркеры готовы, старт рассылается сразу всем, чтобы нагрузка шла одновременно
+	for i, p := range peers {
+		if err = p.send(message{Type: msgStart}); err != nil {
+			return runResult{}, fmt.Errorf("start worker %s: %w", addrs[i], err)
+		}
+	}
+
+	events := make(chan workerEvent)
+	quit := make(chan struct{})
+	defer close(quit)
+	for i, p := range peers {
+		go func(i int, p *peer) {
+			for {
+				msg, err := p.receive()
+				select {
+				case events <- workerEvent{worker: i, msg: msg, err: err}:
+				case <-quit:
+					return
+				}
+				if err != nil || msg.Type != msgProgress {
+					return
+				}
+			}
+		}(i, p)
+	}
+
+	var (
+		total    runResult
+		finished int
+		stopped  bool
+		progress = make([]*progress, len(peers))
+		done     = ctx.Done()
+	)
+	for finished < len(peers) {
+		select {
+		case <-done:
+			for _, p := range peers {
+				p.send(message{Type: msgStop})
+			}
+			stopped, done = true, nil
+		case ev := <-events:
+			addr := addrs[ev.worker]
+			switch {
+			case ev.err != nil:
+				return runResult{}, fmt.Errorf("worker %s: %w", addr, ev.err)
+			case ev.msg.Type == msgProgress:
+				progress[ev.worker] = ev.msg.Progress
+				printProgress(progress, cfg.requestsCount)
+			case ev.msg.Type == msgResult && ev.msg.Result != nil:
+				total.merge(*ev.msg.Result)
+				finished++
+			default:
+				return runResult{}, fmt.Errorf("worker %s: %s", addr, ev.msg.Error)
+			}
+		}
+	}
+
+	if stopped {
+		fmt.Fprintln(os.Stderr, "load stopped, workers results are partial")
+	}
+
+	return total, nil
+}
+
+// splitConfig делит количество запросов и одновременных запросов между n воркерами
+// файлы тела и заголовков не передаются, их содержимое отправляется отдельно
+// история, пороги и отчёты остаются на координаторе
+func splitConfig(cfg config, n int) []config {
+	parts := make([]config, n)
+	for i := range parts {
+		part := cfg
+		part.requestsCount = share(cfg.requestsCount, n, i)
+		part.concurrency = share(cfg.concurrency, n, i)
+		part.bodyPath, part.headersPath, part.graphqlVarsPath = "", "", ""
+		part.thresholds, part.baselinePath, part.rawPath = "", "", ""
+		part.scenario, part.historyDir = "", ""
+		part.outputs = nil
+		parts[i] = part
+	}
+
+	return parts
+}
+
+// share доля total для i-го из n участников, остаток достаётся первым
+func share(total, n, i int) int {
+	part := total / n
+	if i < total%n {
+		part++
+	}
+
+	return part
+}
+
+func printProgress(progress []*progress, requests int) {
+	var (
+		done, errs int
+		latency    = histogram.New()
+	)
+	for _, p := range progress {
+		if p == nil {
+			continue
+		}
+		done += p.Requests
+		errs += p.Errors
+		latency.Merge(p.Latency)
+	}
+
+	fmt.Fprintf(os.Stderr, "progress: %d/%d requests, %d errors, p99 %v ms\n", done, requests, errs, toMs(latency.Quantile(0.99)))
+}
+
+func splitAddrs(raw string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(raw, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return addrs
+}
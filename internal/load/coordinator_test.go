@@ -0,0 +1,149 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func startWorker(t *testing.T, ctx context.Context, token string) string {
+	ln, err := listenWorker("127.0.0.1:0", token)
+	require.NoError(t, err)
+
+	go serveWorker(ctx, ln, token)
+
+	return ln.Addr().String()
+}
+
+func TestRunDistributed(t *testing.T) {
+	var (
+		requests int64
+		bodies   int64
+	)
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if request.Header.Get("Test") == "header1" && request.ContentLength == int64(len("i am body")) {
+			atomic.AddInt64(&bodies, 1)
+		}
+		if atomic.LoadInt64(&requests)%5 == 0 {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer serv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addrs := []string{startWorker(t, ctx, "s3cret"), startWorker(t, ctx, "s3cret"), startWorker(t, ctx, "s3cret")}
+
+	cfg := config{
+		host:          serv.URL,
+		requestsCount: 20,
+		concurrency:   4,
+		timeOut:       1,
+		method:        http.MethodPost,
+		bodyPath:      "testdata/body.txt",
+		headersPath:   "testdata/headers.json",
+		thresholds:    "errors<50",
+	}
+	require.NoError(t, validateCoordinatorConfig(cfg, addrs))
+
+	res, err := runDistributed(ctx, cfg, addrs, "s3cret")
+	require.NoError(t, err)
+
+	require.Equal(t, int64(20), requests)
+	require.Equal(t, int64(20), bodies, "body and headers are sent to workers")
+	require.Equal(t, 20, res.Report.All)
+	require.Equal(t, 16, res.Report.Success)
+	require.Equal(t, 4, res.Report.Errors)
+	require.Equal(t, uint64(20), res.Stats.Latency.Total)
+	require.Equal(t, map[int]int{200: 16, 500: 4}, res.Stats.Statuses)
+
+	rep, err := buildReport(cfg, res, nil)
+	require.NoError(t, err)
+	require.Equal(t, 20, rep.All)
+	require.Empty(t, rep.FailedThresholds())
+}
+
+func TestRunDistributedWorkerErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("worker is unavailable", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		_, err = runDistributed(ctx, config{host: "http://host", requestsCount: 1, timeOut: 1}, []string{addr}, "")
+		require.Error(t, err)
+	})
+
+	t.Run("worker rejects config", func(t *testing.T) {
+		addr := startWorker(t, ctx, "")
+
+		_, err := runDistributed(ctx, config{host: "http://host", requestsCount: 1, timeOut: 1, method: "BAD METHOD"}, []string{addr}, "")
+		require.Error(t, err)
+	})
+
+	t.Run("worker rejects token", func(t *testing.T) {
+		addr := startWorker(t, ctx, "s3cret")
+		cfg := config{host: "http://host", requestsCount: 1, timeOut: 1, method: http.MethodGet}
+
+		for _, token := range []string{"", "wrong"} {
+			_, err := runDistributed(ctx, cfg, []string{addr}, token)
+			require.EqualError(t, err, "prepare worker "+addr+": unauthorized", token)
+		}
+	})
+
+	t.Run("token is required out of loopback", func(t *testing.T) {
+		_, err := listenWorker(":0", "")
+		require.EqualError(t, err, "token is required to listen on :0")
+	})
+}
+
+func TestRunDistributedStop(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer serv.Close()
+
+	workersCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	addrs := []string{startWorker(t, workersCtx, ""), startWorker(t, workersCtx, "")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	res, err := runDistributed(ctx, config{host: serv.URL, requestsCount: 1000, timeOut: 1, method: http.MethodGet}, addrs, "")
+
+	require.NoError(t, err)
+	require.Greater(t, res.Report.All, 0)
+	require.Less(t, res.Report.All, 1000)
+}
+
+func TestSplitConfig(t *testing.T) {
+	cfg := config{host: "http://host", requestsCount: 10, concurrency: 4, bodyPath: "body.txt", outputs: []string{"html=r.html"}, thresholds: "p99<1"}
+
+	parts := splitConfig(cfg, 3)
+
+	require.Equal(t, []config{
+		{host: "http://host", requestsCount: 4, concurrency: 2},
+		{host: "http://host", requestsCount: 3, concurrency: 1},
+		{host: "http://host", requestsCount: 3, concurrency: 1},
+	}, parts)
+}
+
+func TestValidateCoordinatorConfig(t *testing.T) {
+	cfg := config{host: "host", requestsCount: 2, timeOut: 1}
+
+	require.Equal(t, errors.New("empty workers"), validateCoordinatorConfig(cfg, nil))
+	require.Equal(t, errors.New("requests count 2 is less than workers count 3"), validateCoordinatorConfig(cfg, []string{"a", "b", "c"}))
+	require.NoError(t, validateCoordinatorConfig(cfg, []string{"a", "b"}))
+	require.Equal(t, []string{"a:1", "b:2"}, splitAddrs(" a:1, b:2,"))
+}
@@ -0,0 +1,553 @@
+package load
+
+import (
+	"benchutil/internal/history"
+	"benchutil/pkg/cli"
+	"benchutil/pkg/formatter"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// состояния нагрузки, запущенной через daemon
+const (
+	stateRunning  = "running"
+	stateStopping = "stopping"
+	stateFinished = "finished"
+	stateStopped  = "stopped"
+	stateFailed   = "failed"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// fileFlags флаги load, которые читают или пишут файлы, через api они запрещены:
+// иначе любой, кто может обратиться к daemon, прочитает или перезапишет файлы на его машине
+// тело и заголовки запроса вместо -b и -h передаются в конфиге в body и headers, историю задаёт флаг -history самого daemon
+var fileFlags = map[string]bool{
+	"b":            true,
+	"h":            true,
+	"raw":          true,
+	"history":      true,
+	"baseline":     true,
+	"graphql-vars": true,
+}
+
+func NewDaemon() cli.Command {
+	var (
+		listen     string
+		token      string
+		historyDir string
+	)
+	return cli.Command{
+		Name:        "daemon",
+		Description: "Запускает http api для управления нагрузками",
+		Flags: []cli.CmdFlag{
+			cli.StringFlag{
+				Name:        "listen",
+				Destination: &listen,
+				Default:     "127.0.0.1:8080",
+				Usage:       "Адрес, на котором daemon принимает запросы",
+			},
+			cli.StringFlag{
+				Name:        "token",
+				Destination: &token,
+				Usage:       "Токен, который api ждёт в заголовке Authorization: Bearer, обязателен, если -listen не на loopback",
+			},
+			cli.StringFlag{
+				Name:        "history",
+				Destination: &historyDir,
+				Default:     history.DefaultDir(),
+				Usage:       "Директория с историей нагрузок, пустое значение отключает сохранение",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			return serveDaemon(closer(ctx), listen, token, historyDir)
+		},
+	}
+}
+
+func serveDaemon(ctx context.Context, listen, token, historyDir string) error {
+	if token == "" && !loopback(listen) {
+		return fmt.Errorf("token is required to listen on %s", listen)
+	}
+
+	d := newDaemon(ctx, historyDir)
+	srv := &http.Server{Addr: listen, Handler: requireToken(token, d.handler())}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("listen: %w", err)
+	case <-ctx.Done():
+	}
+
+	// нагрузки останавливаются вместе с daemon, их отчёты уже никто не запросит
+	d.stopAll()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	return srv.Shutdown(shutdownCtx)
+}
+
+// daemon хранит нагрузки, запущенные через api, нагрузки выполняются независимо друг от друга
+type daemon struct {
+	ctx        context.Context
+	historyDir string
+
+	mu     sync.Mutex
+	runs   map[string]*daemonRun
+	lastID int
+}
+
+type daemonRun struct {
+	id     string
+	cfg    config
+	col    *collector
+	start  time.Time
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	state  string
+	end    time.Time
+	report formatter.Report
+	err    error
+}
+
+// runStatus состояние нагрузки, отдаваемое api
+type runStatus struct {
+	ID         string     `json:"id"`
+	State      string     `json:"state"`
+	Host       string     `json:"host"`
+	Total      int        `json:"total"`
+	Requests   int        `json:"requests"`
+	Errors     int        `json:"errors"`
+	P50        float64    `json:"p50"`
+	P99        float64    `json:"p99"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Failed     []string   `json:"failedThresholds,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func newDaemon(ctx context.Context, historyDir string) *daemon {
+	return &daemon{ctx: ctx, historyDir: historyDir, runs: make(map[string]*daemonRun)}
+}
+
+// loopback адрес listen доступен только с этой машины
+func loopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+
+	return loopbackHost(host)
+}
+
+// loopbackHost имя или ip хоста без порта указывает на эту машину
+func loopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireToken пропускает только запросы с заголовком Authorization: Bearer token
+// без token пропускает только запросы, адресованные loopback и отправленные не со сторонней страницы:
+// иначе любая страница, открытая в браузере на машине daemon, могла бы запустить нагрузку
+func requireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return requireLocal(next)
+	}
+
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireLocal пропускает запросы с loopback в Host и, если он есть, в Origin
+// проверка Host защищает от DNS rebinding, проверка Origin - от запросов со сторонних страниц
+func requireLocal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !loopbackHost(hostname(r.Host)) {
+			writeJSON(w, http.StatusForbidden, apiError{Error: "host is not loopback"})
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || !loopbackHost(u.Hostname()) {
+				writeJSON(w, http.StatusForbidden, apiError{Error: "origin is not loopback"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hostname хост из заголовка Host, порт может отсутствовать
+func hostname(host string) string {
+	return (&url.URL{Host: host}).Hostname()
+}
+
+// handler маршруты api:
+// POST /runs - запуск нагрузки, тело - json с флагами команды load, тело и заголовки запроса передаются в body и headers
+// GET /runs - список нагрузок
+// GET /runs/{id} - состояние нагрузки
+// POST /runs/{id}/stop - остановка нагрузки
+// GET /runs/{id}/report?format=json - отчёт завершённой нагрузки в любом поддерживаемом формате
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/runs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			d.handleStart(w, r)
+		case http.MethodGet:
+			d.handleList(w)
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+		}
+	})
+	mux.HandleFunc("/runs/", func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+		run, ok := d.run(parts[0])
+		if !ok {
+			writeJSON(w, http.StatusNotFound, apiError{Error: "run not found"})
+			return
+		}
+
+		switch {
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, run.status())
+		case len(parts) == 2 && parts[1] == "stop" && r.Method == http.MethodPost:
+			run.stop()
+			writeJSON(w, http.StatusAccepted, run.status())
+		case len(parts) == 2 && parts[1] == "report" && r.Method == http.MethodGet:
+			d.handleReport(w, r, run)
+		default:
+			writeJSON(w, http.StatusNotFound, apiError{Error: "not found"})
+		}
+	})
+
+	return mux
+}
+
+func (d *daemon) handleStart(w http.ResponseWriter, r *http.Request) {
+	// браузер отправляет на другой сайт без preflight только text/plain и формы, json требует разрешения CORS
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, apiError{Error: "content type must be application/json"})
+		return
+	}
+
+	var flags map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&flags); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("decode config: %v", err)})
+		return
+	}
+
+	input, err := takeInput(flags)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	args, err := flagsToArgs(flags)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	var cfg config
+	if err = cli.Parse("load", loadFlags(&cfg), args); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+	cfg.body, cfg.headers, cfg.historyDir = input.body, input.headers, d.historyDir
+	if unixHost(cfg.host) {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "unix socket host is not supported by api"})
+		return
+	}
+	if err = validateConfig(cfg); err != nil {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, d.start(cfg).status())
+}
+
+func (d *daemon) handleList(w http.ResponseWriter) {
+	d.mu.Lock()
+	runs := make([]*daemonRun, 0, len(d.runs))
+	for _, run := range d.runs {
+		runs = append(runs, run)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].start.Before(runs[j].start)
+	})
+
+	statuses := make([]runStatus, 0, len(runs))
+	for _, run := range runs {
+		statuses = append(statuses, run.status())
+	}
+
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (d *daemon) handleReport(w http.ResponseWriter, r *http.Request, run *daemonRun) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = formatter.Json
+	}
+	if !formatter.Supported(format) {
+		writeJSON(w, http.StatusBadRequest, apiError{Error: fmt.Sprintf("unknown format: %s", format)})
+		return
+	}
+
+	rep, state, err := run.result()
+	switch state {
+	case stateRunning, stateStopping:
+		writeJSON(w, http.StatusConflict, apiError{Error: "run is not finished"})
+		return
+	case stateFailed:
+		writeJSON(w, http.StatusConflict, apiError{Error: err.Error()})
+		return
+	}
+
+	out, err := formatter.Format(format, rep)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, apiError{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// start запускает нагрузку в фоне
+func (d *daemon) start(cfg config) *daemonRun {
+	ctx, cancel := context.WithCancel(d.ctx)
+	now := time.Now()
+	run := &daemonRun{
+		cfg:    cfg,
+		col:    newCollector(now, cfg.expectedInterval()),
+		start:  now,
+		cancel: cancel,
+		state:  stateRunning,
+	}
+
+	d.mu.Lock()
+	d.lastID++
+	run.id = strconv.Itoa(d.lastID)
+	d.runs[run.id] = run
+	d.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		rep, err := execute(ctx, cfg, run.col)
+		if err == nil {
+			_, err = saveHistory(cfg, rep)
+		}
+		run.finish(rep, err)
+	}()
+
+	return run
+}
+
+func (d *daemon) run(id string) (*daemonRun, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	run, ok := d.runs[id]
+	return run, ok
+}
+
+func (d *daemon) stopAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, run := range d.runs {
+		run.stop()
+	}
+}
+
+// stop останавливает нагрузку, уже отправленные запросы завершаются и попадают в отчёт
+func (r *daemonRun) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == stateRunning {
+		r.state = stateStopping
+		r.cancel()
+	}
+}
+
+func (r *daemonRun) finish(rep formatter.Report, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.end = time.Now()
+	r.report, r.err = rep, err
+	switch {
+	case err != nil:
+		r.state = stateFailed
+	case r.state == stateStopping:
+		r.state = stateStopped
+	default:
+		r.state = stateFinished
+	}
+}
+
+func (r *daemonRun) result() (formatter.Report, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.report, r.state, r.err
+}
+
+func (r *daemonRun) status() runStatus {
+	st := r.col.snapshot()
+	p := st.progress()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := runStatus{
+		ID:        r.id,
+		State:     r.state,
+		Host:      r.cfg.host,
+		Total:     r.cfg.requestsCount,
+		Requests:  p.Requests,
+		Errors:    p.Errors,
+		P50:       toMs(p.Latency.Quantile(0.5)),
+		P99:       toMs(p.Latency.Quantile(0.99)),
+		StartedAt: r.start,
+	}
+	if !r.end.IsZero() {
+		end := r.end
+		status.FinishedAt = &end
+		status.Failed = r.report.FailedThresholds()
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+
+	return status
+}
+
+// apiInput тело и заголовки запроса нагрузки, переданные в конфиге вместо файлов
+type apiInput struct {
+	body    []byte
+	headers *http.Header
+}
+
+// takeInput забирает из конфига body (строка) и headers (объект, значение - строка или массив строк)
+// и проверяет, что в конфиге нет флагов с путями к файлам
+func takeInput(flags map[string]interface{}) (apiInput, error) {
+	for name := range flags {
+		if fileFlags[name] {
+			return apiInput{}, fmt.Errorf("flag %s works with local files and is not supported by api", name)
+		}
+	}
+
+	var input apiInput
+	if raw, ok := flags["body"]; ok {
+		body, ok := raw.(string)
+		if !ok {
+			return apiInput{}, errors.New("body must be a string")
+		}
+		input.body = []byte(body)
+		delete(flags, "body")
+	}
+
+	if raw, ok := flags["headers"]; ok {
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return apiInput{}, errors.New("headers must be an object")
+		}
+		h := make(http.Header, len(object))
+		for name, value := range object {
+			values, ok := value.([]interface{})
+			if !ok {
+				values = []interface{}{value}
+			}
+			for _, v := range values {
+				s, ok := v.(string)
+				if !ok {
+					return apiInput{}, fmt.Errorf("invalid value of header %s", name)
+				}
+				h.Add(name, s)
+			}
+		}
+		input.headers = &h
+		delete(flags, "headers")
+	}
+
+	return input, nil
+}
+
+// flagsToArgs переводит json конфиг в аргументы команды load
+// ключи - имена флагов, массив задаёт повторяемый флаг, например {"host": "http://target", "n": 100, "resolve": ["target:80:10.0.0.1", "target:443:10.0.0.1"]}
+func flagsToArgs(flags map[string]interface{}) ([]string, error) {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		values, ok := flags[name].([]interface{})
+		if !ok {
+			values = []interface{}{flags[name]}
+		}
+
+		for _, v := range values {
+			var value string
+			switch v := v.(type) {
+			case string:
+				value = v
+			case float64:
+				value = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				value = strconv.FormatBool(v)
+			default:
+				return nil, fmt.Errorf("invalid value of flag %s", name)
+			}
+			args = append(args, fmt.Sprintf("-%s=%s", name, value))
+		}
+	}
+
+	return args, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
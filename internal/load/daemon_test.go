@@ -0,0 +1,283 @@
+package load
+
+import (
+	"benchutil/internal/history"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startRun(t *testing.T, api string, cfg string) (int, runStatus) {
+	resp, err := http.Post(api+"/runs", "application/json", strings.NewReader(cfg))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var status runStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+
+	return resp.StatusCode, status
+}
+
+func getStatus(t *testing.T, api, id string) runStatus {
+	resp, err := http.Get(api + "/runs/" + id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var status runStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+
+	return status
+}
+
+func waitRun(t *testing.T, api, id string) runStatus {
+	var status runStatus
+	require.Eventually(t, func() bool {
+		status = getStatus(t, api, id)
+		return status.State != stateRunning && status.State != stateStopping
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return status
+}
+
+func getReport(t *testing.T, api, id, format string) (int, []byte) {
+	resp, err := http.Get(api + "/runs/" + id + "/report?format=" + format)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, body
+}
+
+func TestDaemon(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		if string(body) != `{"id": 1}` || request.Header.Get("X-Api-Key") != "key" || len(request.Header.Values("Accept")) != 2 {
+			writer.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	api := httptest.NewServer(newDaemon(ctx, dir).handler())
+	defer api.Close()
+
+	code, status := startRun(t, api.URL, `{"host": "`+target.URL+`", "n": 20, "c": 2, "threshold": "errors<1", "scenario": "api", "m": "POST", `+
+		`"body": "{\"id\": 1}", "headers": {"X-Api-Key": "key", "Accept": ["application/json", "text/plain"]}}`)
+	require.Equal(t, http.StatusCreated, code)
+	require.Equal(t, "1", status.ID)
+	require.Equal(t, 20, status.Total)
+
+	status = waitRun(t, api.URL, status.ID)
+	require.Equal(t, stateFinished, status.State)
+	require.Equal(t, 20, status.Requests)
+	require.Empty(t, status.Failed)
+	require.NotNil(t, status.FinishedAt)
+
+	code, body := getReport(t, api.URL, status.ID, "")
+	require.Equal(t, http.StatusOK, code)
+	var rep struct {
+		All     int `json:"All"`
+		Success int `json:"Success"`
+	}
+	require.NoError(t, json.Unmarshal(body, &rep))
+	require.Equal(t, 20, rep.All)
+	require.Equal(t, 20, rep.Success)
+
+	code, body = getReport(t, api.URL, status.ID, "markdown")
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, bytes.HasPrefix(body, []byte("### Результаты нагрузки")))
+
+	code, _ = getReport(t, api.URL, status.ID, "unknown")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	resp, err := http.Get(api.URL + "/runs")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var list []runStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&list))
+	require.Len(t, list, 1)
+
+	store, err := history.Open(dir)
+	require.NoError(t, err)
+	runs, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	require.Equal(t, "api", runs[0].Scenario)
+	require.Equal(t, 20, runs[0].Report.All)
+}
+
+func TestDaemonStop(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := httptest.NewServer(newDaemon(ctx, "").handler())
+	defer api.Close()
+
+	_, status := startRun(t, api.URL, `{"host": "`+target.URL+`", "n": 1000, "o": ["json", "human"]}`)
+
+	code, _ := getReport(t, api.URL, status.ID, "json")
+	require.Equal(t, http.StatusConflict, code, "report of running load")
+
+	resp, err := http.Post(api.URL+"/runs/"+status.ID+"/stop", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	status = waitRun(t, api.URL, status.ID)
+	require.Equal(t, stateStopped, status.State)
+	require.Less(t, status.Requests, 1000)
+
+	code, _ = getReport(t, api.URL, status.ID, "json")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestDaemonErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := httptest.NewServer(newDaemon(ctx, "").handler())
+	defer api.Close()
+
+	tests := []struct {
+		name string
+		cfg  string
+		err  string
+	}{
+		{name: "invalid json", cfg: `{`, err: "decode config: unexpected EOF"},
+		{name: "unknown flag", cfg: `{"host": "http://host", "n": 1, "x": 1}`, err: "flag provided but not defined: -x"},
+		{name: "invalid config", cfg: `{"host": "http://host"}`, err: "invalid requests count value - 0"},
+		{name: "invalid value", cfg: `{"host": {}}`, err: "invalid value of flag host"},
+		{name: "body file", cfg: `{"host": "http://host", "n": 1, "b": "/etc/passwd"}`, err: "flag b works with local files and is not supported by api"},
+		{name: "raw file", cfg: `{"host": "http://host", "n": 1, "raw": "/tmp/r.csv"}`, err: "flag raw works with local files and is not supported by api"},
+		{name: "history directory", cfg: `{"host": "http://host", "n": 1, "history": "/tmp"}`, err: "flag history works with local files and is not supported by api"},
+		{name: "invalid body", cfg: `{"host": "http://host", "n": 1, "body": {}}`, err: "body must be a string"},
+		{name: "invalid header", cfg: `{"host": "http://host", "n": 1, "headers": {"X-Id": 1}}`, err: "invalid value of header X-Id"},
+		{name: "unix socket", cfg: `{"host": "unix:///var/run/docker.sock:/containers/json", "n": 1}`, err: "unix socket host is not supported by api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, status := startRun(t, api.URL, tt.cfg)
+
+			require.Equal(t, http.StatusBadRequest, code)
+			require.Equal(t, tt.err, status.Error)
+		})
+	}
+
+	t.Run("not json", func(t *testing.T) {
+		resp, err := http.Post(api.URL+"/runs", "text/plain", strings.NewReader(`{"host": "http://host", "n": 1}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+	})
+
+	t.Run("unknown run", func(t *testing.T) {
+		resp, err := http.Get(api.URL + "/runs/42")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func TestDaemonToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := httptest.NewServer(requireToken("s3cret", newDaemon(ctx, "").handler()))
+	defer api.Close()
+
+	for token, code := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "s3cret": http.StatusOK} {
+		req, err := http.NewRequest(http.MethodGet, api.URL+"/runs", nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, code, resp.StatusCode, token)
+	}
+
+	err := serveDaemon(ctx, ":0", "", "")
+	require.EqualError(t, err, "token is required to listen on :0")
+}
+
+func TestDaemonLocal(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := httptest.NewServer(requireToken("", newDaemon(ctx, "").handler()))
+	defer api.Close()
+
+	tests := []struct {
+		name   string
+		host   string
+		origin string
+		code   int
+	}{
+		{name: "loopback", code: http.StatusOK},
+		{name: "localhost without port", host: "localhost", code: http.StatusOK},
+		{name: "local page", origin: "http://localhost:3000", code: http.StatusOK},
+		{name: "dns rebinding", host: "evil.example:8080", code: http.StatusForbidden},
+		{name: "cross site page", origin: "https://evil.example", code: http.StatusForbidden},
+		{name: "sandboxed page", origin: "null", code: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, api.URL+"/runs", nil)
+			require.NoError(t, err)
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			require.Equal(t, tt.code, resp.StatusCode)
+		})
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	for listen, expected := range map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+		"8080":           false,
+	} {
+		require.Equal(t, expected, loopback(listen), listen)
+	}
+}
+
+func TestFlagsToArgs(t *testing.T) {
+	args, err := flagsToArgs(map[string]interface{}{
+		"host":    "http://host",
+		"n":       float64(100),
+		"resolve": []interface{}{"host:80:10.0.0.1", "host:443:10.0.0.1"},
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"-host=http://host", "-n=100", "-resolve=host:80:10.0.0.1", "-resolve=host:443:10.0.0.1"}, args)
+}
@@ -0,0 +1,94 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/httploader"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// dnsPort порт DNS сервера, если в -dns-server он не указан
+const dnsPort = "53"
+
+// dnsFlags флаги разрешения имени нагружаемого хоста, общие для load и grpc
+func dnsFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.StringsFlag{
+			Name:        "resolve",
+			Destination: &cfg.resolve,
+			Usage:       "Адреса хоста в формате host:port:addr[,addr], как у curl --resolve: соединения идут на addr, Host и SNI остаются от хоста, флаг можно указать несколько раз",
+		},
+		cli.StringFlag{
+			Name:        "dns-server",
+			Destination: &cfg.dnsServer,
+			Usage:       "DNS сервер ip[:port] вместо системного",
+		},
+		cli.BoolFlag{
+			Name:        "dns-cache",
+			Destination: &cfg.dnsCache,
+			Usage:       "Разрешать имя хоста один раз за нагрузку, без флага - при каждом новом соединении",
+		},
+	}
+}
+
+// parseResolve разбирает "host:port:addr[,addr]" в адрес хоста "host:port" и ip адреса, на которые идут его соединения
+// ip v6 можно указать в квадратных скобках, как у curl
+func parseResolve(raw string) (string, []string, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", nil, fmt.Errorf("%q, expected host:port:addr[,addr]", raw)
+	}
+	if port, err := strconv.Atoi(parts[1]); err != nil || port <= 0 || port > 65535 {
+		return "", nil, fmt.Errorf("invalid port in %q", raw)
+	}
+
+	var addrs []string
+	for _, addr := range strings.Split(parts[2], ",") {
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if net.ParseIP(addr) == nil {
+			return "", nil, fmt.Errorf("invalid address %q in %q", addr, raw)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	return net.JoinHostPort(parts[0], parts[1]), addrs, nil
+}
+
+// parseDNSServer разбирает "ip" или "ip:port" в адрес DNS сервера "ip:port", для пустой строки - пустой адрес
+func parseDNSServer(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	host, port, err := net.SplitHostPort(raw)
+	if err != nil {
+		host, port = strings.TrimSuffix(strings.TrimPrefix(raw, "["), "]"), dnsPort
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("%q is not an ip address", raw)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return "", fmt.Errorf("invalid port in %q", raw)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
+
+// dnsOptions опции httploader для флагов -resolve, -dns-server и -dns-cache, cfg уже проверен validateConfig
+func dnsOptions(cfg config) []httploader.Option {
+	var opts []httploader.Option
+	for _, raw := range cfg.resolve {
+		hostPort, addrs, _ := parseResolve(raw)
+		opts = append(opts, httploader.WithResolve(hostPort, addrs...))
+	}
+	if server, _ := parseDNSServer(cfg.dnsServer); server != "" {
+		opts = append(opts, httploader.WithDNSServer(server))
+	}
+	if cfg.dnsCache {
+		opts = append(opts, httploader.WithDNSCache())
+	}
+
+	return opts
+}
@@ -0,0 +1,68 @@
+package load
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	type testCase struct {
+		raw           string
+		expectedHost  string
+		expectedAddrs []string
+		expectedErr   string
+	}
+
+	cases := [...]testCase{
+		{raw: "api.local:443:10.0.0.1", expectedHost: "api.local:443", expectedAddrs: []string{"10.0.0.1"}},
+		{raw: "api.local:80:10.0.0.1,10.0.0.2", expectedHost: "api.local:80", expectedAddrs: []string{"10.0.0.1", "10.0.0.2"}},
+		{raw: "api.local:80:[::1],10.0.0.2", expectedHost: "api.local:80", expectedAddrs: []string{"::1", "10.0.0.2"}},
+		{raw: "api.local:80", expectedErr: `"api.local:80", expected host:port:addr[,addr]`},
+		{raw: ":80:10.0.0.1", expectedErr: `":80:10.0.0.1", expected host:port:addr[,addr]`},
+		{raw: "api.local:http:10.0.0.1", expectedErr: `invalid port in "api.local:http:10.0.0.1"`},
+		{raw: "api.local:80:pod-1", expectedErr: `invalid address "pod-1" in "api.local:80:pod-1"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.raw, func(t *testing.T) {
+			host, addrs, err := parseResolve(tc.raw)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedHost, host)
+			require.Equal(t, tc.expectedAddrs, addrs)
+		})
+	}
+}
+
+func TestParseDNSServer(t *testing.T) {
+	type testCase struct {
+		raw         string
+		expected    string
+		expectedErr string
+	}
+
+	cases := [...]testCase{
+		{raw: ""},
+		{raw: "10.0.0.53", expected: "10.0.0.53:53"},
+		{raw: "127.0.0.1:5353", expected: "127.0.0.1:5353"},
+		{raw: "::1", expected: "[::1]:53"},
+		{raw: "[::1]:5353", expected: "[::1]:5353"},
+		{raw: "dns.local:53", expectedErr: `"dns.local:53" is not an ip address`},
+		{raw: "10.0.0.53:dns", expectedErr: `invalid port in "10.0.0.53:dns"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.raw, func(t *testing.T) {
+			server, err := parseDNSServer(tc.raw)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, server)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"context"
+	"errors"
+)
+
+// NewGrpc команда grpc, нагрузка на метод gRPC с тем же отчётом, что и у load
+// -n - количество вызовов, вызов с потоками считается одним запросом
+func NewGrpc() cli.Command {
+	var cfg config
+	return cli.Command{
+		Name:        "grpc",
+		Description: "Нагружает метод gRPC и даёт отчёт по нагрузке",
+		Flags:       grpcFlags(&cfg),
+		Action: func(ctx context.Context) error {
+			if cfg.grpcCall == "" {
+				return errors.New("empty call")
+			}
+			return action(ctx, cfg)
+		},
+	}
+}
+
+func grpcFlags(cfg *config) []cli.CmdFlag {
+	flags := []cli.CmdFlag{
+		cli.IntFlag{
+			Name:        "n",
+			Destination: &cfg.requestsCount,
+			Usage:       "Количество вызовов метода",
+		},
+		cli.IntFlag{
+			Name:        "c",
+			Destination: &cfg.concurrency,
+			Usage:       "Количество одновременных вызовов",
+		},
+		cli.IntFlag{
+			Name:        "t",
+			Destination: &cfg.timeOut,
+			Default:     1,
+			Usage:       "Таймаут вызова",
+		},
+		cli.StringFlag{
+			Name:        "host",
+			Destination: &cfg.host,
+			Usage:       "Адрес сервера gRPC, например localhost:50051",
+		},
+		cli.StringFlag{
+			Name:        "call",
+			Destination: &cfg.grpcCall,
+			Usage:       "Метод в формате package.Service/Method",
+		},
+		cli.StringsFlag{
+			Name:        "proto",
+			Destination: &cfg.protoFiles,
+			Usage:       "Путь до .proto файла с описанием метода, без флага описание запрашивается через server reflection, флаг можно указать несколько раз",
+		},
+		cli.StringsFlag{
+			Name:        "import-path",
+			Destination: &cfg.importPaths,
+			Usage:       "Директория для поиска .proto файлов и их импортов, флаг можно указать несколько раз",
+		},
+		cli.StringFlag{
+			Name:        "b",
+			Destination: &cfg.bodyPath,
+			Usage:       "Путь до файла с json шаблоном сообщения, {{id}} в нём заменяется на id сообщения",
+		},
+		cli.StringFlag{
+			Name:        "h",
+			Destination: &cfg.headersPath,
+			Usage:       "Путь до файла с заголовками, они передаются как metadata",
+		},
+		cli.BoolFlag{
+			Name:        "tls",
+			Destination: &cfg.grpcTLS,
+			Usage:       "Соединяться с сервером по TLS",
+		},
+		cli.IntFlag{
+			Name:        "stream-n",
+			Destination: &cfg.streamMessages,
+			Default:     1,
+			Usage:       "Количество сообщений в каждом вызове с потоком от клиента",
+		},
+	}
+	flags = append(flags, dnsFlags(cfg)...)
+	flags = append(flags, proxyFlags(cfg)...)
+	flags = append(flags, sourceFlags(cfg)...)
+	flags = append(flags, phaseFlags(cfg)...)
+
+	return append(flags, reportFlags(cfg)...)
+}
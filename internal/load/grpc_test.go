@@ -0,0 +1,61 @@
+package load
+
+import (
+	"benchutil/internal/history"
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGrpc(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	body := filepath.Join(t.TempDir(), "message.json")
+	require.NoError(t, os.WriteFile(body, []byte(`{"service": ""}`), 0o644))
+
+	cfg := config{
+		host:           lis.Addr().String(),
+		grpcCall:       "grpc.health.v1.Health/Check",
+		requestsCount:  6,
+		concurrency:    2,
+		timeOut:        1,
+		bodyPath:       body,
+		streamMessages: 1,
+		outputs:        []string{"json"},
+		thresholds:     "errors<1",
+		historyDir:     t.TempDir(),
+	}
+	require.NoError(t, validateConfig(cfg))
+
+	rep, err := execute(context.Background(), cfg, newCollector(time.Now(), 0))
+	require.NoError(t, err)
+
+	require.Equal(t, 6, rep.All)
+	require.Equal(t, 6, rep.Success)
+	require.Equal(t, map[string]int{"OK": 6}, rep.GrpcCodes)
+	require.Nil(t, rep.StatusCodes)
+	require.NotNil(t, rep.Latency)
+	require.Empty(t, rep.FailedThresholds())
+
+	run, err := saveHistory(cfg, rep)
+	require.NoError(t, err)
+	store, err := history.Open(cfg.historyDir)
+	require.NoError(t, err)
+	saved, err := store.Get(run.ID)
+	require.NoError(t, err)
+	require.Contains(t, saved.Args, "-call=grpc.health.v1.Health/Check")
+}
@@ -0,0 +1,45 @@
+package load
+
+import (
+	"benchutil/internal/history"
+	"benchutil/pkg/cli"
+	"benchutil/pkg/formatter"
+	"fmt"
+)
+
+// saveHistory сохраняет нагрузку в историю, пустая директория истории отключает сохранение
+// сценарий по умолчанию - адрес нагрузки
+func saveHistory(cfg config, rep formatter.Report) (history.Run, error) {
+	if cfg.historyDir == "" {
+		return history.Run{}, nil
+	}
+
+	store, err := history.Open(cfg.historyDir)
+	if err != nil {
+		return history.Run{}, err
+	}
+
+	scenario := cfg.scenario
+	if scenario == "" {
+		scenario = cfg.host
+	}
+
+	// в историю аргументы попадают без секретов
+	masked := maskSecrets(cfg)
+	flags := loadFlags(&masked)
+	if cfg.grpcCall != "" {
+		flags = grpcFlags(&masked)
+	}
+
+	run, err := store.Save(history.Run{
+		Scenario: scenario,
+		Args:     cli.Args(flags),
+		Env:      history.CurrentEnvironment(),
+		Report:   rep,
+	})
+	if err != nil {
+		return history.Run{}, fmt.Errorf("save history: %w", err)
+	}
+
+	return run, nil
+}
@@ -1,44 +1,252 @@
 package load
 
 import (
+	"benchutil/pkg/formatter"
 	"benchutil/pkg/headers"
 	"benchutil/pkg/httploader"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
-func load(ctx context.Context, cfg config, loader httploader.Loader) (output []byte, err error) {
+// runResult результат нагрузки до построения отчёта
+// результаты нагрузок с нескольких машин объединяются через merge
+// Warmup результат прогрева, если он был
+type runResult struct {
+	Report  httploader.Report `json:"report"`
+	Elapsed time.Duration     `json:"elapsed"`
+	Stats   stats             `json:"stats"`
+	Warmup  *runResult        `json:"warmup,omitempty"`
+}
+
+func load(ctx context.Context, cfg config, loader httploader.Loader, col *collector) (formatter.Report, error) {
+	baseline, err := readConfigBaseline(cfg)
+	if err != nil {
+		return formatter.Report{}, err
+	}
+
+	res, err := run(ctx, cfg, loader, col)
+	if err != nil {
+		return formatter.Report{}, err
+	}
+
+	return buildReport(cfg, res, baseline)
+}
+
+func run(ctx context.Context, cfg config, loader httploader.Loader, col *collector) (runResult, error) {
+	start := time.Now()
 	loadRep, err := makeLoad(ctx, cfg, loader)
 	if err != nil {
-		return nil, err
+		return runResult{}, err
 	}
 
-	return readResult(loadRep, cfg.outputFormat)
+	return runResult{Report: loadRep, Elapsed: time.Since(start), Stats: col.snapshot()}, nil
+}
+
+// buildReport строит итоговый отчёт и проверяет пороги из cfg
+func buildReport(cfg config, res runResult, baseline *formatter.Report) (formatter.Report, error) {
+	thresholds, err := parseThresholds(cfg.thresholds)
+	if err != nil {
+		return formatter.Report{}, fmt.Errorf("parse thresholds: %w", err)
+	}
+
+	rep := loaderReportToInternal(res.Report)
+	rep.Host = cfg.host
+	rep.Baseline = baseline
+	rep.Duration = math.Round(res.Elapsed.Seconds()*1000) / 1000
+	if res.Elapsed > 0 {
+		rep.Rps = math.Round(float64(rep.All)/res.Elapsed.Seconds()*100) / 100
+	}
+	res.Stats.fill(&rep)
+	if res.Warmup != nil {
+		rep.Warmup = newWarmupStats(*res.Warmup)
+	}
+	rep.Thresholds = checkThresholds(thresholds, rep)
+
+	return rep, nil
+}
+
+// merge добавляет результат нагрузки, выполнявшейся параллельно
+func (r *runResult) merge(other runResult) {
+	success := r.Report.Success + other.Report.Success
+	if success > 0 {
+		r.Report.AvgResponseTime = (r.Report.AvgResponseTime*time.Duration(r.Report.Success) +
+			other.Report.AvgResponseTime*time.Duration(other.Report.Success)) / time.Duration(success)
+	}
+	r.Report.Success = success
+	r.Report.Cancelled += other.Report.Cancelled
+	r.Report.Errors += other.Report.Errors
+	r.Report.All += other.Report.All
+
+	// воркеры подстраиваются под цель независимо, их устойчивые нагрузки складываются
+	if a := other.Report.Adaptive; a != nil {
+		if r.Report.Adaptive == nil {
+			r.Report.Adaptive = &httploader.AdaptiveReport{Target: a.Target}
+		}
+		r.Report.Adaptive.Concurrency += a.Concurrency
+		r.Report.Adaptive.Rps += a.Rps
+	}
+
+	if rt := other.Report.Retry; rt != nil {
+		if r.Report.Retry == nil {
+			r.Report.Retry = &httploader.RetryReport{}
+		}
+		r.Report.Retry.FirstTry += rt.FirstTry
+		r.Report.Retry.AfterRetry += rt.AfterRetry
+		r.Report.Retry.Failed += rt.Failed
+		r.Report.Retry.Retries += rt.Retries
+	}
+
+	// воркеры ограничиваются сервером независимо, принятые без ограничения нагрузки складываются
+	if th := other.Report.Throttle; th != nil {
+		if r.Report.Throttle == nil {
+			r.Report.Throttle = &httploader.ThrottleReport{}
+		}
+		r.Report.Throttle.Events += th.Events
+		r.Report.Throttle.ThrottledTime += th.ThrottledTime
+		r.Report.Throttle.AllowedRate += th.AllowedRate
+	}
+
+	if c := other.Report.Connections; c != nil {
+		if r.Report.Connections == nil {
+			r.Report.Connections = &httploader.ConnectionReport{}
+		}
+		conns := r.Report.Connections
+		if opened := conns.Opened + c.Opened; opened > 0 {
+			conns.ConnectTime = (conns.ConnectTime*time.Duration(conns.Opened) + c.ConnectTime*time.Duration(c.Opened)) / time.Duration(opened)
+		}
+		// перцентили с разных машин точно не объединить, берётся среднее, взвешенное по количеству сообщений
+		if messages := conns.Messages + c.Messages; messages > 0 {
+			conns.InterEventP50 = (conns.InterEventP50*time.Duration(conns.Messages) + c.InterEventP50*time.Duration(c.Messages)) / time.Duration(messages)
+			conns.InterEventP99 = (conns.InterEventP99*time.Duration(conns.Messages) + c.InterEventP99*time.Duration(c.Messages)) / time.Duration(messages)
+		}
+		conns.Opened += c.Opened
+		conns.Failed += c.Failed
+		conns.Dropped += c.Dropped
+		conns.Messages += c.Messages
+		conns.MessageRate += c.MessageRate
+	}
+
+	if s := other.Report.Socket; s != nil {
+		if r.Report.Socket == nil {
+			r.Report.Socket = &httploader.SocketReport{}
+		}
+		socket := r.Report.Socket
+		socket.Sent += s.Sent
+		socket.Lost += s.Lost
+		socket.BytesSent += s.BytesSent
+		socket.BytesReceived += s.BytesReceived
+		socket.Throughput += s.Throughput
+		if socket.Sent > 0 {
+			socket.LossRate = math.Round(float64(socket.Lost)/float64(socket.Sent)*10000) / 100
+		}
+	}
+
+	if other.Elapsed > r.Elapsed {
+		r.Elapsed = other.Elapsed
+	}
+
+	if r.Stats.Latency == nil {
+		r.Stats = newStats()
+	}
+	r.Stats.merge(other.Stats)
+
+	if other.Warmup != nil {
+		if r.Warmup == nil {
+			r.Warmup = &runResult{}
+		}
+		r.Warmup.merge(*other.Warmup)
+	}
 }
 
 func makeLoad(ctx context.Context, cfg config, loader httploader.Loader) (rep httploader.Report, err error) {
-	var h *http.Header
+	h, body, err := readInput(cfg)
+	if err != nil {
+		return httploader.Report{}, err
+	}
+
+	rep, err = loader.Load(ctx, targetURL(cfg), h, body)
+	if err != nil {
+		return httploader.Report{}, fmt.Errorf("load: %w", err)
+	}
+
+	return rep, nil
+}
+
+// readInput заголовки и тело запроса, файлы из cfg имеют приоритет над уже прочитанными данными
+func readInput(cfg config) (h *http.Header, body []byte, err error) {
+	h, body = cfg.headers, cfg.body
 	if cfg.headersPath != "" {
 		if h, err = headers.ReadFromFile(cfg.headersPath); err != nil {
-			return httploader.Report{}, fmt.Errorf("read headers:%w", err)
+			return nil, nil, fmt.Errorf("read headers:%w", err)
 		}
 	}
 
-	var body []byte
 	if cfg.bodyPath != "" {
 		if body, err = readBody(cfg.bodyPath); err != nil {
-			return httploader.Report{}, fmt.Errorf("read body: %w", err)
+			return nil, nil, fmt.Errorf("read body: %w", err)
+		}
+		if cfg.graphql && isGraphQLQuery(cfg.bodyPath) {
+			if body, err = graphQLBody(body, cfg.graphqlVarsPath); err != nil {
+				return nil, nil, fmt.Errorf("read graphql: %w", err)
+			}
+		}
+	}
+
+	return h, body, nil
+}
+
+// isGraphQLQuery файл содержит текст операции GraphQL, а не тело запроса в json
+func isGraphQLQuery(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".graphql" || ext == ".gql"
+}
+
+// graphQLBody тело запроса GraphQL из текста операции и переменных из файла varsPath
+func graphQLBody(query []byte, varsPath string) ([]byte, error) {
+	var vars []byte
+	if varsPath != "" {
+		var err error
+		if vars, err = readBody(varsPath); err != nil {
+			return nil, err
 		}
 	}
 
-	rep, err = loader.Load(ctx, cfg.host, h, body)
+	return httploader.GraphQLBody(query, vars)
+}
+
+func readConfigBaseline(cfg config) (*formatter.Report, error) {
+	if cfg.baselinePath == "" {
+		return nil, nil
+	}
+
+	baseline, err := readBaseline(cfg.baselinePath)
 	if err != nil {
-		return httploader.Report{}, fmt.Errorf("load: %w", err)
+		return nil, fmt.Errorf("read baseline: %w", err)
 	}
 
-	return rep, nil
+	return baseline, nil
+}
+
+// readBaseline читает отчёт предыдущей нагрузки, сохранённый в формате json
+func readBaseline(path string) (*formatter.Report, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rep formatter.Report
+	if err = json.Unmarshal(raw, &rep); err != nil {
+		return nil, err
+	}
+
+	return &rep, nil
 }
 
 func readBody(path string) ([]byte, error) {
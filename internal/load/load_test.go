@@ -1,6 +1,7 @@
 package load
 
 import (
+	"benchutil/pkg/formatter"
 	"benchutil/pkg/httploader"
 	"context"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"testing"
+	"time"
 )
 
 func TestMakeLoadFunc(t *testing.T) {
@@ -52,6 +54,17 @@ func TestMakeLoadFunc(t *testing.T) {
 			expectedResult: httploader.Report{},
 			cfg:            config{host: "hostload"},
 		},
+		{
+			name: "ok, unix socket host",
+			setupFunc: func(mc *gomock.Controller) *httploader.MockLoader {
+				loader := httploader.NewMockLoader(mc)
+
+				loader.EXPECT().Load(ctx, "http://localhost/health", nil, nil).Return(httploader.Report{}, nil)
+				return loader
+			},
+			expectedResult: httploader.Report{},
+			cfg:            config{host: "unix:///tmp/app.sock:/health"},
+		},
 		{
 			name: "ok, with headers",
 			setupFunc: func(mc *gomock.Controller) *httploader.MockLoader {
@@ -85,6 +98,18 @@ func TestMakeLoadFunc(t *testing.T) {
 			},
 			cfg: config{host: "loadhost", bodyPath: "testdata/body.txt", headersPath: "testdata/headers.json"},
 		},
+		{
+			name: "ok, graphql query with variables",
+			setupFunc: func(mc *gomock.Controller) *httploader.MockLoader {
+				loader := httploader.NewMockLoader(mc)
+
+				body := []byte(`{"query":"query GetUser($id: ID!) { user(id: $id) { name } }\n","variables":{"id":"42"}}`)
+				loader.EXPECT().Load(ctx, "", nil, body).Return(httploader.Report{}, nil)
+
+				return loader
+			},
+			cfg: config{graphql: true, bodyPath: "testdata/query.graphql", graphqlVarsPath: "testdata/variables.json"},
+		},
 		{
 			name: "ok, return not changed report",
 			setupFunc: func(mc *gomock.Controller) *httploader.MockLoader {
@@ -114,3 +139,47 @@ func TestMakeLoadFunc(t *testing.T) {
 		})
 	}
 }
+
+func TestReadBaseline(t *testing.T) {
+	rep, err := readBaseline("testdata/baseline.json")
+
+	require.NoError(t, err)
+	require.Equal(t, &formatter.Report{
+		Success:     9,
+		Errors:      1,
+		All:         10,
+		Rps:         41.5,
+		Latency:     &formatter.LatencyStats{Min: 1, Mean: 5, P50: 4, P90: 8, P95: 9, P99: 10, Max: 12},
+		StatusCodes: map[int]int{200: 9, 500: 1},
+	}, rep)
+
+	_, err = readBaseline("testdata/body.txt")
+	require.Error(t, err)
+}
+
+func TestRunResultMerge(t *testing.T) {
+	var res runResult
+	res.merge(runResult{
+		Report:  httploader.Report{All: 4, Success: 2, Errors: 2, AvgResponseTime: time.Second, Adaptive: &httploader.AdaptiveReport{Target: time.Second, Concurrency: 3, Rps: 10}, Retry: &httploader.RetryReport{FirstTry: 1, AfterRetry: 1, Failed: 2, Retries: 5}, Throttle: &httploader.ThrottleReport{Events: 2, ThrottledTime: time.Second, AllowedRate: 1.5}, Connections: &httploader.ConnectionReport{Opened: 1, ConnectTime: time.Millisecond, Messages: 4, MessageRate: 2, InterEventP50: 10 * time.Millisecond, InterEventP99: 20 * time.Millisecond}, Socket: &httploader.SocketReport{Sent: 4, Lost: 1, LossRate: 25, BytesSent: 40, BytesReceived: 30, Throughput: 35}},
+		Elapsed: 2 * time.Second,
+		Stats:   newStats(),
+	})
+	res.merge(runResult{
+		Report:  httploader.Report{All: 6, Success: 6, AvgResponseTime: 3 * time.Second, Adaptive: &httploader.AdaptiveReport{Target: time.Second, Concurrency: 5, Rps: 20.5}, Retry: &httploader.RetryReport{FirstTry: 6}, Throttle: &httploader.ThrottleReport{Events: 1, ThrottledTime: time.Second, AllowedRate: 2}, Connections: &httploader.ConnectionReport{Opened: 3, Failed: 1, Dropped: 1, ConnectTime: 5 * time.Millisecond, Messages: 6, MessageRate: 3, InterEventP50: 20 * time.Millisecond, InterEventP99: 40 * time.Millisecond}, Socket: &httploader.SocketReport{Sent: 6, BytesSent: 60, BytesReceived: 60, Throughput: 120}},
+		Elapsed: time.Second,
+		Stats:   newStats(),
+	})
+
+	require.Equal(t, httploader.Report{
+		All:             10,
+		Success:         8,
+		Errors:          2,
+		AvgResponseTime: 2500 * time.Millisecond,
+		Adaptive:        &httploader.AdaptiveReport{Target: time.Second, Concurrency: 8, Rps: 30.5},
+		Retry:           &httploader.RetryReport{FirstTry: 7, AfterRetry: 1, Failed: 2, Retries: 5},
+		Throttle:        &httploader.ThrottleReport{Events: 3, ThrottledTime: 2 * time.Second, AllowedRate: 3.5},
+		Connections:     &httploader.ConnectionReport{Opened: 4, Failed: 1, Dropped: 1, ConnectTime: 4 * time.Millisecond, Messages: 10, MessageRate: 5, InterEventP50: 16 * time.Millisecond, InterEventP99: 32 * time.Millisecond},
+		Socket:          &httploader.SocketReport{Sent: 10, Lost: 1, LossRate: 10, BytesSent: 100, BytesReceived: 90, Throughput: 155},
+	}, res.Report)
+	require.Equal(t, 2*time.Second, res.Elapsed)
+}
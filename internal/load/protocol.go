@@ -0,0 +1,85 @@
+package load
+
+import (
+	"benchutil/pkg/histogram"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// сообщения протокола между координатором и воркером
+// координатор: prepare -> (ready) -> start -> [stop], prepare несёт токен воркера, если он задан
+// воркер: ready, progress каждую секунду, в конце result или error
+const (
+	msgPrepare  = "prepare"
+	msgReady    = "ready"
+	msgStart    = "start"
+	msgStop     = "stop"
+	msgProgress = "progress"
+	msgResult   = "result"
+	msgError    = "error"
+)
+
+// message сообщение протокола, по соединению передаётся json, одно сообщение на строку
+type message struct {
+	Type string `json:"type"`
+
+	// prepare: аргументы команды load для воркера, тело и заголовки запроса
+	Args    []string     `json:"args,omitempty"`
+	Headers *http.Header `json:"headers,omitempty"`
+	Body    []byte       `json:"body,omitempty"`
+	Token   string       `json:"token,omitempty"`
+
+	Progress *progress  `json:"progress,omitempty"`
+	Result   *runResult `json:"result,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// progress промежуточный результат воркера
+type progress struct {
+	Requests int                  `json:"requests"`
+	Errors   int                  `json:"errors"`
+	Latency  *histogram.Histogram `json:"latency"`
+}
+
+// peer соединение с поддержкой конкурентной отправки сообщений
+type peer struct {
+	conn net.Conn
+	dec  *json.Decoder
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newPeer(conn net.Conn) *peer {
+	return &peer{conn: conn, dec: json.NewDecoder(conn), enc: json.NewEncoder(conn)}
+}
+
+func (p *peer) send(msg message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.enc.Encode(msg)
+}
+
+func (p *peer) receive() (message, error) {
+	var msg message
+	err := p.dec.Decode(&msg)
+
+	return msg, err
+}
+
+func (p *peer) close() error {
+	return p.conn.Close()
+}
+
+func (st stats) progress() *progress {
+	p := &progress{Latency: st.Latency}
+	for _, sec := range st.Seconds {
+		p.Requests += sec.Requests
+		p.Errors += sec.Errors
+	}
+
+	return p
+}
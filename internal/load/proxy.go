@@ -0,0 +1,62 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/httploader"
+	"fmt"
+	"net/url"
+)
+
+// proxyFlags флаги прокси для соединений с нагружаемым сервером, общие для load и grpc
+func proxyFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.StringFlag{
+			Name:        "proxy",
+			Destination: &cfg.proxy,
+			Usage:       "Прокси для всех соединений: http(s)://[user:password@]host:port - туннель CONNECT, socks5://[user:password@]host:port - SOCKS5",
+		},
+		cli.BoolFlag{
+			Name:        "proxy-env",
+			Destination: &cfg.proxyEnv,
+			Usage:       "Без -proxy соединять WebSocket, TCP и gRPC через прокси из HTTP_PROXY и HTTPS_PROXY с учётом NO_PROXY, запросы http используют их и без флага",
+		},
+		cli.BoolFlag{
+			Name:        "no-proxy-env",
+			Destination: &cfg.noProxyEnv,
+			Usage:       "Не использовать прокси из HTTP_PROXY, HTTPS_PROXY и NO_PROXY, в том числе для запросов http",
+		},
+	}
+}
+
+// parseProxy разбирает адрес прокси, для пустой строки - nil
+func parseProxy(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse %q: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+		return nil, fmt.Errorf("unsupported scheme %q, expected http, https or socks5", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("%q must contain host and port", raw)
+	}
+
+	return u, nil
+}
+
+// proxyOptions опции httploader для флагов -proxy, -proxy-env и -no-proxy-env, cfg уже проверен validateConfig
+// без флагов запросы http, как и раньше, идут через прокси из окружения средствами транспорта
+func proxyOptions(cfg config) []httploader.Option {
+	if u, _ := parseProxy(cfg.proxy); u != nil {
+		return []httploader.Option{httploader.WithProxy(u)}
+	}
+	if cfg.proxyEnv || cfg.noProxyEnv {
+		return []httploader.Option{httploader.WithEnvProxy(cfg.proxyEnv)}
+	}
+
+	return nil
+}
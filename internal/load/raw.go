@@ -0,0 +1,184 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rawFormats форматы файла с результатами отдельных запросов, формат определяется по расширению файла
+var rawFormats = map[string]func(w io.Writer) rawEncoder{
+	"csv":   newCsvEncoder,
+	"jsonl": newJsonlEncoder,
+}
+
+// rawRecord запись о результате одного запроса, время в миллисекундах
+type rawRecord struct {
+	Timestamp  string  `json:"timestamp"`
+	Worker     int     `json:"worker"`
+	URL        string  `json:"url"`
+	Method     string  `json:"method"`
+	Status     int     `json:"status"`
+	DNS        float64 `json:"dns"`
+	Connect    float64 `json:"connect"`
+	Proxy      float64 `json:"proxy"`
+	TLS        float64 `json:"tls"`
+	FirstByte  float64 `json:"firstByte"`
+	Latency    float64 `json:"latency"`
+	Bytes      int64   `json:"bytes"`
+	ErrorClass string  `json:"errorClass"`
+	Error      string  `json:"error"`
+}
+
+var rawColumns = []string{"timestamp", "worker", "url", "method", "status", "dns", "connect", "proxy", "tls", "firstByte", "latency", "bytes", "errorClass", "error"}
+
+type rawEncoder interface {
+	encode(rec rawRecord) error
+	flush() error
+}
+
+// rawSink пишет результат каждого запроса в файл, безопасен для использования из нескольких горутин
+// первая ошибка записи запоминается и возвращается из close
+type rawSink struct {
+	mu sync.Mutex
+
+	file *os.File
+	enc  rawEncoder
+	err  error
+}
+
+func rawFormat(path string) string {
+	return strings.Replace(filepath.Ext(path), ".", "", -1)
+}
+
+func newRawSink(path string) (*rawSink, error) {
+	newEncoder, ok := rawFormats[rawFormat(path)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported raw format %s", rawFormat(path))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create %s file:%w", path, err)
+	}
+
+	return &rawSink{file: f, enc: newEncoder(f)}, nil
+}
+
+func (s *rawSink) observe(sample httploader.Sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.encode(newRawRecord(sample))
+}
+
+func (s *rawSink) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = s.enc.flush()
+	}
+	if err := s.file.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+
+	return s.err
+}
+
+func newRawRecord(s httploader.Sample) rawRecord {
+	rec := rawRecord{
+		Timestamp:  s.Start.UTC().Format(time.RFC3339Nano),
+		Worker:     s.Worker,
+		URL:        s.URL,
+		Method:     s.Method,
+		Status:     s.Status,
+		DNS:        toMs(s.Phases.DNS),
+		Connect:    toMs(s.Phases.Connect),
+		Proxy:      toMs(s.Phases.Proxy),
+		TLS:        toMs(s.Phases.TLS),
+		FirstByte:  toMs(s.Phases.FirstByte),
+		Latency:    toMs(s.Latency),
+		Bytes:      s.Bytes,
+		ErrorClass: s.ErrorClass(),
+	}
+	if s.Err != nil {
+		rec.Error = s.Err.Error()
+	}
+
+	return rec
+}
+
+type csvEncoder struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCsvEncoder(w io.Writer) rawEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) encode(rec rawRecord) error {
+	if !e.headerWritten {
+		if err := e.w.Write(rawColumns); err != nil {
+			return err
+		}
+		e.headerWritten = true
+	}
+
+	return e.w.Write([]string{
+		rec.Timestamp,
+		strconv.Itoa(rec.Worker),
+		rec.URL,
+		rec.Method,
+		strconv.Itoa(rec.Status),
+		formatMs(rec.DNS),
+		formatMs(rec.Connect),
+		formatMs(rec.Proxy),
+		formatMs(rec.TLS),
+		formatMs(rec.FirstByte),
+		formatMs(rec.Latency),
+		strconv.FormatInt(rec.Bytes, 10),
+		rec.ErrorClass,
+		rec.Error,
+	})
+}
+
+func (e *csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonlEncoder struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJsonlEncoder(w io.Writer) rawEncoder {
+	buf := bufio.NewWriter(w)
+	return &jsonlEncoder{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (e *jsonlEncoder) encode(rec rawRecord) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *jsonlEncoder) flush() error {
+	return e.buf.Flush()
+}
+
+func formatMs(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,80 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRawSink(t *testing.T) {
+	start := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	samples := []httploader.Sample{
+		{
+			Start:   start,
+			Worker:  1,
+			URL:     "http://host/path",
+			Method:  "GET",
+			Status:  200,
+			Latency: 12500 * time.Microsecond,
+			Phases:  httploader.Phases{DNS: time.Millisecond, Connect: 2 * time.Millisecond, Proxy: 3 * time.Millisecond, FirstByte: 10 * time.Millisecond},
+			Bytes:   42,
+		},
+		{
+			Start:   start.Add(time.Second),
+			URL:     "http://host/path",
+			Method:  "GET",
+			Latency: time.Millisecond,
+			Err:     errors.New("connection refused"),
+		},
+	}
+
+	type testCase struct {
+		name     string
+		file     string
+		expected string
+	}
+
+	cases := [...]testCase{
+		{
+			name: "csv",
+			file: "raw.csv",
+			expected: `timestamp,worker,url,method,status,dns,connect,proxy,tls,firstByte,latency,bytes,errorClass,error
+2022-05-01T10:00:00Z,1,http://host/path,GET,200,1,2,3,0,10,12.5,42,,
+2022-05-01T10:00:01Z,0,http://host/path,GET,0,0,0,0,0,0,1,0,network,connection refused
+`,
+		},
+		{
+			name: "jsonl",
+			file: "raw.jsonl",
+			expected: `{"timestamp":"2022-05-01T10:00:00Z","worker":1,"url":"http://host/path","method":"GET","status":200,"dns":1,"connect":2,"proxy":3,"tls":0,"firstByte":10,"latency":12.5,"bytes":42,"errorClass":"","error":""}
+{"timestamp":"2022-05-01T10:00:01Z","worker":0,"url":"http://host/path","method":"GET","status":0,"dns":0,"connect":0,"proxy":0,"tls":0,"firstByte":0,"latency":1,"bytes":0,"errorClass":"network","error":"connection refused"}
+`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			sink, err := newRawSink(path)
+			require.NoError(t, err)
+
+			for _, s := range samples {
+				sink.observe(s)
+			}
+			require.NoError(t, sink.close())
+
+			raw, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(raw))
+		})
+	}
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := newRawSink(filepath.Join(t.TempDir(), "raw.xml"))
+		require.Equal(t, errors.New("unsupported raw format xml"), err)
+	})
+}
@@ -1,70 +1,54 @@
 package load
 
 import (
+	"benchutil/pkg/formatter"
 	"benchutil/pkg/httploader"
-	"encoding/json"
 	"fmt"
-	"gopkg.in/yaml.v2"
 	"math"
+	"os"
+	"strings"
 )
 
-const (
-	outputYaml  = "yaml"
-	outputJson  = "json"
-	outputHuman = "human"
-)
-
-var outputFormats = map[string]struct{}{
-	outputJson:  {},
-	outputYaml:  {},
-	outputHuman: {},
-}
-
-type report struct {
-	Success     int `json:"success" yaml:"success"`
-	Canceled    int `json:"canceled" yaml:"canceled"`
-	Errors      int `json:"errors" yaml:"errors"`
-	All         int `json:"all" yaml:"all"`
-	AvgRespTime int `json:"avgRespTime" yaml:"avgRespTime"`
+// output формат отчёта и файл для записи, при пустом path отчёт пишется в stdout
+// в командной строке задаётся как "format" или "format=path"
+type output struct {
+	format string
+	path   string
 }
 
-func (rep report) toBytes(format string) ([]byte, error) {
-	if format == outputJson {
-		return rep.toJson()
+func parseOutput(raw string) output {
+	parts := strings.SplitN(raw, "=", 2)
+	out := output{format: parts[0]}
+	if len(parts) == 2 {
+		out.path = parts[1]
 	}
 
-	if format == outputYaml {
-		return rep.toYaml()
-	}
-
-	if format == outputHuman {
-		return rep.toHuman()
-	}
-
-	return nil, fmt.Errorf("unknown format: %s", format)
-}
-
-func (rep report) toHuman() ([]byte, error) {
-	messageFormat := "Всего запросов: %d \nИз них \nУспешно: %d \nС ошибкой: %d \nОтменённых: %d \nСреднее время запроса(сек): %d"
-
-	return []byte(fmt.Sprintf(messageFormat, rep.All, rep.Success, rep.Errors, rep.Canceled, rep.AvgRespTime)), nil
-}
-
-func (rep report) toJson() ([]byte, error) {
-	return json.MarshalIndent(&rep, "", " ")
+	return out
 }
 
-func (rep report) toYaml() ([]byte, error) {
-	return yaml.Marshal(&rep)
-}
+func writeOutputs(rep formatter.Report, outputs []string) error {
+	for _, raw := range outputs {
+		out := parseOutput(raw)
+		result, err := formatter.Format(out.format, rep)
+		if err != nil {
+			return err
+		}
+
+		if out.path == "" {
+			_, err = os.Stdout.Write(result)
+		} else {
+			err = os.WriteFile(out.path, result, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("write %s output: %w", out.format, err)
+		}
+	}
 
-func readResult(report httploader.Report, format string) ([]byte, error) {
-	rep := loaderReportToInternal(report)
-	return rep.toBytes(format)
+	return nil
 }
 
-func loaderReportToInternal(loaderRep httploader.Report) report {
-	rep := report{
+func loaderReportToInternal(loaderRep httploader.Report) formatter.Report {
+	rep := formatter.Report{
 		Success:  loaderRep.Success,
 		Errors:   loaderRep.Errors,
 		Canceled: loaderRep.Cancelled,
@@ -75,5 +59,54 @@ func loaderReportToInternal(loaderRep httploader.Report) report {
 
 	rep.AvgRespTime = avgT
 
+	if a := loaderRep.Adaptive; a != nil {
+		rep.Adaptive = &formatter.AdaptiveStats{
+			TargetP95:   toMs(a.Target),
+			Concurrency: a.Concurrency,
+			Rps:         a.Rps,
+		}
+	}
+
+	if r := loaderRep.Retry; r != nil {
+		rep.Retry = &formatter.RetryStats{
+			FirstTry:   r.FirstTry,
+			AfterRetry: r.AfterRetry,
+			Failed:     r.Failed,
+			Retries:    r.Retries,
+		}
+	}
+
+	if c := loaderRep.Connections; c != nil {
+		rep.Connections = &formatter.ConnectionStats{
+			Opened:        c.Opened,
+			Failed:        c.Failed,
+			Dropped:       c.Dropped,
+			ConnectTime:   toMs(c.ConnectTime),
+			Messages:      c.Messages,
+			MessageRps:    c.MessageRate,
+			InterEventP50: toMs(c.InterEventP50),
+			InterEventP99: toMs(c.InterEventP99),
+		}
+	}
+
+	if s := loaderRep.Socket; s != nil {
+		rep.Socket = &formatter.SocketStats{
+			Sent:          s.Sent,
+			Lost:          s.Lost,
+			LossRate:      s.LossRate,
+			BytesSent:     s.BytesSent,
+			BytesReceived: s.BytesReceived,
+			Throughput:    s.Throughput,
+		}
+	}
+
+	if th := loaderRep.Throttle; th != nil {
+		rep.Throttle = &formatter.ThrottleStats{
+			Events:        th.Events,
+			ThrottledTime: math.Round(th.ThrottledTime.Seconds()*1000) / 1000,
+			AllowedRps:    th.AllowedRate,
+		}
+	}
+
 	return rep
 }
@@ -1,9 +1,12 @@
 package load
 
 import (
+	"benchutil/pkg/formatter"
 	"benchutil/pkg/httploader"
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -11,7 +14,7 @@ import (
 func TestLoaderReportToInternal(t *testing.T) {
 	type testCase struct {
 		name             string
-		expectedInternal report
+		expectedInternal formatter.Report
 		loaderReport     httploader.Report
 	}
 
@@ -24,7 +27,7 @@ func TestLoaderReportToInternal(t *testing.T) {
 				Cancelled:       3,
 				AvgResponseTime: time.Duration(10) * time.Second,
 			},
-			expectedInternal: report{
+			expectedInternal: formatter.Report{
 				All:         1,
 				Success:     2,
 				Canceled:    3,
@@ -39,114 +42,112 @@ func TestLoaderReportToInternal(t *testing.T) {
 				Cancelled:       3,
 				AvgResponseTime: time.Duration(10),
 			},
-			expectedInternal: report{
+			expectedInternal: formatter.Report{
 				All:         1,
 				Success:     2,
 				Canceled:    3,
 				AvgRespTime: 0,
 			},
 		},
-	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			internalRep := loaderReportToInternal(tc.loaderReport)
-
-			require.Equal(t, tc.expectedInternal, internalRep)
-		})
-	}
-}
-
-func TestReportToFormats(t *testing.T) {
-	type testCase struct {
-		name        string
-		rep         report
-		format      string
-		expectedRes []byte
-		expectedErr error
-	}
-
-	const (
-		jsonOutPut = `{
- "success": 0,
- "canceled": 2,
- "errors": 3,
- "all": 1,
- "avgRespTime": 0
-}`
-		yamlOutPut = `success: 0
-canceled: 123
-errors: 9
-all: 4
-avgRespTime: 0
-`
-
-		humanOutPut = `Всего запросов: 5 
-Из них 
-Успешно: 0 
-С ошибкой: 234 
-Отменённых: 321 
-Среднее время запроса(сек): 0`
-
-		humanAllZeroOutput = `Всего запросов: 0 
-Из них 
-Успешно: 0 
-С ошибкой: 0 
-Отменённых: 0 
-Среднее время запроса(сек): 0`
-	)
-
-	cases := [...]testCase{
 		{
-			name: "ok, normal json format",
-			rep: report{
+			name: "ok, adaptive load",
+			loaderReport: httploader.Report{
+				All:      1,
+				Success:  1,
+				Adaptive: &httploader.AdaptiveReport{Target: 250 * time.Millisecond, Concurrency: 12, Rps: 812.5},
+			},
+			expectedInternal: formatter.Report{
 				All:      1,
-				Canceled: 2,
-				Errors:   3,
+				Success:  1,
+				Adaptive: &formatter.AdaptiveStats{TargetP95: 250, Concurrency: 12, Rps: 812.5},
 			},
-			format:      "json",
-			expectedRes: []byte(jsonOutPut),
 		},
 		{
-			name: "ok, normal yaml format",
-			rep: report{
-				All:      4,
-				Canceled: 123,
-				Errors:   9,
+			name: "ok, load with retries",
+			loaderReport: httploader.Report{
+				All:    3,
+				Errors: 1,
+				Retry:  &httploader.RetryReport{FirstTry: 1, AfterRetry: 1, Failed: 1, Retries: 3},
+			},
+			expectedInternal: formatter.Report{
+				All:    3,
+				Errors: 1,
+				Retry:  &formatter.RetryStats{FirstTry: 1, AfterRetry: 1, Failed: 1, Retries: 3},
 			},
-			format:      "yaml",
-			expectedRes: []byte(yamlOutPut),
 		},
 		{
-			name: "ok, normal human format",
-			rep: report{
-				All:      5,
-				Canceled: 321,
-				Errors:   234,
+			name: "ok, websocket load",
+			loaderReport: httploader.Report{
+				All:         5,
+				Success:     5,
+				Connections: &httploader.ConnectionReport{Opened: 2, ConnectTime: 1500 * time.Microsecond, Messages: 5, MessageRate: 50, InterEventP50: 2 * time.Millisecond, InterEventP99: 9500 * time.Microsecond},
+			},
+			expectedInternal: formatter.Report{
+				All:         5,
+				Success:     5,
+				Connections: &formatter.ConnectionStats{Opened: 2, ConnectTime: 1.5, Messages: 5, MessageRps: 50, InterEventP50: 2, InterEventP99: 9.5},
 			},
-			format:      "human",
-			expectedRes: []byte(humanOutPut),
 		},
 		{
-			name:        "ok, empty report human format",
-			rep:         report{},
-			format:      "human",
-			expectedRes: []byte(humanAllZeroOutput),
+			name: "ok, udp load",
+			loaderReport: httploader.Report{
+				All:         4,
+				Success:     3,
+				Cancelled:   1,
+				Connections: &httploader.ConnectionReport{Opened: 1, Messages: 3, MessageRate: 30},
+				Socket:      &httploader.SocketReport{Sent: 4, Lost: 1, LossRate: 25, BytesSent: 16, BytesReceived: 12, Throughput: 280},
+			},
+			expectedInternal: formatter.Report{
+				All:         4,
+				Success:     3,
+				Canceled:    1,
+				Connections: &formatter.ConnectionStats{Opened: 1, Messages: 3, MessageRps: 30},
+				Socket:      &formatter.SocketStats{Sent: 4, Lost: 1, LossRate: 25, BytesSent: 16, BytesReceived: 12, Throughput: 280},
+			},
 		},
 		{
-			name:        "error, unknown format",
-			rep:         report{},
-			format:      "xml",
-			expectedErr: fmt.Errorf("unknown format: %s", "xml"),
+			name: "ok, load with backpressure",
+			loaderReport: httploader.Report{
+				All:      3,
+				Throttle: &httploader.ThrottleReport{Events: 2, ThrottledTime: 1500 * time.Millisecond, AllowedRate: 0.67},
+			},
+			expectedInternal: formatter.Report{
+				All:      3,
+				Throttle: &formatter.ThrottleStats{Events: 2, ThrottledTime: 1.5, AllowedRps: 0.67},
+			},
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			output, err := tc.rep.toBytes(tc.format)
+			internalRep := loaderReportToInternal(tc.loaderReport)
 
-			require.Equal(t, tc.expectedRes, output)
-			require.Equal(t, tc.expectedErr, err)
+			require.Equal(t, tc.expectedInternal, internalRep)
 		})
 	}
 }
+
+func TestParseOutput(t *testing.T) {
+	require.Equal(t, output{format: "json"}, parseOutput("json"))
+	require.Equal(t, output{format: "html", path: "out/report.html"}, parseOutput("html=out/report.html"))
+	require.Equal(t, output{format: "junit", path: "a=b.xml"}, parseOutput("junit=a=b.xml"))
+}
+
+func TestWriteOutputs(t *testing.T) {
+	dir := t.TempDir()
+	rep := formatter.Report{All: 2, Success: 2}
+
+	err := writeOutputs(rep, []string{"json=" + filepath.Join(dir, "report.json"), "yaml=" + filepath.Join(dir, "report.yaml")})
+	require.NoError(t, err)
+
+	jsonOut, err := os.ReadFile(filepath.Join(dir, "report.json"))
+	require.NoError(t, err)
+	require.Contains(t, string(jsonOut), `"all": 2`)
+
+	yamlOut, err := os.ReadFile(filepath.Join(dir, "report.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(yamlOut), "all: 2")
+
+	err = writeOutputs(rep, []string{"xml=" + filepath.Join(dir, "report.xml")})
+	require.Equal(t, fmt.Errorf("unknown format: %s", "xml"), err)
+}
@@ -0,0 +1,49 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// retryJitter доля паузы перед повтором, на которую она случайно уменьшается
+const retryJitter = 0.5
+
+// retryErrorClasses классы ошибок, которые можно указать в -retry-on, статусы указываются числами
+var retryErrorClasses = map[string]bool{
+	httploader.ErrClassTimeout: true,
+	httploader.ErrClassDNS:     true,
+	httploader.ErrClassNetwork: true,
+	httploader.ErrClassGraphQL: true,
+	httploader.ErrClassAuth:    true,
+}
+
+// retryPolicy политика повторов из флагов, при -retry <= 1 повторов нет
+func (cfg config) retryPolicy() (httploader.RetryPolicy, error) {
+	policy := httploader.RetryPolicy{
+		MaxAttempts:       cfg.retryAttempts,
+		Backoff:           cfg.retryBackoff,
+		MaxBackoff:        cfg.retryMaxBackoff,
+		Jitter:            retryJitter,
+		RespectRetryAfter: cfg.retryAfter,
+	}
+
+	for _, item := range strings.Split(cfg.retryOn, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if status, err := strconv.Atoi(item); err == nil {
+			policy.Statuses = append(policy.Statuses, status)
+			continue
+		}
+		if !retryErrorClasses[item] {
+			return httploader.RetryPolicy{}, fmt.Errorf("unknown retry condition %s", item)
+		}
+		policy.ErrorClasses = append(policy.ErrorClasses, item)
+	}
+
+	return policy, nil
+}
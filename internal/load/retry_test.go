@@ -0,0 +1,53 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	type testCase struct {
+		name        string
+		cfg         config
+		expected    httploader.RetryPolicy
+		expectedErr error
+	}
+
+	cases := [...]testCase{
+		{
+			name: "statuses and error classes",
+			cfg:  config{retryAttempts: 3, retryBackoff: time.Second, retryMaxBackoff: time.Minute, retryOn: "429, 503,timeout,network", retryAfter: true},
+			expected: httploader.RetryPolicy{
+				MaxAttempts:       3,
+				Backoff:           time.Second,
+				MaxBackoff:        time.Minute,
+				Jitter:            retryJitter,
+				Statuses:          []int{429, 503},
+				ErrorClasses:      []string{httploader.ErrClassTimeout, httploader.ErrClassNetwork},
+				RespectRetryAfter: true,
+			},
+		},
+		{
+			name:     "no retry conditions",
+			cfg:      config{retryAttempts: 2},
+			expected: httploader.RetryPolicy{MaxAttempts: 2, Jitter: retryJitter},
+		},
+		{
+			name:        "unknown error class",
+			cfg:         config{retryAttempts: 2, retryOn: "429,status"},
+			expectedErr: errors.New("unknown retry condition status"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := tc.cfg.retryPolicy()
+
+			require.Equal(t, tc.expectedErr, err)
+			require.Equal(t, tc.expected, policy)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// способы выделения ответа TCP в значении флага -frame
+const (
+	frameDelimiter = "delim:"
+	frameLength    = "len:"
+)
+
+// socketNetwork сеть нагрузки по сырому сокету ("tcp" или "udp") по схеме host, пустая строка для остальных схем
+// в такой нагрузке тело запроса отправляется как есть, -n - количество запросов, -c - количество соединений
+func socketNetwork(host string) string {
+	for _, network := range []string{"tcp", "udp"} {
+		if strings.HasPrefix(host, network+"://") {
+			return network
+		}
+	}
+
+	return ""
+}
+
+// parseFraming разбирает способ выделения ответа TCP: "delim:\r\n" - ответ до разделителя,
+// в котором понимаются экранирования Go, "len:4" - ответ с длиной в первых 4 байтах big-endian
+// для пустой строки ответом считается то, что вернуло одно чтение из соединения
+func parseFraming(raw string) (httploader.Framing, error) {
+	switch {
+	case raw == "":
+		return httploader.Framing{}, nil
+	case strings.HasPrefix(raw, frameDelimiter):
+		delim, err := strconv.Unquote(`"` + strings.TrimPrefix(raw, frameDelimiter) + `"`)
+		if err != nil {
+			return httploader.Framing{}, fmt.Errorf("invalid delimiter %q", raw)
+		}
+		if delim == "" {
+			return httploader.Framing{}, fmt.Errorf("empty delimiter %q", raw)
+		}
+
+		return httploader.Framing{Delimiter: []byte(delim)}, nil
+	case strings.HasPrefix(raw, frameLength):
+		size, err := strconv.Atoi(strings.TrimPrefix(raw, frameLength))
+		if err != nil || (size != 1 && size != 2 && size != 4 && size != 8) {
+			return httploader.Framing{}, fmt.Errorf("length prefix size %q must be 1, 2, 4 or 8", raw)
+		}
+
+		return httploader.Framing{LengthPrefix: size}, nil
+	default:
+		return httploader.Framing{}, fmt.Errorf("unknown framing %q, expected delim:<delimiter> or len:<size>", raw)
+	}
+}
@@ -0,0 +1,46 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseFraming(t *testing.T) {
+	type testCase struct {
+		raw         string
+		expected    httploader.Framing
+		expectedErr string
+	}
+
+	cases := [...]testCase{
+		{raw: ""},
+		{raw: `delim:\r\n`, expected: httploader.Framing{Delimiter: []byte("\r\n")}},
+		{raw: `delim:\x00`, expected: httploader.Framing{Delimiter: []byte{0}}},
+		{raw: "delim:END", expected: httploader.Framing{Delimiter: []byte("END")}},
+		{raw: "len:4", expected: httploader.Framing{LengthPrefix: 4}},
+		{raw: "delim:", expectedErr: `empty delimiter "delim:"`},
+		{raw: `delim:\q`, expectedErr: `invalid delimiter "delim:\\q"`},
+		{raw: "len:16", expectedErr: `length prefix size "len:16" must be 1, 2, 4 or 8`},
+		{raw: "lines", expectedErr: `unknown framing "lines", expected delim:<delimiter> or len:<size>`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.raw, func(t *testing.T) {
+			framing, err := parseFraming(tc.raw)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, framing)
+		})
+	}
+}
+
+func TestSocketNetwork(t *testing.T) {
+	require.Equal(t, "tcp", socketNetwork("tcp://localhost:9000"))
+	require.Equal(t, "udp", socketNetwork("udp://localhost:9000"))
+	require.Equal(t, "", socketNetwork("http://localhost:9000"))
+	require.Equal(t, "", socketNetwork("localhost:9000"))
+}
@@ -0,0 +1,46 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/httploader"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// sourceFlags флаги локальных адресов соединений, общие для load и grpc
+func sourceFlags(cfg *config) []cli.CmdFlag {
+	return []cli.CmdFlag{
+		cli.StringsFlag{
+			Name:        "source-ip",
+			Destination: &cfg.sourceIPs,
+			Usage:       "Локальный ip для соединений, через запятую или флагом несколько раз - соединения открываются с адресов по кругу",
+		},
+	}
+}
+
+// parseSourceIPs разбирает значения -source-ip, в каждом может быть несколько ip через запятую
+func parseSourceIPs(raw []string) ([]net.IP, error) {
+	var ips []net.IP
+	for _, value := range raw {
+		for _, s := range strings.Split(value, ",") {
+			ip := net.ParseIP(strings.TrimSpace(s))
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an ip address", s)
+			}
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips, nil
+}
+
+// sourceOptions опции httploader для флага -source-ip, cfg уже проверен validateConfig
+func sourceOptions(cfg config) []httploader.Option {
+	ips, _ := parseSourceIPs(cfg.sourceIPs)
+	if len(ips) == 0 {
+		return nil
+	}
+
+	return []httploader.Option{httploader.WithSourceIPs(ips...)}
+}
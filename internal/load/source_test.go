@@ -0,0 +1,36 @@
+package load
+
+import (
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+)
+
+func TestParseSourceIPs(t *testing.T) {
+	type testCase struct {
+		name        string
+		raw         []string
+		expected    []net.IP
+		expectedErr string
+	}
+
+	cases := [...]testCase{
+		{name: "empty"},
+		{name: "repeated flag", raw: []string{"10.0.0.1", "10.0.0.2"}, expected: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}},
+		{name: "comma separated", raw: []string{"10.0.0.1, 10.0.0.2", "fd00::1"}, expected: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("fd00::1")}},
+		{name: "not an ip", raw: []string{"10.0.0.1,eth0"}, expectedErr: `"eth0" is not an ip address`},
+		{name: "empty item", raw: []string{"10.0.0.1,"}, expectedErr: `"" is not an ip address`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ips, err := parseSourceIPs(tc.raw)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ips)
+		})
+	}
+}
@@ -0,0 +1,392 @@
+package load
+
+import (
+	"benchutil/pkg/formatter"
+	"benchutil/pkg/histogram"
+	"benchutil/pkg/httploader"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxErrorSamples   = 20
+	histogramBarCount = 30
+)
+
+// collector собирает статистику по каждому запросу нагрузки
+// безопасен для использования из нескольких горутин
+// при ненулевом interval дополнительно собирает время ответа с коррекцией coordinated omission
+type collector struct {
+	mu sync.Mutex
+
+	start    time.Time
+	interval time.Duration
+	stats    stats
+	// warm запросы относятся к прогреву, их статистика отдаётся отдельно через endWarmup
+	warm bool
+}
+
+// stats статистика нагрузки, секунды отсчитываются от начала нагрузки
+// сериализуется в json и объединяется через merge, что позволяет собрать единый отчёт с нескольких машин
+// Corrected - время ответа с коррекцией coordinated omission, есть только если известен ожидаемый интервал между запросами
+// Proxy - время открытия туннелей через прокси
+// Codes - коды статуса вызовов gRPC, Operations - запросы по операциям GraphQL, Addresses - по адресам серверов,
+// Sources - по локальным адресам соединений
+type stats struct {
+	Latency    *histogram.Histogram    `json:"latency"`
+	Corrected  *histogram.Histogram    `json:"corrected,omitempty"`
+	Proxy      *histogram.Histogram    `json:"proxy,omitempty"`
+	Seconds    map[int]*secondStats    `json:"seconds"`
+	Statuses   map[int]int             `json:"statuses"`
+	Codes      map[string]int          `json:"codes,omitempty"`
+	Operations map[string]*groupStats  `json:"operations,omitempty"`
+	Addresses  map[string]*groupStats  `json:"addresses,omitempty"`
+	Sources    map[string]*groupStats  `json:"sources,omitempty"`
+	Errors     []formatter.ErrorSample `json:"errors"`
+}
+
+type secondStats struct {
+	Requests int                  `json:"requests"`
+	Errors   int                  `json:"errors"`
+	Latency  *histogram.Histogram `json:"latency"`
+}
+
+// groupStats статистика части запросов, например одной операции GraphQL или одного адреса сервера
+type groupStats struct {
+	Requests int                  `json:"requests"`
+	Errors   int                  `json:"errors"`
+	Latency  *histogram.Histogram `json:"latency"`
+}
+
+// newCollector interval - ожидаемый интервал между запросами одного исполнителя, 0 отключает коррекцию
+func newCollector(start time.Time, interval time.Duration) *collector {
+	return &collector{
+		start:    start,
+		interval: interval,
+		stats:    newStats(),
+	}
+}
+
+func newStats() stats {
+	return stats{
+		Latency:    histogram.New(),
+		Seconds:    make(map[int]*secondStats),
+		Statuses:   make(map[int]int),
+		Codes:      make(map[string]int),
+		Operations: make(map[string]*groupStats),
+		Addresses:  make(map[string]*groupStats),
+		Sources:    make(map[string]*groupStats),
+	}
+}
+
+// observe учитывает только итоговые попытки запросов, повторённые попытки не попадают в статистику,
+// чтобы она сходилась с итогом запросов в httploader.Report
+func (c *collector) observe(s httploader.Sample) {
+	if s.Retried {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	second := int(s.Start.Sub(c.start) / time.Second)
+	if second < 0 {
+		second = 0
+	}
+	sec := c.stats.second(second)
+	sec.Requests++
+
+	if s.Status != 0 {
+		c.stats.Statuses[s.Status]++
+	}
+	if s.Code != "" {
+		c.stats.Codes[s.Code]++
+	}
+	var groups []*groupStats
+	if s.Operation != "" {
+		groups = append(groups, group(&c.stats.Operations, s.Operation))
+	}
+	if s.Addr != "" {
+		groups = append(groups, group(&c.stats.Addresses, s.Addr))
+	}
+	if s.Source != "" {
+		groups = append(groups, group(&c.stats.Sources, s.Source))
+	}
+	for _, g := range groups {
+		g.Requests++
+	}
+	if s.Answered() {
+		c.stats.Latency.Record(s.Latency)
+		sec.Latency.Record(s.Latency)
+		for _, g := range groups {
+			g.Latency.Record(s.Latency)
+		}
+
+		if c.interval > 0 {
+			if c.stats.Corrected == nil {
+				c.stats.Corrected = histogram.New()
+			}
+			c.stats.Corrected.RecordCorrected(s.Latency, c.interval)
+		}
+	}
+
+	if s.Phases.Proxy > 0 {
+		if c.stats.Proxy == nil {
+			c.stats.Proxy = histogram.New()
+		}
+		c.stats.Proxy.Record(s.Phases.Proxy)
+	}
+
+	if s.ErrorClass() == httploader.ErrClassNone {
+		return
+	}
+	sec.Errors++
+	for _, g := range groups {
+		g.Errors++
+	}
+
+	if len(c.stats.Errors) < maxErrorSamples {
+		c.stats.Errors = append(c.stats.Errors, newErrorSample(second, s))
+	}
+}
+
+func (c *collector) beginWarmup() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.warm = true
+}
+
+// endWarmup возвращает статистику прогрева и начинает сбор статистики основной нагрузки с момента start
+func (c *collector) endWarmup(start time.Time) stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	warm := c.stats
+	c.stats = newStats()
+	c.start = start
+	c.warm = false
+
+	return warm
+}
+
+func (c *collector) warming() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.warm
+}
+
+// snapshot копия собранной статистики, которую можно использовать независимо от collector
+func (c *collector) snapshot() stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	st := newStats()
+	st.merge(c.stats)
+
+	return st
+}
+
+// fill дополняет отчёт собранной статистикой
+func (c *collector) fill(rep *formatter.Report) {
+	st := c.snapshot()
+	st.fill(rep)
+}
+
+func (st *stats) second(s int) *secondStats {
+	sec, ok := st.Seconds[s]
+	if !ok {
+		sec = &secondStats{Latency: histogram.New()}
+		st.Seconds[s] = sec
+	}
+
+	return sec
+}
+
+// group статистика группы name из groups, создаётся при первом обращении
+func group(groups *map[string]*groupStats, name string) *groupStats {
+	// в статистике, прочитанной из json, групп может не быть
+	if *groups == nil {
+		*groups = make(map[string]*groupStats)
+	}
+	g, ok := (*groups)[name]
+	if !ok {
+		g = &groupStats{Latency: histogram.New()}
+		(*groups)[name] = g
+	}
+
+	return g
+}
+
+// merge добавляет к статистике other, секунды объединяются по номеру
+func (st *stats) merge(other stats) {
+	st.Latency.Merge(other.Latency)
+
+	if other.Corrected != nil {
+		if st.Corrected == nil {
+			st.Corrected = histogram.New()
+		}
+		st.Corrected.Merge(other.Corrected)
+	}
+
+	if other.Proxy != nil {
+		if st.Proxy == nil {
+			st.Proxy = histogram.New()
+		}
+		st.Proxy.Merge(other.Proxy)
+	}
+
+	for s, otherSec := range other.Seconds {
+		sec := st.second(s)
+		sec.Requests += otherSec.Requests
+		sec.Errors += otherSec.Errors
+		sec.Latency.Merge(otherSec.Latency)
+	}
+
+	for code, count := range other.Statuses {
+		st.Statuses[code] += count
+	}
+
+	// в статистике, прочитанной из json, кодов gRPC может не быть
+	if st.Codes == nil && len(other.Codes) > 0 {
+		st.Codes = make(map[string]int, len(other.Codes))
+	}
+	for code, count := range other.Codes {
+		st.Codes[code] += count
+	}
+
+	mergeGroups(&st.Operations, other.Operations)
+	mergeGroups(&st.Addresses, other.Addresses)
+	mergeGroups(&st.Sources, other.Sources)
+
+	for _, e := range other.Errors {
+		if len(st.Errors) >= maxErrorSamples {
+			break
+		}
+		st.Errors = append(st.Errors, e)
+	}
+}
+
+func (st stats) fill(rep *formatter.Report) {
+	if st.Latency.Total > 0 {
+		rep.Latency = newLatencyStats(st.Latency)
+	}
+
+	if st.Corrected != nil && st.Corrected.Total > 0 {
+		rep.CorrectedLatency = newLatencyStats(st.Corrected)
+	}
+
+	if st.Proxy != nil && st.Proxy.Total > 0 {
+		rep.ProxyConnect = newLatencyStats(st.Proxy)
+	}
+
+	if len(st.Statuses) > 0 {
+		rep.StatusCodes = make(map[int]int, len(st.Statuses))
+		for code, count := range st.Statuses {
+			rep.StatusCodes[code] = count
+		}
+	}
+
+	if len(st.Codes) > 0 {
+		rep.GrpcCodes = make(map[string]int, len(st.Codes))
+		for code, count := range st.Codes {
+			rep.GrpcCodes[code] = count
+		}
+	}
+
+	rep.Operations = groupsReport(st.Operations)
+	// с одним адресом разбивка повторяет общую статистику
+	if len(st.Addresses) > 1 {
+		rep.Addresses = groupsReport(st.Addresses)
+	}
+	if len(st.Sources) > 1 {
+		rep.Sources = groupsReport(st.Sources)
+	}
+
+	last := -1
+	for s := range st.Seconds {
+		if s > last {
+			last = s
+		}
+	}
+	// секунды без запросов тоже попадают в график, чтобы не искажать ось времени
+	for s := 0; s <= last; s++ {
+		sec, ok := st.Seconds[s]
+		if !ok {
+			sec = &secondStats{Latency: histogram.New()}
+		}
+		rep.Timeline = append(rep.Timeline, formatter.TimelinePoint{
+			Second:   s,
+			Requests: sec.Requests,
+			Errors:   sec.Errors,
+			P50:      toMs(sec.Latency.Quantile(0.5)),
+			P90:      toMs(sec.Latency.Quantile(0.9)),
+			P99:      toMs(sec.Latency.Quantile(0.99)),
+		})
+	}
+
+	rep.ErrorSamples = append([]formatter.ErrorSample(nil), st.Errors...)
+}
+
+func mergeGroups(groups *map[string]*groupStats, other map[string]*groupStats) {
+	for name, otherGroup := range other {
+		g := group(groups, name)
+		g.Requests += otherGroup.Requests
+		g.Errors += otherGroup.Errors
+		g.Latency.Merge(otherGroup.Latency)
+	}
+}
+
+// groupsReport статистика групп для отчёта, отсортированная по имени
+func groupsReport(groups map[string]*groupStats) []formatter.GroupStats {
+	var res []formatter.GroupStats
+	for name, g := range groups {
+		res = append(res, formatter.GroupStats{
+			Name:     name,
+			Requests: g.Requests,
+			Errors:   g.Errors,
+			P50:      toMs(g.Latency.Quantile(0.5)),
+			P95:      toMs(g.Latency.Quantile(0.95)),
+			P99:      toMs(g.Latency.Quantile(0.99)),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+
+	return res
+}
+
+func newLatencyStats(h *histogram.Histogram) *formatter.LatencyStats {
+	stats := &formatter.LatencyStats{
+		Min:  toMs(h.Quantile(0)),
+		Mean: toMs(h.Mean()),
+		P50:  toMs(h.Quantile(0.5)),
+		P90:  toMs(h.Quantile(0.9)),
+		P95:  toMs(h.Quantile(0.95)),
+		P99:  toMs(h.Quantile(0.99)),
+		Max:  toMs(h.Quantile(1)),
+	}
+
+	for _, b := range h.Buckets(histogramBarCount) {
+		stats.Histogram = append(stats.Histogram, formatter.HistogramBar{From: toMs(b.From), To: toMs(b.To), Count: b.Count})
+	}
+
+	return stats
+}
+
+func newErrorSample(second int, s httploader.Sample) formatter.ErrorSample {
+	sample := formatter.ErrorSample{Second: second, Status: s.Status}
+	if s.Err != nil {
+		sample.Error = s.Err.Error()
+	} else {
+		sample.Error = fmt.Sprintf("unexpected status %d", s.Status)
+	}
+
+	return sample
+}
+
+func toMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
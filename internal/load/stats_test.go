@@ -0,0 +1,218 @@
+package load
+
+import (
+	"benchutil/pkg/formatter"
+	"benchutil/pkg/histogram"
+	"benchutil/pkg/httploader"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCollectorFill(t *testing.T) {
+	start := time.Unix(100, 0)
+
+	t.Run("empty collector keeps formatter.Report unchanged", func(t *testing.T) {
+		col := newCollector(start, 0)
+		rep := formatter.Report{All: 1}
+
+		col.fill(&rep)
+
+		require.Equal(t, formatter.Report{All: 1}, rep)
+	})
+
+	t.Run("statuses, timeline and errors", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Status: 200, Latency: 10 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start.Add(500 * time.Millisecond), Status: 500, Latency: 20 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start.Add(2 * time.Second), Err: errors.New("connection refused"), Latency: time.Millisecond})
+
+		rep := formatter.Report{}
+		col.fill(&rep)
+
+		require.Equal(t, map[int]int{200: 1, 500: 1}, rep.StatusCodes)
+		require.InDelta(t, 10, rep.Timeline[0].P50, 0.2, "histogram precision is about 1%")
+		rep.Timeline[0].P50 = 10
+		require.Equal(t, []formatter.TimelinePoint{
+			{Second: 0, Requests: 2, Errors: 1, P50: 10, P90: 20, P99: 20},
+			{Second: 1},
+			{Second: 2, Requests: 1, Errors: 1},
+		}, rep.Timeline)
+		require.Equal(t, []formatter.ErrorSample{
+			{Second: 0, Status: 500, Error: "unexpected status 500"},
+			{Second: 2, Error: "connection refused"},
+		}, rep.ErrorSamples)
+
+		require.NotNil(t, rep.Latency)
+		require.Equal(t, 10.0, rep.Latency.Min)
+		require.Equal(t, 20.0, rep.Latency.Max)
+		require.Equal(t, 15.0, rep.Latency.Mean)
+	})
+
+	t.Run("grpc codes", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Code: "OK", Latency: time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Code: "NotFound", Err: errors.New("rpc error: code = NotFound"), Latency: time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Code: "Unavailable", Err: errors.New("rpc error: code = Unavailable")})
+
+		rep := formatter.Report{}
+		col.fill(&rep)
+
+		require.Equal(t, map[string]int{"OK": 1, "NotFound": 1, "Unavailable": 1}, rep.GrpcCodes)
+		require.Nil(t, rep.StatusCodes)
+		require.Equal(t, uint64(2), col.snapshot().Latency.Total, "server answered with error code, but not unavailable")
+
+		// статистика из json без кодов gRPC
+		merged := stats{Latency: histogram.New(), Seconds: map[int]*secondStats{}, Statuses: map[int]int{}}
+		merged.merge(col.snapshot())
+		require.Equal(t, 3, len(merged.Codes))
+	})
+
+	t.Run("graphql operations", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Status: 200, Operation: "GetUser", Latency: 10 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Status: 200, Operation: "GetUser", Latency: 10 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Status: 200, Operation: "AddUser", Err: errors.New("graphql errors: denied"), Latency: 20 * time.Millisecond})
+
+		// статистика из json без операций
+		merged := stats{Latency: histogram.New(), Seconds: map[int]*secondStats{}, Statuses: map[int]int{}}
+		merged.merge(col.snapshot())
+		merged.merge(col.snapshot())
+
+		rep := formatter.Report{}
+		merged.fill(&rep)
+
+		require.Equal(t, []formatter.GroupStats{
+			{Name: "AddUser", Requests: 2, Errors: 2, P50: 20, P95: 20, P99: 20},
+			{Name: "GetUser", Requests: 4, P50: 10, P95: 10, P99: 10},
+		}, rep.Operations)
+	})
+
+	t.Run("addresses", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Status: 200, Addr: "10.0.0.1:80", Latency: 10 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Err: errors.New("connection refused")})
+
+		rep := formatter.Report{}
+		col.fill(&rep)
+		require.Nil(t, rep.Addresses, "single address repeats the whole report")
+
+		col.observe(httploader.Sample{Start: start, Status: 500, Addr: "10.0.0.2:80", Latency: 20 * time.Millisecond})
+		merged := stats{Latency: histogram.New(), Seconds: map[int]*secondStats{}, Statuses: map[int]int{}}
+		merged.merge(col.snapshot())
+
+		rep = formatter.Report{}
+		merged.fill(&rep)
+		require.Equal(t, []formatter.GroupStats{
+			{Name: "10.0.0.1:80", Requests: 1, P50: 10, P95: 10, P99: 10},
+			{Name: "10.0.0.2:80", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+		}, rep.Addresses)
+	})
+
+	t.Run("source ips", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Status: 200, Source: "10.0.0.1", Latency: 10 * time.Millisecond})
+		col.observe(httploader.Sample{Start: start, Err: errors.New("cannot assign requested address"), Source: "10.0.0.2"})
+
+		merged := stats{Latency: histogram.New(), Seconds: map[int]*secondStats{}, Statuses: map[int]int{}}
+		merged.merge(col.snapshot())
+
+		rep := formatter.Report{}
+		merged.fill(&rep)
+		require.Equal(t, []formatter.GroupStats{
+			{Name: "10.0.0.1", Requests: 1, P50: 10, P95: 10, P99: 10},
+			{Name: "10.0.0.2", Requests: 1, Errors: 1},
+		}, rep.Sources)
+		require.Nil(t, rep.Addresses)
+	})
+
+	t.Run("proxy connect time", func(t *testing.T) {
+		col := newCollector(start, 0)
+		col.observe(httploader.Sample{Start: start, Status: 200, Latency: 10 * time.Millisecond, Phases: httploader.Phases{Proxy: 4 * time.Millisecond}})
+		col.observe(httploader.Sample{Start: start, Status: 200, Latency: 2 * time.Millisecond})
+
+		merged := stats{Latency: histogram.New(), Seconds: map[int]*secondStats{}, Statuses: map[int]int{}}
+		merged.merge(col.snapshot())
+
+		rep := formatter.Report{}
+		merged.fill(&rep)
+		require.NotNil(t, rep.ProxyConnect)
+		require.Equal(t, 4.0, rep.ProxyConnect.Max, "reused connections have no proxy phase")
+		require.Equal(t, uint64(1), merged.Proxy.Total)
+	})
+
+	t.Run("error samples are limited", func(t *testing.T) {
+		col := newCollector(start, 0)
+		for i := 0; i < maxErrorSamples*2; i++ {
+			col.observe(httploader.Sample{Start: start, Err: errors.New("timeout")})
+		}
+
+		rep := formatter.Report{}
+		col.fill(&rep)
+
+		require.Len(t, rep.ErrorSamples, maxErrorSamples)
+		require.Equal(t, maxErrorSamples*2, rep.Timeline[0].Errors)
+	})
+
+	t.Run("coordinated omission correction", func(t *testing.T) {
+		col := newCollector(start, 10*time.Millisecond)
+		// сервер завис на 100 мс, за это время исполнитель должен был отправить ещё 9 запросов
+		col.observe(httploader.Sample{Start: start, Status: 200, Latency: 100 * time.Millisecond})
+		for i := 0; i < 9; i++ {
+			col.observe(httploader.Sample{Start: start, Status: 200, Latency: time.Millisecond})
+		}
+
+		rep := formatter.Report{}
+		col.fill(&rep)
+
+		require.InDelta(t, 1, rep.Latency.P50, 0.01, "raw latency hides the stall")
+		require.NotNil(t, rep.CorrectedLatency)
+		require.InDelta(t, 10, rep.CorrectedLatency.P50, 0.2)
+		require.InDelta(t, 90, rep.CorrectedLatency.P90, 1)
+		require.Equal(t, 100.0, rep.CorrectedLatency.Max)
+
+		merged := newStats()
+		merged.merge(col.snapshot())
+		require.Equal(t, uint64(19), merged.Corrected.Total)
+	})
+}
+
+func TestCollectorRetries(t *testing.T) {
+	// сервер отвечает 503 на каждый нечётный запрос, часть запросов повторяется
+	var received int64
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt64(&received, 1)%2 == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer serv.Close()
+
+	start := time.Now()
+	col := newCollector(start, 0)
+	policy := httploader.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Statuses: []int{http.StatusServiceUnavailable}}
+	loader := httploader.New(time.Second, http.MethodGet, 10, 2, httploader.WithRetry(policy), httploader.WithObserver(col.observe))
+
+	rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, 10, rep.All)
+	require.Greater(t, rep.Retry.Retries, 0)
+
+	snapshot := col.snapshot()
+	statuses := 0
+	for _, n := range snapshot.Statuses {
+		statuses += n
+	}
+	requests := 0
+	for _, sec := range snapshot.Seconds {
+		requests += sec.Requests
+	}
+	require.Equal(t, rep.All, statuses)
+	require.Equal(t, rep.All, requests)
+	require.Equal(t, uint64(rep.All), snapshot.Latency.Total)
+	require.Equal(t, rep.Success, snapshot.Statuses[http.StatusOK])
+}
@@ -0,0 +1,265 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/formatter"
+	"benchutil/pkg/httploader"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultSweepSteps = "1,2,4,8,16,32,64,128,256"
+
+// sweepFormats форматы вывода результата sweep
+var sweepFormats = map[string]func(res sweepResult) ([]byte, error){
+	formatter.Human: sweepHumanFormat,
+	formatter.Json: func(res sweepResult) ([]byte, error) {
+		return json.MarshalIndent(res, "", " ")
+	},
+}
+
+type sweepConfig struct {
+	load    config
+	steps   string
+	outputs []string
+}
+
+// sweepStep результат нагрузки с одним значением одновременных запросов
+type sweepStep struct {
+	Concurrency int      `json:"concurrency"`
+	Rps         float64  `json:"rps"`
+	P50         float64  `json:"p50"`
+	P99         float64  `json:"p99"`
+	Errors      float64  `json:"errors"`
+	Failed      []string `json:"failed,omitempty"`
+}
+
+// sweepResult кривая пропускной способности и задержки
+// Knee - точка насыщения, шаг с максимальным отношением rps к p99 среди шагов, прошедших пределы
+type sweepResult struct {
+	Host    string      `json:"host"`
+	Steps   []sweepStep `json:"steps"`
+	Knee    *sweepStep  `json:"knee,omitempty"`
+	Stopped bool        `json:"stopped,omitempty"`
+}
+
+func NewSweep() cli.Command {
+	var cfg sweepConfig
+
+	flags := []cli.CmdFlag{
+		cli.IntFlag{
+			Name:        "n",
+			Destination: &cfg.load.requestsCount,
+			Usage:       "Количество запросов к серверу на каждом шаге",
+		},
+		cli.StringFlag{
+			Name:        "steps",
+			Destination: &cfg.steps,
+			Default:     defaultSweepSteps,
+			Usage:       "Количество одновременных запросов на шагах через запятую",
+		},
+	}
+	flags = append(flags, targetFlags(&cfg.load)...)
+	flags = append(flags,
+		cli.StringFlag{
+			Name:        "threshold",
+			Destination: &cfg.load.thresholds,
+			Default:     "errors<1",
+			Usage:       "Пределы через запятую, как у load, sweep останавливается на первом шаге, который их нарушил",
+		},
+		cli.StringsFlag{
+			Name:        "o",
+			Destination: &cfg.outputs,
+			Default:     []string{formatter.Human},
+			Usage:       "Формат вывода (human или json), \"format=path\" пишет в файл, флаг можно указать несколько раз",
+		},
+	)
+
+	return cli.Command{
+		Name:        "sweep",
+		Description: "Повторяет нагрузку с растущим количеством одновременных запросов и ищет точку насыщения",
+		Flags:       flags,
+		Action: func(ctx context.Context) error {
+			return sweepAction(closer(ctx), cfg)
+		},
+	}
+}
+
+func sweepAction(ctx context.Context, cfg sweepConfig) error {
+	steps, err := parseSteps(cfg.steps)
+	if err != nil {
+		return fmt.Errorf("invalid steps - %w", err)
+	}
+
+	if err = validateConfig(cfg.load); err != nil {
+		return err
+	}
+
+	for _, raw := range cfg.outputs {
+		if _, ok := sweepFormats[parseOutput(raw).format]; !ok {
+			return fmt.Errorf("invalid output format - %s", parseOutput(raw).format)
+		}
+	}
+
+	res, err := sweep(ctx, cfg.load, steps)
+	if err != nil {
+		return err
+	}
+
+	return writeSweepOutputs(res, cfg.outputs)
+}
+
+// sweep выполняет нагрузку на каждом шаге, пока не нарушены пределы из cfg.thresholds
+// при отмене контекста возвращает уже выполненные шаги
+func sweep(ctx context.Context, cfg config, steps []int) (sweepResult, error) {
+	res := sweepResult{Host: cfg.host}
+	for _, c := range steps {
+		if ctx.Err() != nil {
+			break
+		}
+
+		stepCfg := cfg
+		stepCfg.concurrency = c
+
+		col := newCollector(time.Now(), stepCfg.expectedInterval())
+		rep, err := load(ctx, stepCfg, newLoader(stepCfg, httploader.WithObserver(col.observe)), col)
+		if err != nil {
+			return sweepResult{}, fmt.Errorf("step c=%d: %w", c, err)
+		}
+
+		step := newSweepStep(c, rep)
+		res.Steps = append(res.Steps, step)
+		fmt.Fprintf(os.Stderr, "c=%d: %v rps, p99 %v ms, errors %v%%\n", c, step.Rps, step.P99, step.Errors)
+
+		if len(step.Failed) > 0 {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		res.Stopped = true
+	}
+
+	res.Knee = knee(res.Steps)
+
+	return res, nil
+}
+
+func newSweepStep(c int, rep formatter.Report) sweepStep {
+	rate, _ := errorRate(rep)
+	step := sweepStep{
+		Concurrency: c,
+		Rps:         rep.Rps,
+		Errors:      math.Round(rate*100) / 100,
+		Failed:      rep.FailedThresholds(),
+	}
+	if rep.Latency != nil {
+		step.P50, step.P99 = rep.Latency.P50, rep.Latency.P99
+	}
+
+	return step
+}
+
+// knee шаг с максимальной мощностью (rps / p99) среди шагов без нарушенных пределов
+// после этой точки рост одновременных запросов увеличивает задержку быстрее, чем пропускную способность
+func knee(steps []sweepStep) *sweepStep {
+	var (
+		best  *sweepStep
+		power float64
+	)
+	for i := range steps {
+		s := &steps[i]
+		if len(s.Failed) > 0 || s.P99 <= 0 {
+			continue
+		}
+
+		if p := s.Rps / s.P99; best == nil || p > power {
+			best, power = s, p
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+	knee := *best
+
+	return &knee
+}
+
+func parseSteps(raw string) ([]int, error) {
+	var steps []int
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		c, err := strconv.Atoi(part)
+		if err != nil || c <= 0 {
+			return nil, fmt.Errorf("invalid step %q", part)
+		}
+		steps = append(steps, c)
+	}
+
+	if len(steps) == 0 {
+		return nil, errors.New("empty steps")
+	}
+
+	return steps, nil
+}
+
+func writeSweepOutputs(res sweepResult, outputs []string) error {
+	for _, raw := range outputs {
+		out := parseOutput(raw)
+		result, err := sweepFormats[out.format](res)
+		if err != nil {
+			return err
+		}
+
+		if out.path == "" {
+			_, err = os.Stdout.Write(result)
+		} else {
+			err = os.WriteFile(out.path, result, 0644)
+		}
+		if err != nil {
+			return fmt.Errorf("write %s output: %w", out.format, err)
+		}
+	}
+
+	return nil
+}
+
+func sweepHumanFormat(res sweepResult) ([]byte, error) {
+	buf := bytes.Buffer{}
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "c\trps\tp50, мс\tp99, мс\tошибки, %\t")
+	for _, s := range res.Steps {
+		fmt.Fprintf(tw, "%d\t%v\t%v\t%v\t%v\t%s\n", s.Concurrency, s.Rps, s.P50, s.P99, s.Errors, strings.Join(s.Failed, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return nil, err
+	}
+
+	if res.Knee != nil {
+		fmt.Fprintf(&buf, "Точка насыщения: c=%d, %v rps, p99 %v мс\n", res.Knee.Concurrency, res.Knee.Rps, res.Knee.P99)
+	} else {
+		buf.WriteString("Точка насыщения не найдена\n")
+	}
+
+	if n := len(res.Steps); n > 0 && len(res.Steps[n-1].Failed) > 0 {
+		fmt.Fprintf(&buf, "Остановлено на c=%d: нарушены пределы %s\n", res.Steps[n-1].Concurrency, strings.Join(res.Steps[n-1].Failed, ", "))
+	}
+	if res.Stopped {
+		buf.WriteString("Остановлено пользователем\n")
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package load
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	var inFlight int64
+	// сервер отвечает ошибкой, если одновременных запросов больше 4
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		defer atomic.AddInt64(&inFlight, -1)
+		if atomic.AddInt64(&inFlight, 1) > 4 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer serv.Close()
+
+	cfg := config{host: serv.URL, requestsCount: 40, timeOut: 1, method: http.MethodGet, thresholds: "errors<1"}
+
+	res, err := sweep(context.Background(), cfg, []int{1, 2, 4, 16, 32})
+	require.NoError(t, err)
+
+	require.Equal(t, serv.URL, res.Host)
+	require.False(t, res.Stopped)
+	require.GreaterOrEqual(t, len(res.Steps), 3)
+	last := res.Steps[len(res.Steps)-1]
+	require.Equal(t, 16, last.Concurrency, "stops on first failed step")
+	require.Equal(t, []string{"errors<1"}, last.Failed)
+	require.NotNil(t, res.Knee)
+	require.LessOrEqual(t, res.Knee.Concurrency, 4)
+
+	out, err := sweepHumanFormat(res)
+	require.NoError(t, err)
+	require.Contains(t, string(out), "Остановлено на c=16: нарушены пределы errors<1")
+}
+
+func TestSweepCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res, err := sweep(ctx, config{host: "http://host", requestsCount: 1, timeOut: 1}, []int{1, 2})
+
+	require.NoError(t, err)
+	require.True(t, res.Stopped)
+	require.Empty(t, res.Steps)
+	require.Nil(t, res.Knee)
+}
+
+func TestKnee(t *testing.T) {
+	steps := []sweepStep{
+		{Concurrency: 1, Rps: 100, P99: 10},
+		{Concurrency: 2, Rps: 190, P99: 11},
+		{Concurrency: 4, Rps: 300, P99: 20},
+		{Concurrency: 8, Rps: 1000, P99: 10, Failed: []string{"errors<1"}},
+	}
+
+	require.Equal(t, &steps[1], knee(steps))
+	require.Nil(t, knee(nil))
+}
+
+func TestParseSteps(t *testing.T) {
+	tests := []struct {
+		raw   string
+		steps []int
+		err   string
+	}{
+		{raw: "1, 2,4,", steps: []int{1, 2, 4}},
+		{raw: "", err: "empty steps"},
+		{raw: "1,0", err: `invalid step "0"`},
+		{raw: "a", err: `invalid step "a"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			steps, err := parseSteps(tt.raw)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.steps, steps)
+		})
+	}
+}
+
+func TestSweepJsonFormat(t *testing.T) {
+	out, err := sweepFormats["json"](sweepResult{Host: "h", Steps: []sweepStep{{Concurrency: 1}}})
+
+	require.NoError(t, err)
+	require.True(t, strings.Contains(string(out), `"concurrency": 1`))
+}
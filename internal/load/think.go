@@ -0,0 +1,68 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// распределения think time в значении флага -think
+const (
+	thinkUniform     = "uniform:"
+	thinkExponential = "exp:"
+)
+
+// parseThinkTime разбирает think time: "500ms" - постоянная пауза,
+// "uniform:100ms-1s" - равномерное распределение, "exp:500ms" - экспоненциальное со средним 500ms
+// для пустой строки возвращает nil
+func parseThinkTime(raw string) (httploader.Delay, error) {
+	switch {
+	case raw == "":
+		return nil, nil
+	case strings.HasPrefix(raw, thinkUniform):
+		bounds := strings.SplitN(strings.TrimPrefix(raw, thinkUniform), "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("uniform think time %q must be min-max", raw)
+		}
+		min, err := parseThinkDuration(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		max, err := parseThinkDuration(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if min > max {
+			return nil, fmt.Errorf("uniform think time min %s is greater than max %s", min, max)
+		}
+
+		return httploader.UniformDelay(min, max), nil
+	case strings.HasPrefix(raw, thinkExponential):
+		mean, err := parseThinkDuration(strings.TrimPrefix(raw, thinkExponential))
+		if err != nil {
+			return nil, err
+		}
+
+		return httploader.ExponentialDelay(mean), nil
+	default:
+		d, err := parseThinkDuration(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		return httploader.ConstantDelay(d), nil
+	}
+}
+
+func parseThinkDuration(raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse duration: %w", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration %s", d)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,71 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	type testCase struct {
+		name     string
+		raw      string
+		min, max time.Duration
+		err      string
+	}
+
+	cases := [...]testCase{
+		{name: "constant", raw: "500ms", min: 500 * time.Millisecond, max: 500 * time.Millisecond},
+		{name: "uniform", raw: "uniform:100ms-1s", min: 100 * time.Millisecond, max: time.Second},
+		{name: "exponential is not negative", raw: "exp:1ms", max: time.Hour},
+		{name: "invalid duration", raw: "500", err: "parse duration: time: missing unit in duration \"500\""},
+		{name: "negative duration", raw: "-1s", err: "negative duration -1s"},
+		{name: "uniform without max", raw: "uniform:1s", err: "uniform think time \"uniform:1s\" must be min-max"},
+		{name: "uniform min is greater than max", raw: "uniform:2s-1s", err: "uniform think time min 2s is greater than max 1s"},
+		{name: "invalid exponential mean", raw: "exp:", err: "parse duration: time: invalid duration \"\""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			delay, err := parseThinkTime(tc.raw)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			for i := 0; i < 100; i++ {
+				d := delay()
+				require.GreaterOrEqual(t, d, tc.min)
+				require.LessOrEqual(t, d, tc.max)
+			}
+		})
+	}
+
+	t.Run("empty think time", func(t *testing.T) {
+		delay, err := parseThinkTime("")
+		require.NoError(t, err)
+		require.Nil(t, delay)
+	})
+}
+
+func TestExpectedInterval(t *testing.T) {
+	require.Equal(t, time.Duration(0), config{}.expectedInterval())
+	require.Equal(t, time.Second, config{pacing: time.Second}.expectedInterval())
+	require.Equal(t, 20*time.Millisecond, config{pacing: time.Second, interval: 20}.expectedInterval())
+	require.Equal(t, time.Duration(0), config{thinkTime: "500ms"}.expectedInterval(), "think time is not a fixed interval")
+	require.Equal(t, time.Second, config{thinkTime: "500ms", pacing: time.Second}.expectedInterval())
+	require.Equal(t, time.Duration(0), config{thinkTime: "exp:500ms"}.expectedInterval())
+
+	// ответы стабильно медленнее think time, но сервер не простаивал, дополнительных значений с коррекцией нет
+	cfg := config{thinkTime: "10ms"}
+	col := newCollector(time.Now(), cfg.expectedInterval())
+	for i := 0; i < 100; i++ {
+		col.observe(httploader.Sample{Start: time.Now(), Status: http.StatusOK, Latency: 50 * time.Millisecond})
+	}
+	st := col.snapshot()
+	require.Equal(t, uint64(100), st.Latency.Total)
+	require.Nil(t, st.Corrected)
+}
@@ -0,0 +1,143 @@
+package load
+
+import (
+	"benchutil/pkg/formatter"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// thresholdMetrics метрики отчёта, для которых можно задать порог
+// перцентили и среднее в миллисекундах, errors - доля неуспешных запросов в процентах, rps - запросов в секунду
+// allowed - запросов в секунду, принятых сервером без ограничения скорости, считается только с -backpressure
+// loss - доля потерянных пакетов UDP в процентах
+// если метрики нет в отчёте, например ни на один запрос не пришёл ответ, порог не пройден
+var thresholdMetrics = map[string]func(rep formatter.Report) (float64, error){
+	"mean":    latencyMetric(func(l *formatter.LatencyStats) float64 { return l.Mean }),
+	"p50":     latencyMetric(func(l *formatter.LatencyStats) float64 { return l.P50 }),
+	"p90":     latencyMetric(func(l *formatter.LatencyStats) float64 { return l.P90 }),
+	"p95":     latencyMetric(func(l *formatter.LatencyStats) float64 { return l.P95 }),
+	"p99":     latencyMetric(func(l *formatter.LatencyStats) float64 { return l.P99 }),
+	"max":     latencyMetric(func(l *formatter.LatencyStats) float64 { return l.Max }),
+	"errors":  errorRate,
+	"rps":     func(rep formatter.Report) (float64, error) { return rep.Rps, nil },
+	"allowed": allowedRate,
+	"loss":    lossRate,
+}
+
+// операторы проверяются по порядку, поэтому двухсимвольные идут первыми
+var thresholdOperators = [...]string{"<=", ">=", "<", ">"}
+
+type threshold struct {
+	raw      string
+	metric   string
+	operator string
+	value    float64
+}
+
+// parseThresholds разбирает пороги вида "p99<500,errors<=1"
+func parseThresholds(raw string) ([]threshold, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var thresholds []threshold
+	for _, part := range strings.Split(raw, ",") {
+		th, err := parseThreshold(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		thresholds = append(thresholds, th)
+	}
+
+	return thresholds, nil
+}
+
+func parseThreshold(raw string) (threshold, error) {
+	for _, op := range thresholdOperators {
+		idx := strings.Index(raw, op)
+		if idx < 0 {
+			continue
+		}
+
+		metric := strings.TrimSpace(raw[:idx])
+		if _, ok := thresholdMetrics[metric]; !ok {
+			return threshold{}, fmt.Errorf("unknown metric %s", metric)
+		}
+
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw[idx+len(op):]), 64)
+		if err != nil {
+			return threshold{}, fmt.Errorf("invalid value in %s", raw)
+		}
+
+		return threshold{raw: raw, metric: metric, operator: op, value: value}, nil
+	}
+
+	return threshold{}, fmt.Errorf("no operator in %s", raw)
+}
+
+func (th threshold) check(rep formatter.Report) formatter.ThresholdResult {
+	actual, err := thresholdMetrics[th.metric](rep)
+	if err != nil {
+		return formatter.ThresholdResult{Name: th.raw, Message: err.Error()}
+	}
+
+	var passed bool
+	switch th.operator {
+	case "<":
+		passed = actual < th.value
+	case "<=":
+		passed = actual <= th.value
+	case ">":
+		passed = actual > th.value
+	case ">=":
+		passed = actual >= th.value
+	}
+
+	return formatter.ThresholdResult{Name: th.raw, Value: actual, Passed: passed}
+}
+
+func checkThresholds(thresholds []threshold, rep formatter.Report) []formatter.ThresholdResult {
+	var results []formatter.ThresholdResult
+	for _, th := range thresholds {
+		results = append(results, th.check(rep))
+	}
+
+	return results
+}
+
+func latencyMetric(get func(l *formatter.LatencyStats) float64) func(rep formatter.Report) (float64, error) {
+	return func(rep formatter.Report) (float64, error) {
+		if rep.Latency == nil {
+			return 0, errors.New("no answered requests, latency is unknown")
+		}
+
+		return get(rep.Latency), nil
+	}
+}
+
+func errorRate(rep formatter.Report) (float64, error) {
+	if rep.All == 0 {
+		return 0, nil
+	}
+
+	return math.Round(float64(rep.Errors+rep.Canceled)*10000/float64(rep.All)) / 100, nil
+}
+
+func allowedRate(rep formatter.Report) (float64, error) {
+	if rep.Throttle == nil {
+		return 0, errors.New("no rate limit stats, run with -backpressure")
+	}
+
+	return rep.Throttle.AllowedRps, nil
+}
+
+func lossRate(rep formatter.Report) (float64, error) {
+	if rep.Socket == nil {
+		return 0, errors.New("no udp stats")
+	}
+
+	return rep.Socket.LossRate, nil
+}
@@ -0,0 +1,87 @@
+package load
+
+import (
+	"benchutil/pkg/formatter"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseThresholds(t *testing.T) {
+	type testCase struct {
+		name        string
+		raw         string
+		expected    []threshold
+		expectedErr error
+	}
+
+	cases := [...]testCase{
+		{
+			name: "empty",
+		},
+		{
+			name: "several thresholds",
+			raw:  "p99<500, errors<=1.5,mean>=0",
+			expected: []threshold{
+				{raw: "p99<500", metric: "p99", operator: "<", value: 500},
+				{raw: "errors<=1.5", metric: "errors", operator: "<=", value: 1.5},
+				{raw: "mean>=0", metric: "mean", operator: ">=", value: 0},
+			},
+		},
+		{
+			name:        "unknown metric",
+			raw:         "p42<1",
+			expectedErr: errors.New("unknown metric p42"),
+		},
+		{
+			name:        "invalid value",
+			raw:         "p99<fast",
+			expectedErr: errors.New("invalid value in p99<fast"),
+		},
+		{
+			name:        "no operator",
+			raw:         "p99",
+			expectedErr: errors.New("no operator in p99"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			thresholds, err := parseThresholds(tc.raw)
+
+			require.Equal(t, tc.expected, thresholds)
+			require.Equal(t, tc.expectedErr, err)
+		})
+	}
+}
+
+func TestCheckThresholds(t *testing.T) {
+	thresholds, err := parseThresholds("p99<500,errors<10,max>1000,rps>=50,allowed<=100,loss<1")
+	require.NoError(t, err)
+
+	rep := formatter.Report{All: 10, Errors: 1, Canceled: 1, Rps: 50, Latency: &formatter.LatencyStats{P99: 200, Max: 1000}, Throttle: &formatter.ThrottleStats{AllowedRps: 40}, Socket: &formatter.SocketStats{Sent: 10, Lost: 1, LossRate: 10}}
+	results := checkThresholds(thresholds, rep)
+
+	require.Equal(t, []formatter.ThresholdResult{
+		{Name: "p99<500", Value: 200, Passed: true},
+		{Name: "errors<10", Value: 20},
+		{Name: "max>1000", Value: 1000},
+		{Name: "rps>=50", Value: 50, Passed: true},
+		{Name: "allowed<=100", Value: 40, Passed: true},
+		{Name: "loss<1", Value: 10},
+	}, results)
+
+	rep.Thresholds = results
+	require.Equal(t, []string{"errors<10", "max>1000", "loss<1"}, rep.FailedThresholds())
+
+	// ни на один запрос не пришёл ответ
+	results = checkThresholds(thresholds, formatter.Report{All: 10, Errors: 10})
+	require.Equal(t, []formatter.ThresholdResult{
+		{Name: "p99<500", Message: "no answered requests, latency is unknown"},
+		{Name: "errors<10", Value: 100},
+		{Name: "max>1000", Message: "no answered requests, latency is unknown"},
+		{Name: "rps>=50"},
+		{Name: "allowed<=100", Message: "no rate limit stats, run with -backpressure"},
+		{Name: "loss<1", Message: "no udp stats"},
+	}, results)
+}
@@ -0,0 +1,51 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+const unixScheme = "unix://"
+
+// unixHost нагрузка через unix сокет: "unix:///path/to.sock" или, как у nginx, с путём запроса http "unix:///path/to.sock:/api/health"
+// gRPC понимает такой адрес сам, для остальных нагрузок запросы идут по http через сокет
+func unixHost(host string) bool {
+	return strings.HasPrefix(host, unixScheme)
+}
+
+// parseUnixHost разбирает unix адрес в путь до сокета и url запроса, который отправляется через сокет
+func parseUnixHost(host string) (socket string, url string, err error) {
+	socket, path := strings.TrimPrefix(host, unixScheme), "/"
+	if i := strings.Index(socket, ":"); i >= 0 {
+		socket, path = socket[:i], socket[i+1:]
+	}
+	if socket == "" {
+		return "", "", errors.New("empty unix socket path")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", errors.New("request path must start with /")
+	}
+
+	return socket, "http://localhost" + path, nil
+}
+
+// targetURL адрес, на который отправляются запросы нагрузки
+func targetURL(cfg config) string {
+	if !unixHost(cfg.host) || cfg.grpcCall != "" {
+		return cfg.host
+	}
+
+	_, url, _ := parseUnixHost(cfg.host)
+	return url
+}
+
+// unixDialer соединения нагрузки открываются на unix сокет socket, куда бы ни шёл запрос
+func unixDialer(socket string) httploader.Option {
+	var d net.Dialer
+	return httploader.WithDialContext(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return d.DialContext(ctx, "unix", socket)
+	})
+}
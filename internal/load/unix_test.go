@@ -0,0 +1,42 @@
+package load
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseUnixHost(t *testing.T) {
+	type testCase struct {
+		host           string
+		expectedSocket string
+		expectedURL    string
+		expectedErr    string
+	}
+
+	cases := [...]testCase{
+		{host: "unix:///var/run/app.sock", expectedSocket: "/var/run/app.sock", expectedURL: "http://localhost/"},
+		{host: "unix:///var/run/app.sock:/api/health?full=1", expectedSocket: "/var/run/app.sock", expectedURL: "http://localhost/api/health?full=1"},
+		{host: "unix://app.sock", expectedSocket: "app.sock", expectedURL: "http://localhost/"},
+		{host: "unix://", expectedErr: "empty unix socket path"},
+		{host: "unix:///var/run/app.sock:health", expectedErr: "request path must start with /"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.host, func(t *testing.T) {
+			socket, url, err := parseUnixHost(tc.host)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedSocket, socket)
+			require.Equal(t, tc.expectedURL, url)
+		})
+	}
+}
+
+func TestTargetURL(t *testing.T) {
+	require.Equal(t, "http://host/path", targetURL(config{host: "http://host/path"}))
+	require.Equal(t, "http://localhost/health", targetURL(config{host: "unix:///tmp/app.sock:/health"}))
+	require.Equal(t, "unix:///tmp/app.sock", targetURL(config{host: "unix:///tmp/app.sock", grpcCall: "pkg.Service/Method"}), "grpc dials unix sockets itself")
+}
@@ -0,0 +1,97 @@
+package load
+
+import (
+	"benchutil/pkg/formatter"
+	"benchutil/pkg/httploader"
+	"context"
+	"math"
+	"time"
+)
+
+// warmupBatch количество запросов на одного исполнителя в одной пачке прогрева по времени
+const warmupBatch = 100
+
+func (cfg config) warmupEnabled() bool {
+	return cfg.warmup > 0 || cfg.warmupN > 0
+}
+
+// runPhases выполняет прогрев, если он задан, и основную нагрузку
+// прогрев идёт через такой же Loader, что и основная нагрузка, его статистика возвращается отдельно в Warmup
+func runPhases(ctx context.Context, cfg config, col *collector, opts ...httploader.Option) (runResult, error) {
+	var warm *runResult
+	if cfg.warmupEnabled() {
+		col.beginWarmup()
+		w, err := warmUp(ctx, cfg, opts...)
+		w.Stats = col.endWarmup(time.Now())
+		if err != nil {
+			return runResult{}, err
+		}
+		warm = &w
+	}
+
+	res, err := run(ctx, cfg, newLoader(cfg, opts...), col)
+	if err != nil {
+		return runResult{}, err
+	}
+	res.Warmup = warm
+
+	return res, nil
+}
+
+// warmUp отправляет cfg.warmupN запросов или отправляет запросы пачками в течение cfg.warmup
+// если заданы оба ограничения, прогрев заканчивается по первому из них
+func warmUp(ctx context.Context, cfg config, opts ...httploader.Option) (runResult, error) {
+	start := time.Now()
+	if cfg.warmup > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.warmup)
+		defer cancel()
+	}
+
+	warmCfg := cfg
+	if cfg.warmupN > 0 {
+		warmCfg.requestsCount = cfg.warmupN
+		rep, err := makeLoad(ctx, warmCfg, newLoader(warmCfg, opts...))
+		if err != nil {
+			return runResult{}, err
+		}
+
+		return runResult{Report: rep, Elapsed: time.Since(start)}, nil
+	}
+
+	workers := cfg.concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	warmCfg.requestsCount = warmupBatch * workers
+	loader := newLoader(warmCfg, opts...)
+
+	var res runResult
+	for ctx.Err() == nil {
+		rep, err := makeLoad(ctx, warmCfg, loader)
+		if err != nil {
+			return runResult{}, err
+		}
+		res.merge(runResult{Report: rep})
+	}
+	res.Elapsed = time.Since(start)
+
+	return res, nil
+}
+
+func newWarmupStats(res runResult) *formatter.WarmupStats {
+	warm := &formatter.WarmupStats{
+		All:      res.Report.All,
+		Errors:   res.Report.Errors + res.Report.Cancelled,
+		Duration: math.Round(res.Elapsed.Seconds()*1000) / 1000,
+	}
+	if res.Elapsed > 0 {
+		warm.Rps = math.Round(float64(warm.All)/res.Elapsed.Seconds()*100) / 100
+	}
+	if res.Stats.Latency != nil && res.Stats.Latency.Total > 0 {
+		warm.Latency = newLatencyStats(res.Stats.Latency)
+		warm.Latency.Histogram = nil
+	}
+
+	return warm
+}
@@ -0,0 +1,100 @@
+package load
+
+import (
+	"benchutil/pkg/httploader"
+	"bufio"
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExecuteWithWarmup(t *testing.T) {
+	var requests int64
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		// первые запросы медленные, как у холодного сервиса
+		if atomic.AddInt64(&requests, 1) <= 5 {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer serv.Close()
+
+	rawPath := filepath.Join(t.TempDir(), "raw.jsonl")
+	cfg := config{host: serv.URL, requestsCount: 20, concurrency: 2, timeOut: 1, method: http.MethodGet, warmupN: 10, rawPath: rawPath}
+
+	rep, err := execute(context.Background(), cfg, newCollector(time.Now(), 0))
+	require.NoError(t, err)
+
+	require.Equal(t, int64(30), requests)
+	require.Equal(t, 20, rep.All)
+	require.Less(t, rep.Latency.Max, 50.0, "slow warm-up requests are excluded")
+	require.NotNil(t, rep.Warmup)
+	require.Equal(t, 10, rep.Warmup.All)
+	require.Equal(t, 0, rep.Warmup.Errors)
+	require.GreaterOrEqual(t, rep.Warmup.Latency.Max, 50.0)
+
+	f, err := os.Open(rawPath)
+	require.NoError(t, err)
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		lines++
+	}
+	require.Equal(t, 20, lines, "warm-up requests are not written to raw output")
+}
+
+func TestWarmUp(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		time.Sleep(time.Millisecond)
+	}))
+	defer serv.Close()
+
+	cfg := config{host: serv.URL, timeOut: 1, method: http.MethodGet}
+
+	t.Run("by duration", func(t *testing.T) {
+		cfg := cfg
+		cfg.warmup = 100 * time.Millisecond
+
+		res, err := warmUp(context.Background(), cfg)
+
+		require.NoError(t, err)
+		require.Greater(t, res.Report.All, 0)
+		require.GreaterOrEqual(t, res.Elapsed, 100*time.Millisecond)
+		require.Less(t, res.Elapsed, time.Second)
+	})
+
+	t.Run("duration limits requests count", func(t *testing.T) {
+		cfg := cfg
+		cfg.warmup = 50 * time.Millisecond
+		cfg.warmupN = 1000000
+
+		res, err := warmUp(context.Background(), cfg)
+
+		require.NoError(t, err)
+		require.Less(t, res.Report.All, 1000000)
+	})
+
+	t.Run("merge keeps warm-up separately", func(t *testing.T) {
+		var res runResult
+		res.merge(runResult{Report: httploaderReport(10), Warmup: &runResult{Report: httploaderReport(2), Elapsed: time.Second}})
+		res.merge(runResult{Report: httploaderReport(10), Warmup: &runResult{Report: httploaderReport(3), Elapsed: 2 * time.Second}})
+
+		require.Equal(t, 20, res.Report.All)
+		require.Equal(t, 5, res.Warmup.Report.All)
+		require.Equal(t, 2*time.Second, res.Warmup.Elapsed)
+
+		rep, err := buildReport(config{}, res, nil)
+		require.NoError(t, err)
+		require.Equal(t, 5, rep.Warmup.All)
+		require.Equal(t, 2.5, rep.Warmup.Rps)
+	})
+}
+
+func httploaderReport(all int) httploader.Report {
+	return httploader.Report{All: all, Success: all}
+}
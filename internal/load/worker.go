@@ -0,0 +1,177 @@
+package load
+
+import (
+	"benchutil/pkg/cli"
+	"benchutil/pkg/httploader"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+const (
+	progressInterval = time.Second
+	prepareTimeout   = 10 * time.Second
+)
+
+func NewWorker() cli.Command {
+	var listen, token string
+	return cli.Command{
+		Name:        "worker",
+		Description: "Выполняет часть распределённой нагрузки по команде координатора",
+		Flags: []cli.CmdFlag{
+			cli.StringFlag{
+				Name:        "listen",
+				Destination: &listen,
+				Default:     "127.0.0.1:7070",
+				Usage:       "Адрес, на котором воркер ждёт подключения координатора",
+			},
+			cli.StringFlag{
+				Name:        "token",
+				Destination: &token,
+				Usage:       "Общий токен воркеров и координатора, обязателен, если -listen не на loopback",
+			},
+		},
+		Action: func(ctx context.Context) error {
+			ln, err := listenWorker(listen, token)
+			if err != nil {
+				return err
+			}
+
+			return serveWorker(closer(ctx), ln, token)
+		},
+	}
+}
+
+// listenWorker открывает адрес воркера, без токена только на loopback:
+// иначе любой, кто достучится до воркера, запустит с него нагрузку на произвольный адрес
+func listenWorker(listen, token string) (net.Listener, error) {
+	if token == "" && !loopback(listen) {
+		return nil, fmt.Errorf("token is required to listen on %s", listen)
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("listen: %w", err)
+	}
+
+	return ln, nil
+}
+
+// serveWorker принимает координаторов по одному, одновременно воркер выполняет только одну нагрузку
+// с token воркер выполняет только prepare с тем же токеном
+func serveWorker(ctx context.Context, ln net.Listener, token string) error {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("accept: %w", err)
+		}
+
+		if err = handleCoordinator(ctx, newPeer(conn), token); err != nil {
+			fmt.Fprintf(os.Stderr, "coordinator %s: %v\n", conn.RemoteAddr(), err)
+		}
+	}
+}
+
+func handleCoordinator(ctx context.Context, p *peer, token string) error {
+	defer p.close()
+
+	// пока координатор не прислал prepare, воркер не принимает других, поэтому ждёт его ограниченное время
+	p.conn.SetReadDeadline(time.Now().Add(prepareTimeout))
+	msg, err := p.receive()
+	if err != nil {
+		return fmt.Errorf("receive prepare: %w", err)
+	}
+	p.conn.SetReadDeadline(time.Time{})
+	if msg.Type != msgPrepare {
+		return fmt.Errorf("unexpected message %s", msg.Type)
+	}
+	if token != "" && subtle.ConstantTimeCompare([]byte(msg.Token), []byte(token)) != 1 {
+		p.send(message{Type: msgError, Error: "unauthorized"})
+		return errors.New("unauthorized")
+	}
+
+	cfg, err := parseConfig(msg.Args)
+	if err != nil {
+		p.send(message{Type: msgError, Error: err.Error()})
+		return err
+	}
+	cfg.headers, cfg.body = msg.Headers, msg.Body
+
+	if err = p.send(message{Type: msgReady}); err != nil {
+		return fmt.Errorf("send ready: %w", err)
+	}
+
+	if msg, err = p.receive(); err != nil {
+		return fmt.Errorf("receive start: %w", err)
+	}
+	if msg.Type != msgStart {
+		return nil
+	}
+
+	loadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		// любое следующее сообщение или обрыв соединения останавливают нагрузку
+		p.receive()
+		cancel()
+	}()
+
+	col := newCollector(time.Now(), cfg.expectedInterval())
+
+	type runOutcome struct {
+		res runResult
+		err error
+	}
+	done := make(chan runOutcome, 1)
+	go func() {
+		res, err := runPhases(loadCtx, cfg, col, httploader.WithObserver(col.observe))
+		done <- runOutcome{res: res, err: err}
+	}()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err = p.send(message{Type: msgProgress, Progress: col.snapshot().progress()}); err != nil {
+				cancel()
+			}
+		case out := <-done:
+			if out.err != nil {
+				p.send(message{Type: msgError, Error: out.err.Error()})
+				return out.err
+			}
+			return p.send(message{Type: msgResult, Result: &out.res})
+		}
+	}
+}
+
+// parseConfig разбирает аргументы команды load, полученные от координатора
+func parseConfig(args []string) (config, error) {
+	var cfg config
+	if err := cli.Parse("load", loadFlags(&cfg), args); err != nil {
+		return config{}, err
+	}
+
+	if err := validateConfig(cfg); err != nil {
+		return config{}, err
+	}
+
+	if cfg.rawPath != "" {
+		return config{}, errors.New("raw output is not supported by worker")
+	}
+
+	return cfg, nil
+}
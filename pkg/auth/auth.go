@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// Basic авторизация логином и паролем в заголовке Authorization: Basic
+type Basic struct {
+	User     string
+	Password string
+}
+
+func (a Basic) Authenticate(_ context.Context, req *http.Request, _ []byte) error {
+	req.SetBasicAuth(a.User, a.Password)
+	return nil
+}
+
+// Bearer авторизация постоянным токеном в заголовке Authorization: Bearer
+type Bearer struct {
+	Token string
+}
+
+func (a Bearer) Authenticate(_ context.Context, req *http.Request, _ []byte) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStatic(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://host/", nil)
+	require.NoError(t, Basic{User: "bench", Password: "secret"}.Authenticate(context.Background(), req, nil))
+	user, password, ok := req.BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "bench", user)
+	require.Equal(t, "secret", password)
+
+	require.NoError(t, Bearer{Token: "abc"}.Authenticate(context.Background(), req, nil))
+	require.Equal(t, "Bearer abc", req.Header.Get("Authorization"))
+}
+
+// tokenServer заглушка эндпоинта токенов, выдаёт token-1, token-2 и т.д. со сроком expiresIn секунд
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *int64) {
+	var issued int64
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		n := atomic.AddInt64(&issued, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + strconv.FormatInt(n, 10),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(serv.Close)
+
+	return serv, &issued
+}
+
+func TestClientCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("token is cached", func(t *testing.T) {
+		serv, issued := tokenServer(t, 3600)
+		c := &ClientCredentials{TokenURL: serv.URL, ClientID: "client", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodGet, "http://host/", nil)
+				require.NoError(t, c.Authenticate(ctx, req, nil))
+				require.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+			}()
+		}
+		wg.Wait()
+		require.Equal(t, int64(1), *issued)
+		require.Equal(t, 1, c.Fetches())
+	})
+
+	t.Run("token is refreshed before expiration", func(t *testing.T) {
+		serv, issued := tokenServer(t, 1)
+		c := &ClientCredentials{TokenURL: serv.URL, ClientID: "client", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+
+		token, err := c.Token(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "token-1", token)
+
+		time.Sleep(950 * time.Millisecond)
+		token, err = c.Token(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "token-2", token, "refreshed after 90% of expires_in")
+		require.Equal(t, int64(2), *issued)
+	})
+
+	t.Run("unauthorized resets token", func(t *testing.T) {
+		serv, _ := tokenServer(t, 0)
+		c := &ClientCredentials{TokenURL: serv.URL, ClientID: "client", ClientSecret: "s3cret", Scopes: []string{"read", "write"}}
+
+		stale := httptest.NewRequest(http.MethodGet, "http://host/", nil)
+		require.NoError(t, c.Authenticate(ctx, stale, nil))
+		c.Unauthorized(stale)
+
+		req := httptest.NewRequest(http.MethodGet, "http://host/", nil)
+		require.NoError(t, c.Authenticate(ctx, req, nil))
+		require.Equal(t, "Bearer token-2", req.Header.Get("Authorization"))
+
+		c.Unauthorized(stale)
+		token, err := c.Token(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "token-2", token, "late 401 with old token does not reset new one")
+		require.Equal(t, 2, c.Fetches())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		serv, _ := tokenServer(t, 3600)
+		badType := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"access_token":"abc","token_type":"mac"}`))
+		}))
+		defer badType.Close()
+		empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"token_type":"bearer"}`))
+		}))
+		defer empty.Close()
+
+		tt := []struct {
+			name        string
+			c           *ClientCredentials
+			expectedErr string
+		}{
+			{
+				name:        "wrong secret",
+				c:           &ClientCredentials{TokenURL: serv.URL, ClientID: "client", ClientSecret: "wrong"},
+				expectedErr: `fetch token: unexpected status 401: {"error":"invalid_client"}`,
+			},
+			{
+				name:        "unsupported token type",
+				c:           &ClientCredentials{TokenURL: badType.URL},
+				expectedErr: `fetch token: unsupported token type "mac"`,
+			},
+			{
+				name:        "empty token",
+				c:           &ClientCredentials{TokenURL: empty.URL},
+				expectedErr: "fetch token: empty access_token",
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodGet, "http://host/", nil)
+				err := tc.c.Authenticate(ctx, req, nil)
+				require.EqualError(t, err, tc.expectedErr)
+				require.Empty(t, req.Header.Get("Authorization"))
+			})
+		}
+	})
+}
+
+func TestHMAC(t *testing.T) {
+	secret := []byte("s3cret")
+	// сервер проверяет подпись так же, как это делал бы нагружаемый сервис
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		contentHash := hex.EncodeToString(sum[:])
+
+		date := r.Header.Get(HeaderDate)
+		expected := "HMAC-SHA256 Credential=key-1, SignedHeaders=host;x-content-sha256;x-date, Signature=" +
+			Sign(secret, StringToSign(r, date, contentHash))
+		if r.Header.Get(HeaderContentHash) != contentHash || r.Header.Get("Authorization") != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer serv.Close()
+
+	a := HMAC{KeyID: "key-1", Secret: secret}
+	tt := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{name: "get without path", method: http.MethodGet},
+		{name: "get with query", method: http.MethodGet, path: "/items?b=2&a=1"},
+		{name: "post with body", method: http.MethodPost, path: "/items", body: `{"name":"x"}`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, serv.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			require.NoError(t, a.Authenticate(context.Background(), req, []byte(tc.body)))
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+
+	t.Run("signature covers body and method", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, serv.URL+"/items", strings.NewReader(`{"name":"y"}`))
+		require.NoError(t, err)
+		require.NoError(t, a.Authenticate(context.Background(), req, []byte(`{"name":"x"}`)))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		fixed := HMAC{KeyID: "key-1", Secret: secret, now: func() time.Time {
+			return time.Date(2021, 1, 2, 3, 4, 5, 0, time.FixedZone("MSK", 3*3600))
+		}}
+		get, _ := http.NewRequest(http.MethodGet, "http://host/items", nil)
+		post, _ := http.NewRequest(http.MethodPost, "http://host/items", nil)
+		require.NoError(t, fixed.Authenticate(context.Background(), get, nil))
+		require.NoError(t, fixed.Authenticate(context.Background(), post, nil))
+		require.Equal(t, "20210102T000405Z", get.Header.Get(HeaderDate))
+		require.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", get.Header.Get(HeaderContentHash))
+		require.NotEqual(t, get.Header.Get("Authorization"), post.Header.Get("Authorization"))
+	})
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// заголовки подписи HMAC
+const (
+	HeaderDate        = "X-Date"
+	HeaderContentHash = "X-Content-Sha256"
+
+	hmacAlgorithm  = "HMAC-SHA256"
+	hmacDateFormat = "20060102T150405Z"
+)
+
+// HMAC подписывает каждый запрос HMAC-SHA256 в духе AWS SigV4, подпись - hex HMAC от строки
+//
+//	HMAC-SHA256\n<X-Date>\n<метод>\n<путь>\n<query с параметрами по алфавиту>\n<host>\n<X-Content-Sha256>
+//
+// в запрос добавляются X-Date - время подписи в UTC 20060102T150405Z, X-Content-Sha256 - hex sha256 тела
+// и Authorization: HMAC-SHA256 Credential=<KeyID>, SignedHeaders=host;x-content-sha256;x-date, Signature=<подпись>
+type HMAC struct {
+	KeyID  string
+	Secret []byte
+
+	// now время подписи, по умолчанию time.Now
+	now func() time.Time
+}
+
+func (a HMAC) Authenticate(_ context.Context, req *http.Request, body []byte) error {
+	now := time.Now
+	if a.now != nil {
+		now = a.now
+	}
+
+	date := now().UTC().Format(hmacDateFormat)
+	sum := sha256.Sum256(body)
+	contentHash := hex.EncodeToString(sum[:])
+
+	req.Header.Set(HeaderDate, date)
+	req.Header.Set(HeaderContentHash, contentHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s, SignedHeaders=host;x-content-sha256;x-date, Signature=%s",
+		hmacAlgorithm, a.KeyID, Sign(a.Secret, StringToSign(req, date, contentHash))))
+
+	return nil
+}
+
+// StringToSign строка, которую подписывает HMAC, по ней же подпись можно проверить на стороне сервера
+func StringToSign(req *http.Request, date, contentHash string) string {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	// пустой путь клиента сервер получает как "/"
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		hmacAlgorithm,
+		date,
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		host,
+		contentHash,
+	}, "\n")
+}
+
+// Sign hex HMAC-SHA256 от s с ключом secret
+func Sign(secret []byte, s string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(s))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenTimeout таймаут запроса токена, если ClientCredentials.Client не задан
+const tokenTimeout = 10 * time.Second
+
+// ClientCredentials авторизация токеном OAuth2, полученным по client credentials (RFC 6749, 4.4)
+// токен кэшируется и обновляется заранее, по прошествии 90% срока из expires_in, токен без срока живёт до ответа 401
+// пока токен обновляется, запросы ждут одного общего обновления, безопасен для нескольких горутин
+type ClientCredentials struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Client клиент для запросов токена, по умолчанию с таймаутом 10 секунд
+	Client *http.Client
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+	fetches   int
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (c *ClientCredentials) Authenticate(ctx context.Context, req *http.Request, _ []byte) error {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Unauthorized сбрасывает токен, с которым сервер ответил 401, следующий запрос получит новый
+// ответы на запросы со старым токеном, пришедшие после обновления, новый токен не сбрасывают
+func (c *ClientCredentials) Unauthorized(req *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && req.Header.Get("Authorization") == "Bearer "+c.token {
+		c.token = ""
+	}
+}
+
+// Fetches сколько раз был запрошен токен
+func (c *ClientCredentials) Fetches() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fetches
+}
+
+// Token действующий токен, при необходимости запрашивает новый
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.refreshAt.IsZero() || time.Now().Before(c.refreshAt)) {
+		return c.token, nil
+	}
+
+	c.fetches++
+	resp, err := c.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("fetch token: %w", err)
+	}
+
+	c.token, c.refreshAt = resp.AccessToken, time.Time{}
+	if resp.ExpiresIn > 0 {
+		c.refreshAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second * 9 / 10)
+	}
+
+	return c.token, nil
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	cli := c.Client
+	if cli == nil {
+		cli = &http.Client{Timeout: tokenTimeout}
+	}
+	resp, err := cli.Do(req)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tokenResponse{}, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+
+	var token tokenResponse
+	if err = json.Unmarshal(raw, &token); err != nil {
+		return tokenResponse{}, fmt.Errorf("parse response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return tokenResponse{}, errors.New("empty access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return tokenResponse{}, fmt.Errorf("unsupported token type %q", token.TokenType)
+	}
+
+	return token, nil
+}
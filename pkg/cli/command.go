@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
 )
 
 type executable interface {
@@ -16,6 +17,8 @@ type Command struct {
 	Description string
 	Flags       []CmdFlag
 	Action      ActionFunc
+	// Subcommands вложенные команды, первый аргумент выбирает одну из них, Flags и Action при этом не используются
+	Subcommands []Command
 
 	fs *flag.FlagSet
 }
@@ -25,6 +28,10 @@ type Command struct {
 type ActionFunc func(ctx context.Context) error
 
 func (c Command) execute(ctx context.Context, args []string) error {
+	if len(c.Subcommands) > 0 {
+		return c.executeSubcommand(ctx, args)
+	}
+
 	if c.Action == nil {
 		return errors.New("action for command not set")
 	}
@@ -44,6 +51,20 @@ func (c Command) execute(ctx context.Context, args []string) error {
 	return c.Action(ctx)
 }
 
+func (c Command) executeSubcommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s: %w", c.Name, NoCommandErr)
+	}
+
+	for _, sc := range c.Subcommands {
+		if sc.Name == args[0] {
+			return sc.execute(ctx, args[1:])
+		}
+	}
+
+	return fmt.Errorf("%s %s: %w", c.Name, args[0], UnknownCommandErr)
+}
+
 func (c Command) getName() string {
 	return c.Name
 }
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSubcommands(t *testing.T) {
+	var id, called string
+	cmd := Command{
+		Name: "history",
+		Subcommands: []Command{
+			{
+				Name:  "show",
+				Flags: []CmdFlag{StringFlag{Name: "id", Destination: &id}},
+				Action: func(context.Context) error {
+					called = "show"
+					return nil
+				},
+			},
+		},
+	}
+
+	require.NoError(t, cmd.execute(context.Background(), []string{"show", "-id", "42"}))
+	require.Equal(t, "show", called)
+	require.Equal(t, "42", id)
+
+	err := cmd.execute(context.Background(), nil)
+	require.True(t, errors.Is(err, NoCommandErr))
+	require.EqualError(t, err, "history: commands is not provided")
+
+	err = cmd.execute(context.Background(), []string{"delete"})
+	require.True(t, errors.Is(err, UnknownCommandErr))
+	require.EqualError(t, err, "history delete: command is unknown")
+
+	help := helpCommand{}.makeDescription(cmd)
+	require.Contains(t, help, "Команда: history ")
+	require.Contains(t, help, "Команда: history show ")
+}
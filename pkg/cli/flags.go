@@ -1,10 +1,17 @@
 package cli
 
-import "flag"
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
 
 // CmdFlag интерфейс для корректной работы help команды и корректного парса флагов командной строки
 type CmdFlag interface {
 	bind(fs *flag.FlagSet)
+	args() []string
 
 	name() string
 	usage() string
@@ -21,6 +28,10 @@ type IntFlag struct {
 func (f IntFlag) bind(fs *flag.FlagSet) {
 	fs.IntVar(f.Destination, f.Name, f.Default, f.Usage)
 }
+func (f IntFlag) args() []string {
+	return []string{fmt.Sprintf("-%s=%d", f.Name, *f.Destination)}
+}
+
 func (f IntFlag) defaultVal() interface{} {
 	return f.Default
 }
@@ -44,6 +55,10 @@ func (f StringFlag) bind(fs *flag.FlagSet) {
 	fs.StringVar(f.Destination, f.Name, f.Default, f.Usage)
 }
 
+func (f StringFlag) args() []string {
+	return []string{fmt.Sprintf("-%s=%s", f.Name, *f.Destination)}
+}
+
 func (f StringFlag) defaultVal() interface{} {
 	return f.Default
 }
@@ -67,6 +82,10 @@ func (f BoolFlag) bind(fs *flag.FlagSet) {
 	fs.BoolVar(f.Destination, f.Name, f.Default, f.Usage)
 }
 
+func (f BoolFlag) args() []string {
+	return []string{fmt.Sprintf("-%s=%t", f.Name, *f.Destination)}
+}
+
 func (f BoolFlag) defaultVal() interface{} {
 	return f.Default
 }
@@ -78,3 +97,112 @@ func (f BoolFlag) usage() string {
 func (f BoolFlag) name() string {
 	return f.Name
 }
+
+// DurationFlag флаг с длительностью в формате time.ParseDuration, например "30s" или "1m30s"
+type DurationFlag struct {
+	Name        string
+	Destination *time.Duration
+	Default     time.Duration
+	Usage       string
+}
+
+func (f DurationFlag) bind(fs *flag.FlagSet) {
+	fs.DurationVar(f.Destination, f.Name, f.Default, f.Usage)
+}
+
+func (f DurationFlag) args() []string {
+	return []string{fmt.Sprintf("-%s=%s", f.Name, *f.Destination)}
+}
+
+func (f DurationFlag) defaultVal() interface{} {
+	return f.Default
+}
+
+func (f DurationFlag) usage() string {
+	return f.Usage
+}
+
+func (f DurationFlag) name() string {
+	return f.Name
+}
+
+// StringsFlag флаг, который можно указать несколько раз, все значения собираются в Destination
+// при первом явном указании флага значение по умолчанию отбрасывается
+type StringsFlag struct {
+	Name        string
+	Destination *[]string
+	Default     []string
+	Usage       string
+}
+
+func (f StringsFlag) bind(fs *flag.FlagSet) {
+	*f.Destination = append([]string(nil), f.Default...)
+	fs.Var(&stringsValue{dst: f.Destination}, f.Name, f.Usage)
+}
+
+func (f StringsFlag) args() []string {
+	args := make([]string, 0, len(*f.Destination))
+	for _, v := range *f.Destination {
+		args = append(args, fmt.Sprintf("-%s=%s", f.Name, v))
+	}
+
+	return args
+}
+
+func (f StringsFlag) defaultVal() interface{} {
+	return strings.Join(f.Default, ",")
+}
+
+func (f StringsFlag) usage() string {
+	return f.Usage
+}
+
+func (f StringsFlag) name() string {
+	return f.Name
+}
+
+type stringsValue struct {
+	dst *[]string
+	set bool
+}
+
+func (v *stringsValue) String() string {
+	if v.dst == nil {
+		return ""
+	}
+
+	return strings.Join(*v.dst, ",")
+}
+
+func (v *stringsValue) Set(s string) error {
+	if !v.set {
+		*v.dst = nil
+		v.set = true
+	}
+	*v.dst = append(*v.dst, s)
+
+	return nil
+}
+
+// Parse разбирает args в Destination флагов так же, как это делает команда при запуске
+func Parse(name string, flags []CmdFlag, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	// ошибка возвращается вызывающему, печатать usage не нужно
+	fs.SetOutput(io.Discard)
+	for _, f := range flags {
+		f.bind(fs)
+	}
+
+	return fs.Parse(args)
+}
+
+// Args собирает текущие значения флагов обратно в аргументы командной строки
+// результат можно передать в Parse, например, чтобы запустить команду на другой машине
+func Args(flags []CmdFlag) []string {
+	var args []string
+	for _, f := range flags {
+		args = append(args, f.args()...)
+	}
+
+	return args
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"flag"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStringsFlag(t *testing.T) {
+	type testCase struct {
+		name     string
+		args     []string
+		expected []string
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "default value",
+			expected: []string{"human"},
+		},
+		{
+			name:     "default value is replaced",
+			args:     []string{"-o", "json"},
+			expected: []string{"json"},
+		},
+		{
+			name:     "several values",
+			args:     []string{"-o", "json", "-o", "html=report.html"},
+			expected: []string{"json", "html=report.html"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var dst []string
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			StringsFlag{Name: "o", Destination: &dst, Default: []string{"human"}}.bind(fs)
+
+			require.NoError(t, fs.Parse(tc.args))
+			require.Equal(t, tc.expected, dst)
+		})
+	}
+}
+
+func TestArgs(t *testing.T) {
+	var (
+		n       int
+		host    string
+		verbose bool
+		outputs []string
+		warmup  time.Duration
+	)
+	flags := []CmdFlag{
+		IntFlag{Name: "n", Destination: &n},
+		StringFlag{Name: "host", Destination: &host},
+		BoolFlag{Name: "v", Destination: &verbose},
+		StringsFlag{Name: "o", Destination: &outputs, Default: []string{"human"}},
+		DurationFlag{Name: "warmup", Destination: &warmup},
+	}
+
+	require.NoError(t, Parse("test", flags, []string{"-n", "10", "-host", "http://host?a=b", "-o", "json", "-o", "html=r.html", "-warmup", "1m30s"}))
+	args := Args(flags)
+	require.Equal(t, []string{"-n=10", "-host=http://host?a=b", "-v=false", "-o=json", "-o=html=r.html", "-warmup=1m30s"}, args)
+
+	n, host, outputs, warmup = 0, "", nil, 0
+	require.NoError(t, Parse("test", flags, args))
+	require.Equal(t, 10, n)
+	require.Equal(t, "http://host?a=b", host)
+	require.Equal(t, []string{"json", "html=r.html"}, outputs)
+	require.Equal(t, 90*time.Second, warmup)
+}
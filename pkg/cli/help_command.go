@@ -67,6 +67,10 @@ func (hc helpCommand) makeDescription(cmd Command) string {
 			description += fmt.Sprintf(flagTmpl, f.name(), f.usage(), f.defaultVal())
 		}
 	}
+	for _, sc := range cmd.Subcommands {
+		sc.Name = cmd.Name + " " + sc.Name
+		description += hc.makeDescription(sc)
+	}
 
 	return description
 }
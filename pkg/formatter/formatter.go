@@ -0,0 +1,150 @@
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strings"
+)
+
+const (
+	Yaml     = "yaml"
+	Json     = "json"
+	Human    = "human"
+	Html     = "html"
+	Junit    = "junit"
+	Markdown = "markdown"
+)
+
+var supportedFormats = map[string]Formatter{
+	Json:     jsonFormat,
+	Yaml:     yamlFormat,
+	Human:    humanFormat,
+	Html:     htmlFormat,
+	Junit:    junitFormat,
+	Markdown: markdownFormat,
+}
+
+// Formatter функция, преобразующая отчёт по нагрузке в вывод конкретного формата
+type Formatter func(rep Report) ([]byte, error)
+
+// SetFormat позволяет выставлять новый формат вывода отчёта
+// метод перезаписывает существующий функционал для конкретного формата
+// имя формата не может быть пустым и содержать "=", которым во флаге -o отделяется путь к файлу
+func SetFormat(format string, f Formatter) error {
+	if strings.TrimSpace(format) == "" {
+		return errors.New("empty format name")
+	}
+	if strings.Contains(format, "=") {
+		return fmt.Errorf("format name %q contains \"=\"", format)
+	}
+	if f == nil {
+		return fmt.Errorf("nil formatter for format %s", format)
+	}
+
+	supportedFormats[format] = f
+	return nil
+}
+
+// Formats выдаёт все поддерживаемые форматы в алфавитном порядке
+func Formats() []string {
+	formats := make([]string, 0, len(supportedFormats))
+	for f := range supportedFormats {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// Supported проверяет, что для формата зарегистрирован Formatter
+func Supported(format string) bool {
+	_, ok := supportedFormats[format]
+	return ok
+}
+
+// Format отрисовывает отчёт в формате format
+func Format(format string, rep Report) ([]byte, error) {
+	f, ok := supportedFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	return f(rep)
+}
+
+func humanFormat(rep Report) ([]byte, error) {
+	messageFormat := "Всего запросов: %d \nИз них \nУспешно: %d \nС ошибкой: %d \nОтменённых: %d \nСреднее время запроса(сек): %d"
+
+	message := fmt.Sprintf(messageFormat, rep.All, rep.Success, rep.Errors, rep.Canceled, rep.AvgRespTime)
+	if l := rep.Latency; l != nil {
+		message += fmt.Sprintf("\nВремя ответа: p50 %v мс, p90 %v мс, p95 %v мс, p99 %v мс, max %v мс", l.P50, l.P90, l.P95, l.P99, l.Max)
+	}
+	if l := rep.CorrectedLatency; l != nil {
+		message += fmt.Sprintf("\nВремя ответа с коррекцией CO: p50 %v мс, p90 %v мс, p95 %v мс, p99 %v мс, max %v мс", l.P50, l.P90, l.P95, l.P99, l.Max)
+	}
+	if len(rep.GrpcCodes) > 0 {
+		message += "\nКоды gRPC: " + strings.Join(grpcCodeCounts(rep.GrpcCodes), ", ")
+	}
+	for _, op := range rep.Operations {
+		message += fmt.Sprintf("\nОперация GraphQL %s: запросов %d, ошибок %d, p50 %v мс, p95 %v мс, p99 %v мс", op.Name, op.Requests, op.Errors, op.P50, op.P95, op.P99)
+	}
+	for _, addr := range rep.Addresses {
+		message += fmt.Sprintf("\nАдрес %s: запросов %d, ошибок %d, p50 %v мс, p95 %v мс, p99 %v мс", addr.Name, addr.Requests, addr.Errors, addr.P50, addr.P95, addr.P99)
+	}
+	for _, src := range rep.Sources {
+		message += fmt.Sprintf("\nЛокальный ip %s: запросов %d, ошибок %d, p50 %v мс, p95 %v мс, p99 %v мс", src.Name, src.Requests, src.Errors, src.P50, src.P95, src.P99)
+	}
+	if p := rep.ProxyConnect; p != nil {
+		message += fmt.Sprintf("\nСоединение через прокси: p50 %v мс, p99 %v мс, max %v мс", p.P50, p.P99, p.Max)
+	}
+	if w := rep.Warmup; w != nil {
+		message += fmt.Sprintf("\nПрогрев (не входит в отчёт): %d запросов за %v сек, ошибок: %d", w.All, w.Duration, w.Errors)
+	}
+	if r := rep.Retry; r != nil {
+		message += fmt.Sprintf("\nС первой попытки: %d, после повтора: %d, неуспешно после всех попыток: %d, повторных запросов: %d", r.FirstTry, r.AfterRetry, r.Failed, r.Retries)
+	}
+	if c := rep.Connections; c != nil {
+		message += fmt.Sprintf("\nСоединения: открыто %d, не удалось открыть %d, разорвано %d, время соединения %v мс, получено сообщений %d (%v в сек)", c.Opened, c.Failed, c.Dropped, c.ConnectTime, c.Messages, c.MessageRps)
+		if c.InterEventP99 > 0 {
+			message += fmt.Sprintf("\nМежду событиями: p50 %v мс, p99 %v мс", c.InterEventP50, c.InterEventP99)
+		}
+	}
+	if s := rep.Socket; s != nil {
+		message += fmt.Sprintf("\nОтправлено: %d запросов, %d байт, получено %d байт (%v байт в сек), потеряно пакетов: %d (%v%%)", s.Sent, s.BytesSent, s.BytesReceived, s.Throughput, s.Lost, s.LossRate)
+	}
+	if th := rep.Throttle; th != nil {
+		message += fmt.Sprintf("\nОграничение скорости: ответов 429/503: %d, пауза исполнителей: %v сек, принято без ограничения: %v rps", th.Events, th.ThrottledTime, th.AllowedRps)
+	}
+	if a := rep.Adaptive; a != nil {
+		message += fmt.Sprintf("\nУстойчивая нагрузка при p95 <= %v мс: %v rps, одновременных запросов: %d", a.TargetP95, a.Rps, a.Concurrency)
+	}
+
+	return []byte(message), nil
+}
+
+// grpcCodeCounts "код: количество" для каждого кода gRPC в алфавитном порядке
+func grpcCodeCounts(grpcCodes map[string]int) []string {
+	codes := make([]string, 0, len(grpcCodes))
+	for code := range grpcCodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	counts := make([]string, 0, len(codes))
+	for _, code := range codes {
+		counts = append(counts, fmt.Sprintf("%s: %d", code, grpcCodes[code]))
+	}
+
+	return counts
+}
+
+func jsonFormat(rep Report) ([]byte, error) {
+	return json.MarshalIndent(&rep, "", " ")
+}
+
+func yamlFormat(rep Report) ([]byte, error) {
+	return yaml.Marshal(&rep)
+}
@@ -0,0 +1,322 @@
+package formatter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	type testCase struct {
+		name        string
+		rep         Report
+		format      string
+		expectedRes []byte
+		expectedErr error
+	}
+
+	const (
+		jsonOutPut = `{
+ "success": 0,
+ "canceled": 2,
+ "errors": 3,
+ "all": 1,
+ "avgRespTime": 0
+}`
+		yamlOutPut = `success: 0
+canceled: 123
+errors: 9
+all: 4
+avgRespTime: 0
+`
+
+		humanOutPut = `Всего запросов: 5 
+Из них 
+Успешно: 0 
+С ошибкой: 234 
+Отменённых: 321 
+Среднее время запроса(сек): 0`
+
+		humanAdaptiveOutput = `Всего запросов: 0 
+Из них 
+Успешно: 0 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Устойчивая нагрузка при p95 <= 250 мс: 812.5 rps, одновременных запросов: 12`
+
+		humanWarmupOutput = `Всего запросов: 0 
+Из них 
+Успешно: 0 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Прогрев (не входит в отчёт): 100 запросов за 1.5 сек, ошибок: 2`
+
+		humanRetryOutput = `Всего запросов: 10 
+Из них 
+Успешно: 8 
+С ошибкой: 2 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+С первой попытки: 5, после повтора: 3, неуспешно после всех попыток: 2, повторных запросов: 7`
+
+		humanThrottleOutput = `Всего запросов: 0 
+Из них 
+Успешно: 0 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Ограничение скорости: ответов 429/503: 12, пауза исполнителей: 7.5 сек, принято без ограничения: 99.5 rps`
+
+		humanConnectionsOutput = `Всего запросов: 10 
+Из них 
+Успешно: 10 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Соединения: открыто 2, не удалось открыть 1, разорвано 1, время соединения 1.5 мс, получено сообщений 12 (40 в сек)`
+
+		humanStreamOutput = `Всего запросов: 2 
+Из них 
+Успешно: 2 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Соединения: открыто 2, не удалось открыть 0, разорвано 0, время соединения 1 мс, получено сообщений 40 (20 в сек)
+Между событиями: p50 50 мс, p99 120.5 мс`
+
+		humanSocketOutput = `Всего запросов: 4 
+Из них 
+Успешно: 3 
+С ошибкой: 0 
+Отменённых: 1 
+Среднее время запроса(сек): 0
+Соединения: открыто 1, не удалось открыть 0, разорвано 0, время соединения 0.5 мс, получено сообщений 3 (30 в сек)
+Отправлено: 4 запросов, 16 байт, получено 12 байт (280 байт в сек), потеряно пакетов: 1 (25%)`
+
+		humanGraphQLOutput = `Всего запросов: 3 
+Из них 
+Успешно: 2 
+С ошибкой: 1 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Операция GraphQL AddUser: запросов 1, ошибок 1, p50 20 мс, p95 20 мс, p99 20 мс
+Операция GraphQL GetUser: запросов 2, ошибок 0, p50 10 мс, p95 12.5 мс, p99 12.5 мс`
+
+		humanProxyOutput = `Всего запросов: 2 
+Из них 
+Успешно: 2 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Соединение через прокси: p50 3 мс, p99 5.5 мс, max 6 мс`
+
+		humanAddressesOutput = `Всего запросов: 3 
+Из них 
+Успешно: 2 
+С ошибкой: 1 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Адрес 10.0.0.1:443: запросов 2, ошибок 0, p50 10 мс, p95 10 мс, p99 10 мс
+Адрес 10.0.0.2:443: запросов 1, ошибок 1, p50 20 мс, p95 20 мс, p99 20 мс
+Локальный ip 192.168.0.10: запросов 2, ошибок 0, p50 10 мс, p95 10 мс, p99 10 мс
+Локальный ip 192.168.0.11: запросов 1, ошибок 1, p50 20 мс, p95 20 мс, p99 20 мс`
+
+		humanGrpcOutput = `Всего запросов: 10 
+Из них 
+Успешно: 8 
+С ошибкой: 1 
+Отменённых: 1 
+Среднее время запроса(сек): 0
+Коды gRPC: DeadlineExceeded: 1, NotFound: 1, OK: 8`
+
+		humanLatencyOutput = `Всего запросов: 10 
+Из них 
+Успешно: 10 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0
+Время ответа: p50 1 мс, p90 1 мс, p95 2 мс, p99 2 мс, max 100 мс
+Время ответа с коррекцией CO: p50 50 мс, p90 90 мс, p95 95 мс, p99 99 мс, max 100 мс`
+
+		humanAllZeroOutput = `Всего запросов: 0 
+Из них 
+Успешно: 0 
+С ошибкой: 0 
+Отменённых: 0 
+Среднее время запроса(сек): 0`
+	)
+
+	cases := [...]testCase{
+		{
+			name: "ok, normal json format",
+			rep: Report{
+				All:      1,
+				Canceled: 2,
+				Errors:   3,
+			},
+			format:      "json",
+			expectedRes: []byte(jsonOutPut),
+		},
+		{
+			name: "ok, normal yaml format",
+			rep: Report{
+				All:      4,
+				Canceled: 123,
+				Errors:   9,
+			},
+			format:      "yaml",
+			expectedRes: []byte(yamlOutPut),
+		},
+		{
+			name: "ok, human format with raw and corrected latency",
+			rep: Report{
+				All:              10,
+				Success:          10,
+				Latency:          &LatencyStats{P50: 1, P90: 1, P95: 2, P99: 2, Max: 100},
+				CorrectedLatency: &LatencyStats{P50: 50, P90: 90, P95: 95, P99: 99, Max: 100},
+			},
+			format:      "human",
+			expectedRes: []byte(humanLatencyOutput),
+		},
+		{
+			name: "ok, normal human format",
+			rep: Report{
+				All:      5,
+				Canceled: 321,
+				Errors:   234,
+			},
+			format:      "human",
+			expectedRes: []byte(humanOutPut),
+		},
+		{
+			name:        "ok, empty report human format",
+			rep:         Report{},
+			format:      "human",
+			expectedRes: []byte(humanAllZeroOutput),
+		},
+		{
+			name:        "ok, human format with adaptive load",
+			rep:         Report{Adaptive: &AdaptiveStats{TargetP95: 250, Concurrency: 12, Rps: 812.5}},
+			format:      "human",
+			expectedRes: []byte(humanAdaptiveOutput),
+		},
+		{
+			name:        "ok, human format with warm-up",
+			rep:         Report{Warmup: &WarmupStats{All: 100, Errors: 2, Duration: 1.5}},
+			format:      "human",
+			expectedRes: []byte(humanWarmupOutput),
+		},
+		{
+			name:        "ok, human format with retries",
+			rep:         Report{All: 10, Success: 8, Errors: 2, Retry: &RetryStats{FirstTry: 5, AfterRetry: 3, Failed: 2, Retries: 7}},
+			format:      "human",
+			expectedRes: []byte(humanRetryOutput),
+		},
+		{
+			name:        "ok, human format with throttling",
+			rep:         Report{Throttle: &ThrottleStats{Events: 12, ThrottledTime: 7.5, AllowedRps: 99.5}},
+			format:      "human",
+			expectedRes: []byte(humanThrottleOutput),
+		},
+		{
+			name:        "ok, human format with connections",
+			rep:         Report{All: 10, Success: 10, Connections: &ConnectionStats{Opened: 2, Failed: 1, Dropped: 1, ConnectTime: 1.5, Messages: 12, MessageRps: 40}},
+			format:      "human",
+			expectedRes: []byte(humanConnectionsOutput),
+		},
+		{
+			name:        "ok, human format with streams",
+			rep:         Report{All: 2, Success: 2, Connections: &ConnectionStats{Opened: 2, ConnectTime: 1, Messages: 40, MessageRps: 20, InterEventP50: 50, InterEventP99: 120.5}},
+			format:      "human",
+			expectedRes: []byte(humanStreamOutput),
+		},
+		{
+			name:        "ok, human format with udp load",
+			rep:         Report{All: 4, Success: 3, Canceled: 1, Connections: &ConnectionStats{Opened: 1, ConnectTime: 0.5, Messages: 3, MessageRps: 30}, Socket: &SocketStats{Sent: 4, Lost: 1, LossRate: 25, BytesSent: 16, BytesReceived: 12, Throughput: 280}},
+			format:      "human",
+			expectedRes: []byte(humanSocketOutput),
+		},
+		{
+			name: "ok, human format with graphql operations",
+			rep: Report{All: 3, Success: 2, Errors: 1, Operations: []GroupStats{
+				{Name: "AddUser", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+				{Name: "GetUser", Requests: 2, P50: 10, P95: 12.5, P99: 12.5},
+			}},
+			format:      "human",
+			expectedRes: []byte(humanGraphQLOutput),
+		},
+		{
+			name:        "ok, human format with proxy connect time",
+			rep:         Report{All: 2, Success: 2, ProxyConnect: &LatencyStats{P50: 3, P99: 5.5, Max: 6}},
+			format:      "human",
+			expectedRes: []byte(humanProxyOutput),
+		},
+		{
+			name: "ok, human format with addresses and source ips",
+			rep: Report{All: 3, Success: 2, Errors: 1, Addresses: []GroupStats{
+				{Name: "10.0.0.1:443", Requests: 2, P50: 10, P95: 10, P99: 10},
+				{Name: "10.0.0.2:443", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+			}, Sources: []GroupStats{
+				{Name: "192.168.0.10", Requests: 2, P50: 10, P95: 10, P99: 10},
+				{Name: "192.168.0.11", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+			}},
+			format:      "human",
+			expectedRes: []byte(humanAddressesOutput),
+		},
+		{
+			name:        "ok, human format with grpc codes",
+			rep:         Report{All: 10, Success: 8, Errors: 1, Canceled: 1, GrpcCodes: map[string]int{"OK": 8, "NotFound": 1, "DeadlineExceeded": 1}},
+			format:      "human",
+			expectedRes: []byte(humanGrpcOutput),
+		},
+		{
+			name:        "error, unknown format",
+			rep:         Report{},
+			format:      "xml",
+			expectedErr: fmt.Errorf("unknown format: %s", "xml"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := Format(tc.format, tc.rep)
+
+			require.Equal(t, tc.expectedRes, output)
+			require.Equal(t, tc.expectedErr, err)
+		})
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	const markdown = "test-markdown"
+	require.False(t, Supported(markdown))
+
+	require.NoError(t, SetFormat(markdown, func(rep Report) ([]byte, error) {
+		return []byte(fmt.Sprintf("| all | %d |", rep.All)), nil
+	}))
+	defer delete(supportedFormats, markdown)
+
+	require.True(t, Supported(markdown))
+	require.Contains(t, Formats(), markdown)
+
+	out, err := Format(markdown, Report{All: 7})
+	require.NoError(t, err)
+	require.Equal(t, "| all | 7 |", string(out))
+
+	require.NoError(t, SetFormat(markdown, func(rep Report) ([]byte, error) {
+		return nil, errors.New("overridden")
+	}))
+	_, err = Format(markdown, Report{})
+	require.Equal(t, errors.New("overridden"), err)
+
+	valid := func(Report) ([]byte, error) { return nil, nil }
+	require.EqualError(t, SetFormat("", valid), "empty format name")
+	require.EqualError(t, SetFormat(" \t", valid), "empty format name")
+	require.EqualError(t, SetFormat("csv=out", valid), `format name "csv=out" contains "="`)
+	require.EqualError(t, SetFormat("csv", nil), "nil formatter for format csv")
+	require.False(t, Supported("csv"))
+	require.Len(t, Formats(), 7)
+}
@@ -0,0 +1,418 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	chartWidth   = 720
+	chartHeight  = 240
+	chartPadding = 40
+	pieRadius    = 100
+)
+
+var chartColors = [...]string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7"}
+
+// htmlTemplate самодостаточная страница отчёта: стили и графики (svg) встроены,
+// внешних ресурсов нет, файл можно прикладывать к задачам и артефактам CI
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Отчёт по нагрузке</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+h2 { margin-top: 32px; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 12px; text-align: left; }
+svg { background: #fafafa; border: 1px solid #eee; }
+svg text { font-size: 11px; fill: #555; }
+.legend span { display: inline-block; margin-right: 16px; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>Отчёт по нагрузке</h1>
+<table>
+<tr><th>Всего запросов</th><td>{{.Report.All}}</td></tr>
+<tr><th>Успешно</th><td>{{.Report.Success}}</td></tr>
+<tr><th>С ошибкой</th><td>{{.Report.Errors}}</td></tr>
+<tr><th>Отменённых</th><td>{{.Report.Canceled}}</td></tr>
+{{- with .Report.Latency}}
+<tr><th>Время ответа min / mean / max (мс)</th><td>{{.Min}} / {{.Mean}} / {{.Max}}</td></tr>
+<tr><th>p50 / p90 / p95 / p99 (мс)</th><td>{{.P50}} / {{.P90}} / {{.P95}} / {{.P99}}</td></tr>
+{{- end}}
+{{- with .Report.Warmup}}
+<tr><th>Прогрев, не входит в отчёт (запросов / ошибок / сек)</th><td>{{.All}} / {{.Errors}} / {{.Duration}}</td></tr>
+{{- end}}
+{{- with .Report.Retry}}
+<tr><th>С первой попытки / после повтора / неуспешно после всех попыток (повторных запросов)</th><td>{{.FirstTry}} / {{.AfterRetry}} / {{.Failed}} ({{.Retries}})</td></tr>
+{{- end}}
+{{- with .Report.Connections}}
+<tr><th>Соединения: открыто / не удалось открыть / разорвано</th><td>{{.Opened}} / {{.Failed}} / {{.Dropped}}</td></tr>
+<tr><th>Время соединения (мс) / получено сообщений / сообщений в секунду</th><td>{{.ConnectTime}} / {{.Messages}} / {{.MessageRps}}</td></tr>
+{{- if .InterEventP99}}
+<tr><th>Между событиями p50 / p99 (мс)</th><td>{{.InterEventP50}} / {{.InterEventP99}}</td></tr>
+{{- end}}
+{{- end}}
+{{- with .Report.Socket}}
+<tr><th>Отправлено запросов / байт / получено байт / байт в секунду</th><td>{{.Sent}} / {{.BytesSent}} / {{.BytesReceived}} / {{.Throughput}}</td></tr>
+<tr><th>Потеряно пакетов (%)</th><td>{{.Lost}} ({{.LossRate}})</td></tr>
+{{- end}}
+{{- with .Report.Throttle}}
+<tr><th>Ограничение скорости: ответов 429/503 / пауза исполнителей (сек) / принято без ограничения (rps)</th><td>{{.Events}} / {{.ThrottledTime}} / {{.AllowedRps}}</td></tr>
+{{- end}}
+{{- with .Report.CorrectedLatency}}
+<tr><th>p50 / p90 / p95 / p99 с коррекцией coordinated omission (мс)</th><td>{{.P50}} / {{.P90}} / {{.P95}} / {{.P99}}</td></tr>
+{{- end}}
+{{- with .Report.ProxyConnect}}
+<tr><th>Соединение через прокси p50 / p99 / max (мс)</th><td>{{.P50}} / {{.P99}} / {{.Max}}</td></tr>
+{{- end}}
+</table>
+{{with .Report.Thresholds}}
+<h2>Пороги</h2>
+<table>
+<tr><th>Порог</th><th>Значение</th><th>Результат</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{if .Message}}{{.Message}}{{else}}{{.Value}}{{end}}</td><td>{{if .Passed}}пройден{{else}}не пройден{{end}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{with .Histogram}}
+<h2>Распределение времени ответа</h2>
+{{template "chart" .}}
+{{end}}
+{{with .Percentiles}}
+<h2>Перцентили времени ответа по секундам (мс)</h2>
+{{template "chart" .}}
+{{end}}
+{{with .Rps}}
+<h2>Запросов в секунду</h2>
+{{template "chart" .}}
+{{end}}
+{{with .Statuses}}
+<h2>Коды ответа</h2>
+{{template "pie" .}}
+{{end}}
+{{with .GrpcCodes}}
+<h2>Коды gRPC</h2>
+{{template "pie" .}}
+{{end}}
+{{with .Report.Operations}}
+<h2>Операции GraphQL</h2>
+<table>
+<tr><th>Операция</th><th>Запросов</th><th>Ошибок</th><th>p50 / p95 / p99 (мс)</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{.P50}} / {{.P95}} / {{.P99}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{with .Report.Addresses}}
+<h2>Адреса серверов</h2>
+<table>
+<tr><th>Адрес</th><th>Запросов</th><th>Ошибок</th><th>p50 / p95 / p99 (мс)</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{.P50}} / {{.P95}} / {{.P99}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{with .Report.Sources}}
+<h2>Локальные ip</h2>
+<table>
+<tr><th>Локальный ip</th><th>Запросов</th><th>Ошибок</th><th>p50 / p95 / p99 (мс)</th></tr>
+{{- range .}}
+<tr><td>{{.Name}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{.P50}} / {{.P95}} / {{.P99}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+{{with .Report.ErrorSamples}}
+<h2>Примеры ошибок</h2>
+<table>
+<tr><th>Секунда</th><th>Статус</th><th>Ошибка</th></tr>
+{{- range .}}
+<tr><td>{{.Second}}</td><td>{{if .Status}}{{.Status}}{{end}}</td><td>{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{end}}
+</body>
+</html>
+{{define "pie"}}
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{- range .Slices}}
+<path d="{{.Path}}" fill="{{.Color}}"><title>{{.Label}}</title></path>
+{{- end}}
+</svg>
+<div class="legend">{{range .Slices}}<span><i style="background: {{.Color}}"></i>{{.Label}}</span>{{end}}</div>
+{{end}}
+{{define "chart"}}
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{- range .Bars}}
+<rect x="{{.X}}" y="{{.Y}}" width="{{.W}}" height="{{.H}}" fill="{{.Color}}"><title>{{.Label}}</title></rect>
+{{- end}}
+{{- range .Lines}}
+<polyline points="{{.Points}}" fill="none" stroke="{{.Color}}" stroke-width="2"/>
+{{- end}}
+<line x1="{{.Left}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}" stroke="#999"/>
+<line x1="{{.Left}}" y1="{{.Top}}" x2="{{.Left}}" y2="{{.Bottom}}" stroke="#999"/>
+<text x="{{.Left}}" y="{{.Top}}" text-anchor="end" dx="-4" dy="4">{{.MaxY}}</text>
+<text x="{{.Left}}" y="{{.Bottom}}" text-anchor="end" dx="-4">0</text>
+<text x="{{.Left}}" y="{{.Bottom}}" dy="16">{{.MinX}}</text>
+<text x="{{.Right}}" y="{{.Bottom}}" dy="16" text-anchor="end">{{.MaxX}}</text>
+</svg>
+{{- with .Lines}}
+<div class="legend">{{range .}}<span><i style="background: {{.Color}}"></i>{{.Label}}</span>{{end}}</div>
+{{- end}}
+{{end}}`))
+
+type htmlPage struct {
+	Report      Report
+	Histogram   *svgChart
+	Percentiles *svgChart
+	Rps         *svgChart
+	Statuses    *svgPie
+	GrpcCodes   *svgPie
+}
+
+type svgChart struct {
+	Width, Height            int
+	Left, Right, Top, Bottom int
+	MinX, MaxX, MaxY         string
+	Bars                     []svgBar
+	Lines                    []svgLine
+}
+
+type svgBar struct {
+	X, Y, W, H float64
+	Color      string
+	Label      string
+}
+
+type svgLine struct {
+	Points string
+	Color  string
+	Label  string
+}
+
+type svgPie struct {
+	Width, Height int
+	Slices        []svgSlice
+}
+
+type svgSlice struct {
+	Path  string
+	Color string
+	Label string
+}
+
+type series struct {
+	label  string
+	values []float64
+}
+
+func htmlFormat(rep Report) ([]byte, error) {
+	page := htmlPage{
+		Report:    rep,
+		Statuses:  statusPie(rep.StatusCodes),
+		GrpcCodes: grpcCodePie(rep.GrpcCodes),
+	}
+
+	if rep.Latency != nil {
+		page.Histogram = histogramChart(rep.Latency.Histogram)
+	}
+
+	if len(rep.Timeline) > 0 {
+		var p50, p90, p99, rps, errs []float64
+		for _, p := range rep.Timeline {
+			p50 = append(p50, p.P50)
+			p90 = append(p90, p.P90)
+			p99 = append(p99, p.P99)
+			rps = append(rps, float64(p.Requests))
+			errs = append(errs, float64(p.Errors))
+		}
+		first, last := rep.Timeline[0].Second, rep.Timeline[len(rep.Timeline)-1].Second
+		page.Percentiles = lineChart(first, last, series{"p50", p50}, series{"p90", p90}, series{"p99", p99})
+		page.Rps = lineChart(first, last, series{"запросы", rps}, series{"ошибки", errs})
+	}
+
+	buf := bytes.Buffer{}
+	if err := htmlTemplate.Execute(&buf, page); err != nil {
+		return nil, fmt.Errorf("render html: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func newSvgChart() *svgChart {
+	return &svgChart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartPadding,
+		Right:  chartWidth - chartPadding/2,
+		Top:    chartPadding / 2,
+		Bottom: chartHeight - chartPadding,
+	}
+}
+
+func histogramChart(bars []HistogramBar) *svgChart {
+	if len(bars) == 0 {
+		return nil
+	}
+
+	chart := newSvgChart()
+	var maxCount uint64
+	for _, b := range bars {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+
+	plotW := float64(chart.Right - chart.Left)
+	plotH := float64(chart.Bottom - chart.Top)
+	barW := plotW / float64(len(bars))
+	for i, b := range bars {
+		h := plotH * float64(b.Count) / float64(maxCount)
+		chart.Bars = append(chart.Bars, svgBar{
+			X:     round2(float64(chart.Left) + float64(i)*barW),
+			Y:     round2(float64(chart.Bottom) - h),
+			W:     round2(barW * 0.9),
+			H:     round2(h),
+			Color: chartColors[0],
+			Label: fmt.Sprintf("%v–%v мс: %d", b.From, b.To, b.Count),
+		})
+	}
+
+	chart.MinX = fmt.Sprintf("%v мс", bars[0].From)
+	chart.MaxX = fmt.Sprintf("%v мс", bars[len(bars)-1].To)
+	chart.MaxY = fmt.Sprint(maxCount)
+
+	return chart
+}
+
+func lineChart(firstSecond, lastSecond int, lines ...series) *svgChart {
+	chart := newSvgChart()
+
+	var maxY float64
+	for _, l := range lines {
+		for _, v := range l.values {
+			maxY = math.Max(maxY, v)
+		}
+	}
+	if maxY == 0 {
+		maxY = 1
+	}
+
+	plotW := float64(chart.Right - chart.Left)
+	plotH := float64(chart.Bottom - chart.Top)
+	for i, l := range lines {
+		step := plotW
+		if len(l.values) > 1 {
+			step = plotW / float64(len(l.values)-1)
+		}
+
+		points := make([]string, 0, len(l.values))
+		for j, v := range l.values {
+			x := float64(chart.Left) + float64(j)*step
+			y := float64(chart.Bottom) - plotH*v/maxY
+			points = append(points, fmt.Sprintf("%v,%v", round2(x), round2(y)))
+		}
+
+		chart.Lines = append(chart.Lines, svgLine{
+			Points: strings.Join(points, " "),
+			Color:  chartColors[i%len(chartColors)],
+			Label:  l.label,
+		})
+	}
+
+	chart.MinX = fmt.Sprintf("%d с", firstSecond)
+	chart.MaxX = fmt.Sprintf("%d с", lastSecond)
+	chart.MaxY = fmt.Sprint(round2(maxY))
+
+	return chart
+}
+
+func statusPie(statuses map[int]int) *svgPie {
+	codes := make([]int, 0, len(statuses))
+	for code := range statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	labels := make([]string, 0, len(codes))
+	counts := make([]int, 0, len(codes))
+	for _, code := range codes {
+		labels = append(labels, strconv.Itoa(code))
+		counts = append(counts, statuses[code])
+	}
+
+	return pieChart(labels, counts)
+}
+
+func grpcCodePie(grpcCodes map[string]int) *svgPie {
+	labels := make([]string, 0, len(grpcCodes))
+	for code := range grpcCodes {
+		labels = append(labels, code)
+	}
+	sort.Strings(labels)
+
+	counts := make([]int, 0, len(labels))
+	for _, code := range labels {
+		counts = append(counts, grpcCodes[code])
+	}
+
+	return pieChart(labels, counts)
+}
+
+// pieChart круговая диаграмма с долями counts, подписанными labels
+func pieChart(labels []string, counts []int) *svgPie {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	var total int
+	for _, count := range counts {
+		total += count
+	}
+
+	const cx, cy = pieRadius + 10, pieRadius + 10
+	pie := &svgPie{Width: 2*pieRadius + 20, Height: 2*pieRadius + 20}
+
+	var angle float64
+	for i, count := range counts {
+		label := fmt.Sprintf("%s: %d", labels[i], count)
+		color := chartColors[i%len(chartColors)]
+
+		if count == total {
+			// дугу в полный круг svg не рисует, поэтому круг задаётся двумя половинами
+			path := fmt.Sprintf("M %d %d m -%d 0 a %d %d 0 1 0 %d 0 a %d %d 0 1 0 -%d 0",
+				cx, cy, pieRadius, pieRadius, pieRadius, 2*pieRadius, pieRadius, pieRadius, 2*pieRadius)
+			pie.Slices = append(pie.Slices, svgSlice{Path: path, Color: color, Label: label})
+			continue
+		}
+
+		sweep := 2 * math.Pi * float64(count) / float64(total)
+		x1, y1 := cx+pieRadius*math.Sin(angle), cy-pieRadius*math.Cos(angle)
+		angle += sweep
+		x2, y2 := cx+pieRadius*math.Sin(angle), cy-pieRadius*math.Cos(angle)
+
+		largeArc := 0
+		if sweep > math.Pi {
+			largeArc = 1
+		}
+
+		path := fmt.Sprintf("M %d %d L %v %v A %d %d 0 %d 1 %v %v Z",
+			cx, cy, round2(x1), round2(y1), pieRadius, pieRadius, largeArc, round2(x2), round2(y2))
+		pie.Slices = append(pie.Slices, svgSlice{Path: path, Color: color, Label: label})
+	}
+
+	return pie
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
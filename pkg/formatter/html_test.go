@@ -0,0 +1,101 @@
+package formatter
+
+import (
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestReportToHtml(t *testing.T) {
+	t.Run("empty report has no charts", func(t *testing.T) {
+		out, err := Format(Html, Report{})
+
+		require.NoError(t, err)
+		require.Contains(t, string(out), "<h1>Отчёт по нагрузке</h1>")
+		require.NotContains(t, string(out), "<svg")
+	})
+
+	t.Run("all charts are embedded, no external assets", func(t *testing.T) {
+		rep := Report{
+			All:     3,
+			Success: 2,
+			Errors:  1,
+			Latency: &LatencyStats{
+				Min: 1, Max: 3,
+				Histogram: []HistogramBar{{From: 1, To: 2, Count: 1}, {From: 2, To: 3, Count: 2}},
+			},
+			StatusCodes:  map[int]int{200: 2, 500: 1},
+			Timeline:     []TimelinePoint{{Second: 0, Requests: 2, P50: 1}, {Second: 1, Requests: 1, Errors: 1, P50: 3}},
+			ErrorSamples: []ErrorSample{{Second: 1, Status: 500, Error: "<script>alert(1)</script>"}},
+		}
+
+		out, err := Format(Html, rep)
+		require.NoError(t, err)
+
+		html := string(out)
+		require.Equal(t, 4, strings.Count(html, "<svg"), "histogram, percentiles, rps and statuses")
+		require.Contains(t, html, "<title>200: 2</title>")
+		require.Contains(t, html, "&lt;script&gt;alert(1)&lt;/script&gt;")
+		require.NotContains(t, html, "src=")
+		require.NotContains(t, html, "href=")
+	})
+
+	t.Run("grpc codes", func(t *testing.T) {
+		out, err := Format(Html, Report{All: 3, GrpcCodes: map[string]int{"OK": 2, "Unavailable": 1}})
+		require.NoError(t, err)
+
+		html := string(out)
+		require.Contains(t, html, "<h2>Коды gRPC</h2>")
+		require.Contains(t, html, "<title>OK: 2</title>")
+		require.Contains(t, html, "<title>Unavailable: 1</title>")
+	})
+
+	t.Run("graphql operations", func(t *testing.T) {
+		out, err := Format(Html, Report{All: 2, Operations: []GroupStats{{Name: "GetUser", Requests: 2, P50: 10, P95: 12.5, P99: 12.5}}})
+		require.NoError(t, err)
+
+		html := string(out)
+		require.Contains(t, html, "<h2>Операции GraphQL</h2>")
+		require.Contains(t, html, "<tr><td>GetUser</td><td>2</td><td>0</td><td>10 / 12.5 / 12.5</td></tr>")
+		require.NotContains(t, html, "<h2>Адреса серверов</h2>")
+	})
+
+	t.Run("proxy connect time", func(t *testing.T) {
+		out, err := Format(Html, Report{All: 1, ProxyConnect: &LatencyStats{P50: 3, P99: 5.5, Max: 6}})
+		require.NoError(t, err)
+		require.Contains(t, string(out), "<tr><th>Соединение через прокси p50 / p99 / max (мс)</th><td>3 / 5.5 / 6</td></tr>")
+	})
+
+	t.Run("addresses", func(t *testing.T) {
+		out, err := Format(Html, Report{All: 2, Addresses: []GroupStats{{Name: "10.0.0.1:443", Requests: 1, P50: 10, P95: 10, P99: 10}, {Name: "10.0.0.2:443", Requests: 1, Errors: 1}}})
+		require.NoError(t, err)
+
+		html := string(out)
+		require.Contains(t, html, "<h2>Адреса серверов</h2>")
+		require.Contains(t, html, "<tr><td>10.0.0.1:443</td><td>1</td><td>0</td><td>10 / 10 / 10</td></tr>")
+	})
+
+	t.Run("source ips", func(t *testing.T) {
+		out, err := Format(Html, Report{All: 2, Sources: []GroupStats{{Name: "192.168.0.10", Requests: 1}, {Name: "192.168.0.11", Requests: 1, Errors: 1}}})
+		require.NoError(t, err)
+
+		html := string(out)
+		require.Contains(t, html, "<h2>Локальные ip</h2>")
+		require.Contains(t, html, "<tr><td>192.168.0.11</td><td>1</td><td>1</td><td>0 / 0 / 0</td></tr>")
+		require.NotContains(t, html, "<h2>Адреса серверов</h2>")
+	})
+}
+
+func TestStatusPie(t *testing.T) {
+	require.Nil(t, statusPie(nil))
+
+	pie := statusPie(map[int]int{200: 10})
+	require.Len(t, pie.Slices, 1)
+	require.True(t, strings.HasPrefix(pie.Slices[0].Path, "M 110 110 m -100 0"), "full circle")
+
+	pie = statusPie(map[int]int{500: 1, 200: 1})
+	require.Equal(t, []svgSlice{
+		{Path: "M 110 110 L 110 10 A 100 100 0 0 1 110 210 Z", Color: chartColors[0], Label: "200: 1"},
+		{Path: "M 110 110 L 110 210 A 100 100 0 0 1 110 10 Z", Color: chartColors[1], Label: "500: 1"},
+	}, pie.Slices)
+}
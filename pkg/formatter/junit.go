@@ -0,0 +1,112 @@
+package formatter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+const junitSuiteName = "benchutil load"
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitFormat представляет нагрузку в виде JUnit XML: каждый порог - отдельный тест,
+// без порогов единственный тест проверяет, что все запросы к хосту были успешны
+func junitFormat(rep Report) ([]byte, error) {
+	suite := junitTestSuite{
+		Name: junitSuiteName,
+		Properties: []junitProperty{
+			{Name: "all", Value: fmt.Sprint(rep.All)},
+			{Name: "success", Value: fmt.Sprint(rep.Success)},
+			{Name: "errors", Value: fmt.Sprint(rep.Errors)},
+			{Name: "canceled", Value: fmt.Sprint(rep.Canceled)},
+		},
+	}
+	if rep.Latency != nil {
+		suite.Properties = append(suite.Properties,
+			junitProperty{Name: "p50", Value: fmt.Sprint(rep.Latency.P50)},
+			junitProperty{Name: "p99", Value: fmt.Sprint(rep.Latency.P99)},
+		)
+	}
+
+	className := "load." + rep.Host
+	if len(rep.Thresholds) > 0 {
+		for _, th := range rep.Thresholds {
+			tc := junitTestCase{Name: th.Name, ClassName: className}
+			switch {
+			case th.Message != "":
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("threshold %s not passed: %s", th.Name, th.Message),
+					Type:    "threshold",
+				}
+			case !th.Passed:
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("threshold %s not passed, actual value %v", th.Name, th.Value),
+					Type:    "threshold",
+				}
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+	} else {
+		tc := junitTestCase{Name: "all requests succeeded", ClassName: className}
+		if rep.Errors > 0 || rep.Canceled > 0 {
+			tc.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d errors, %d canceled of %d requests", rep.Errors, rep.Canceled, rep.All),
+				Type:    "errors",
+				Text:    junitErrorSamples(rep.ErrorSamples),
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	suite.Tests = len(suite.Cases)
+	for _, tc := range suite.Cases {
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+	}
+
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal junit: %w", err)
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+func junitErrorSamples(samples []ErrorSample) string {
+	lines := make([]string, 0, len(samples))
+	for _, s := range samples {
+		lines = append(lines, fmt.Sprintf("[%ds] %s", s.Second, s.Error))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package formatter
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestReportToJunit(t *testing.T) {
+	type testCase struct {
+		name     string
+		rep      Report
+		expected string
+	}
+
+	cases := [...]testCase{
+		{
+			name: "no thresholds, endpoint test case",
+			rep: Report{
+				All: 3, Success: 2, Errors: 1, Host: "http://host",
+				ErrorSamples: []ErrorSample{{Second: 1, Status: 500, Error: "unexpected status 500"}},
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="benchutil load" tests="1" failures="1">
+    <properties>
+      <property name="all" value="3"></property>
+      <property name="success" value="2"></property>
+      <property name="errors" value="1"></property>
+      <property name="canceled" value="0"></property>
+    </properties>
+    <testcase name="all requests succeeded" classname="load.http://host">
+      <failure message="1 errors, 0 canceled of 3 requests" type="errors">[1s] unexpected status 500</failure>
+    </testcase>
+  </testsuite>
+</testsuites>`,
+		},
+		{
+			name: "test case per threshold",
+			rep: Report{
+				All: 1, Success: 1, Host: "http://host",
+				Latency: &LatencyStats{P50: 10, P99: 600},
+				Thresholds: []ThresholdResult{
+					{Name: "p50<100", Value: 10, Passed: true},
+					{Name: "p99<500", Value: 600},
+					{Name: "max<1000", Message: "no answered requests, latency is unknown"},
+				},
+			},
+			expected: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="benchutil load" tests="3" failures="2">
+    <properties>
+      <property name="all" value="1"></property>
+      <property name="success" value="1"></property>
+      <property name="errors" value="0"></property>
+      <property name="canceled" value="0"></property>
+      <property name="p50" value="10"></property>
+      <property name="p99" value="600"></property>
+    </properties>
+    <testcase name="p50&lt;100" classname="load.http://host"></testcase>
+    <testcase name="p99&lt;500" classname="load.http://host">
+      <failure message="threshold p99&lt;500 not passed, actual value 600" type="threshold"></failure>
+    </testcase>
+    <testcase name="max&lt;1000" classname="load.http://host">
+      <failure message="threshold max&lt;1000 not passed: no answered requests, latency is unknown" type="threshold"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Format(Junit, tc.rep)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(out))
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type markdownRow struct {
+	name     string
+	value    float64
+	baseline float64
+}
+
+// markdownFormat компактная сводка в GitHub-flavoured markdown для комментариев к pull request
+// если у отчёта есть Baseline, таблицы дополняются колонками со значением из базы и разницей
+func markdownFormat(rep Report) ([]byte, error) {
+	buf := bytes.Buffer{}
+	base := rep.Baseline
+
+	title := "Результаты нагрузки"
+	if rep.Host != "" {
+		title += fmt.Sprintf(" `%s`", rep.Host)
+	}
+	fmt.Fprintf(&buf, "### %s\n\n", title)
+
+	rows := []markdownRow{
+		{name: "Всего запросов", value: float64(rep.All)},
+		{name: "Успешно", value: float64(rep.Success)},
+		{name: "С ошибкой", value: float64(rep.Errors)},
+		{name: "Отменённых", value: float64(rep.Canceled)},
+		{name: "RPS", value: rep.Rps},
+	}
+	if base != nil {
+		rows[0].baseline = float64(base.All)
+		rows[1].baseline = float64(base.Success)
+		rows[2].baseline = float64(base.Errors)
+		rows[3].baseline = float64(base.Canceled)
+		rows[4].baseline = base.Rps
+	}
+
+	if rep.Latency != nil {
+		var baseLatency *LatencyStats
+		if base != nil {
+			baseLatency = base.Latency
+		}
+		rows = append(rows, latencyRows(rep.Latency, baseLatency, "")...)
+	}
+
+	if rep.CorrectedLatency != nil {
+		var baseLatency *LatencyStats
+		if base != nil {
+			baseLatency = base.CorrectedLatency
+		}
+		rows = append(rows, latencyRows(rep.CorrectedLatency, baseLatency, " с коррекцией CO")...)
+	}
+
+	if rep.ProxyConnect != nil {
+		var baseLatency *LatencyStats
+		if base != nil {
+			baseLatency = base.ProxyConnect
+		}
+		rows = append(rows, latencyRows(rep.ProxyConnect, baseLatency, " соединения через прокси")...)
+	}
+	writeMarkdownTable(&buf, "Метрика", rows, base != nil)
+
+	if len(rep.StatusCodes) > 0 {
+		var baseCodes map[int]int
+		if base != nil {
+			baseCodes = base.StatusCodes
+		}
+
+		codes := make([]int, 0, len(rep.StatusCodes))
+		for code := range rep.StatusCodes {
+			codes = append(codes, code)
+		}
+		for code := range baseCodes {
+			if _, ok := rep.StatusCodes[code]; !ok {
+				codes = append(codes, code)
+			}
+		}
+		sort.Ints(codes)
+
+		statusRows := make([]markdownRow, 0, len(codes))
+		for _, code := range codes {
+			statusRows = append(statusRows, markdownRow{
+				name:     strconv.Itoa(code),
+				value:    float64(rep.StatusCodes[code]),
+				baseline: float64(baseCodes[code]),
+			})
+		}
+
+		buf.WriteString("\n")
+		writeMarkdownTable(&buf, "Код ответа", statusRows, base != nil)
+	}
+
+	if len(rep.GrpcCodes) > 0 {
+		var baseCodes map[string]int
+		if base != nil {
+			baseCodes = base.GrpcCodes
+		}
+
+		codes := make([]string, 0, len(rep.GrpcCodes))
+		for code := range rep.GrpcCodes {
+			codes = append(codes, code)
+		}
+		for code := range baseCodes {
+			if _, ok := rep.GrpcCodes[code]; !ok {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+
+		codeRows := make([]markdownRow, 0, len(codes))
+		for _, code := range codes {
+			codeRows = append(codeRows, markdownRow{
+				name:     code,
+				value:    float64(rep.GrpcCodes[code]),
+				baseline: float64(baseCodes[code]),
+			})
+		}
+
+		buf.WriteString("\n")
+		writeMarkdownTable(&buf, "Код gRPC", codeRows, base != nil)
+	}
+
+	if len(rep.Operations) > 0 {
+		buf.WriteString("\n| Операция GraphQL | Запросов | Ошибок | p50, мс | p95, мс | p99, мс |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, op := range rep.Operations {
+			fmt.Fprintf(&buf, "| %s | %d | %d | %s | %s | %s |\n", op.Name, op.Requests, op.Errors, formatNumber(op.P50), formatNumber(op.P95), formatNumber(op.P99))
+		}
+	}
+
+	if len(rep.Addresses) > 0 {
+		buf.WriteString("\n| Адрес | Запросов | Ошибок | p50, мс | p95, мс | p99, мс |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, addr := range rep.Addresses {
+			fmt.Fprintf(&buf, "| %s | %d | %d | %s | %s | %s |\n", addr.Name, addr.Requests, addr.Errors, formatNumber(addr.P50), formatNumber(addr.P95), formatNumber(addr.P99))
+		}
+	}
+
+	if len(rep.Sources) > 0 {
+		buf.WriteString("\n| Локальный ip | Запросов | Ошибок | p50, мс | p95, мс | p99, мс |\n|---|---:|---:|---:|---:|---:|\n")
+		for _, src := range rep.Sources {
+			fmt.Fprintf(&buf, "| %s | %d | %d | %s | %s | %s |\n", src.Name, src.Requests, src.Errors, formatNumber(src.P50), formatNumber(src.P95), formatNumber(src.P99))
+		}
+	}
+
+	if w := rep.Warmup; w != nil {
+		fmt.Fprintf(&buf, "\nПрогрев не входит в отчёт: %d запросов за %s сек, ошибок: %d", w.All, formatNumber(w.Duration), w.Errors)
+		if w.Latency != nil {
+			fmt.Fprintf(&buf, ", p99 %s мс", formatNumber(w.Latency.P99))
+		}
+		buf.WriteString("\n")
+	}
+
+	if r := rep.Retry; r != nil {
+		fmt.Fprintf(&buf, "\nПовторы: с первой попытки %d, после повтора %d, неуспешно после всех попыток %d, повторных запросов %d\n", r.FirstTry, r.AfterRetry, r.Failed, r.Retries)
+	}
+
+	if c := rep.Connections; c != nil {
+		fmt.Fprintf(&buf, "\nСоединения: открыто %d, не удалось открыть %d, разорвано %d, время соединения %s мс, получено сообщений %d (%s в сек)\n",
+			c.Opened, c.Failed, c.Dropped, formatNumber(c.ConnectTime), c.Messages, formatNumber(c.MessageRps))
+		if c.InterEventP99 > 0 {
+			fmt.Fprintf(&buf, "\nМежду событиями: p50 %s мс, p99 %s мс\n", formatNumber(c.InterEventP50), formatNumber(c.InterEventP99))
+		}
+	}
+
+	if s := rep.Socket; s != nil {
+		fmt.Fprintf(&buf, "\nОтправлено: %d запросов, %d байт, получено %d байт (%s байт в сек), потеряно пакетов %d (%s%%)\n",
+			s.Sent, s.BytesSent, s.BytesReceived, formatNumber(s.Throughput), s.Lost, formatNumber(s.LossRate))
+	}
+
+	if th := rep.Throttle; th != nil {
+		fmt.Fprintf(&buf, "\nОграничение скорости: ответов 429/503 %d, пауза исполнителей %s сек, принято без ограничения %s rps\n", th.Events, formatNumber(th.ThrottledTime), formatNumber(th.AllowedRps))
+	}
+
+	if len(rep.Thresholds) > 0 {
+		buf.WriteString("\n| Порог | Значение | Результат |\n|---|---:|:---:|\n")
+		for _, th := range rep.Thresholds {
+			result := "✅"
+			if !th.Passed {
+				result = "❌"
+			}
+			value := formatNumber(th.Value)
+			if th.Message != "" {
+				value = th.Message
+			}
+			fmt.Fprintf(&buf, "| `%s` | %s | %s |\n", th.Name, value, result)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// latencyRows строки перцентилей, suffix добавляется к названию метрики
+func latencyRows(latency, base *LatencyStats, suffix string) []markdownRow {
+	rows := []markdownRow{
+		{name: "p50" + suffix + ", мс", value: latency.P50},
+		{name: "p90" + suffix + ", мс", value: latency.P90},
+		{name: "p95" + suffix + ", мс", value: latency.P95},
+		{name: "p99" + suffix + ", мс", value: latency.P99},
+		{name: "max" + suffix + ", мс", value: latency.Max},
+	}
+	if base != nil {
+		rows[0].baseline = base.P50
+		rows[1].baseline = base.P90
+		rows[2].baseline = base.P95
+		rows[3].baseline = base.P99
+		rows[4].baseline = base.Max
+	}
+
+	return rows
+}
+
+func writeMarkdownTable(buf *bytes.Buffer, title string, rows []markdownRow, withBaseline bool) {
+	if withBaseline {
+		fmt.Fprintf(buf, "| %s | Значение | База | Разница |\n|---|---:|---:|---:|\n", title)
+	} else {
+		fmt.Fprintf(buf, "| %s | Значение |\n|---|---:|\n", title)
+	}
+
+	for _, row := range rows {
+		if withBaseline {
+			fmt.Fprintf(buf, "| %s | %s | %s | %s |\n", row.name, formatNumber(row.value), formatNumber(row.baseline), formatDiff(row.value, row.baseline))
+		} else {
+			fmt.Fprintf(buf, "| %s | %s |\n", row.name, formatNumber(row.value))
+		}
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', -1, 64)
+}
+
+func formatDiff(value, baseline float64) string {
+	if baseline == 0 {
+		if value == 0 {
+			return "0%"
+		}
+		return "—"
+	}
+
+	return fmt.Sprintf("%+.1f%%", (value-baseline)*100/baseline)
+}
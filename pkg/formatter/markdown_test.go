@@ -0,0 +1,196 @@
+package formatter
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMarkdownFormat(t *testing.T) {
+	type testCase struct {
+		name     string
+		rep      Report
+		expected string
+	}
+
+	rep := Report{
+		All: 10, Success: 9, Errors: 1, Rps: 20.5, Host: "http://host",
+		Latency:     &LatencyStats{P50: 10, P90: 20, P95: 30, P99: 40.123, Max: 50},
+		StatusCodes: map[int]int{200: 9, 500: 1},
+		Thresholds:  []ThresholdResult{{Name: "p99<50", Value: 40.123, Passed: true}, {Name: "errors<1", Value: 10}, {Name: "loss<1", Message: "no udp stats"}},
+	}
+
+	withBaseline := rep
+	withBaseline.Baseline = &Report{
+		All: 10, Success: 10, Rps: 41,
+		Latency:     &LatencyStats{P50: 10, P90: 10, P95: 20, P99: 20, Max: 25},
+		StatusCodes: map[int]int{200: 8, 404: 2},
+	}
+
+	corrected := Report{
+		Latency:          &LatencyStats{P50: 1, P90: 1, P95: 2, P99: 2, Max: 100},
+		CorrectedLatency: &LatencyStats{P50: 50, P90: 90, P95: 95, P99: 99, Max: 100},
+	}
+
+	grpcCodes := Report{
+		All: 3, Success: 2, Errors: 1,
+		GrpcCodes: map[string]int{"OK": 2, "NotFound": 1},
+		Baseline:  &Report{All: 3, Success: 3, GrpcCodes: map[string]int{"OK": 3}},
+	}
+
+	operations := Report{
+		All: 3, Success: 2, Errors: 1,
+		Operations: []GroupStats{
+			{Name: "AddUser", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+			{Name: "GetUser", Requests: 2, P50: 10, P95: 12.5, P99: 12.5},
+		},
+		Addresses: []GroupStats{
+			{Name: "10.0.0.1:443", Requests: 2, P50: 10, P95: 10, P99: 10},
+			{Name: "10.0.0.2:443", Requests: 1, Errors: 1, P50: 20, P95: 20, P99: 20},
+		},
+	}
+
+	cases := [...]testCase{
+		{
+			name: "graphql operations and addresses",
+			rep:  operations,
+			expected: `### Результаты нагрузки
+
+| Метрика | Значение |
+|---|---:|
+| Всего запросов | 3 |
+| Успешно | 2 |
+| С ошибкой | 1 |
+| Отменённых | 0 |
+| RPS | 0 |
+
+| Операция GraphQL | Запросов | Ошибок | p50, мс | p95, мс | p99, мс |
+|---|---:|---:|---:|---:|---:|
+| AddUser | 1 | 1 | 20 | 20 | 20 |
+| GetUser | 2 | 0 | 10 | 12.5 | 12.5 |
+
+| Адрес | Запросов | Ошибок | p50, мс | p95, мс | p99, мс |
+|---|---:|---:|---:|---:|---:|
+| 10.0.0.1:443 | 2 | 0 | 10 | 10 | 10 |
+| 10.0.0.2:443 | 1 | 1 | 20 | 20 | 20 |
+`,
+		},
+		{
+			name: "grpc codes with baseline",
+			rep:  grpcCodes,
+			expected: `### Результаты нагрузки
+
+| Метрика | Значение | База | Разница |
+|---|---:|---:|---:|
+| Всего запросов | 3 | 3 | +0.0% |
+| Успешно | 2 | 3 | -33.3% |
+| С ошибкой | 1 | 0 | — |
+| Отменённых | 0 | 0 | 0% |
+| RPS | 0 | 0 | 0% |
+
+| Код gRPC | Значение | База | Разница |
+|---|---:|---:|---:|
+| NotFound | 1 | 0 | — |
+| OK | 2 | 3 | -33.3% |
+`,
+		},
+		{
+			name: "raw and corrected percentiles",
+			rep:  corrected,
+			expected: `### Результаты нагрузки
+
+| Метрика | Значение |
+|---|---:|
+| Всего запросов | 0 |
+| Успешно | 0 |
+| С ошибкой | 0 |
+| Отменённых | 0 |
+| RPS | 0 |
+| p50, мс | 1 |
+| p90, мс | 1 |
+| p95, мс | 2 |
+| p99, мс | 2 |
+| max, мс | 100 |
+| p50 с коррекцией CO, мс | 50 |
+| p90 с коррекцией CO, мс | 90 |
+| p95 с коррекцией CO, мс | 95 |
+| p99 с коррекцией CO, мс | 99 |
+| max с коррекцией CO, мс | 100 |
+`,
+		},
+		{
+			name: "empty report",
+			expected: `### Результаты нагрузки
+
+| Метрика | Значение |
+|---|---:|
+| Всего запросов | 0 |
+| Успешно | 0 |
+| С ошибкой | 0 |
+| Отменённых | 0 |
+| RPS | 0 |
+`,
+		},
+		{
+			name: "without baseline",
+			rep:  rep,
+			expected: "### Результаты нагрузки `http://host`\n" + `
+| Метрика | Значение |
+|---|---:|
+| Всего запросов | 10 |
+| Успешно | 9 |
+| С ошибкой | 1 |
+| Отменённых | 0 |
+| RPS | 20.5 |
+| p50, мс | 10 |
+| p90, мс | 20 |
+| p95, мс | 30 |
+| p99, мс | 40.12 |
+| max, мс | 50 |
+
+| Код ответа | Значение |
+|---|---:|
+| 200 | 9 |
+| 500 | 1 |
+
+| Порог | Значение | Результат |
+|---|---:|:---:|
+` + "| `p99<50` | 40.12 | ✅ |\n| `errors<1` | 10 | ❌ |\n| `loss<1` | no udp stats | ❌ |\n",
+		},
+		{
+			name: "with baseline",
+			rep:  withBaseline,
+			expected: "### Результаты нагрузки `http://host`\n" + `
+| Метрика | Значение | База | Разница |
+|---|---:|---:|---:|
+| Всего запросов | 10 | 10 | +0.0% |
+| Успешно | 9 | 10 | -10.0% |
+| С ошибкой | 1 | 0 | — |
+| Отменённых | 0 | 0 | 0% |
+| RPS | 20.5 | 41 | -50.0% |
+| p50, мс | 10 | 10 | +0.0% |
+| p90, мс | 20 | 10 | +100.0% |
+| p95, мс | 30 | 20 | +50.0% |
+| p99, мс | 40.12 | 20 | +100.6% |
+| max, мс | 50 | 25 | +100.0% |
+
+| Код ответа | Значение | База | Разница |
+|---|---:|---:|---:|
+| 200 | 9 | 8 | +12.5% |
+| 404 | 0 | 2 | -100.0% |
+| 500 | 1 | 0 | — |
+
+| Порог | Значение | Результат |
+|---|---:|:---:|
+` + "| `p99<50` | 40.12 | ✅ |\n| `errors<1` | 10 | ❌ |\n| `loss<1` | no udp stats | ❌ |\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Format(Markdown, tc.rep)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(out))
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package formatter
+
+// Report итоговый отчёт по нагрузке, который отрисовывают форматтеры
+// поля с omitempty заполняются только если по ним есть данные, Duration в секундах
+// CorrectedLatency время ответа с коррекцией coordinated omission, есть только если задан ожидаемый интервал между запросами
+// ProxyConnect время открытия туннелей через прокси, есть только у нагрузки через прокси
+// GrpcCodes количество вызовов gRPC по кодам статуса, у http нагрузки не заполняется
+// Operations статистика по операциям GraphQL в алфавитном порядке, заполняется только у нагрузки GraphQL
+// Addresses статистика по адресам серверов "ip:port", заполняется только если запросы шли больше чем на один адрес
+// Sources статистика по локальным ip соединений, заполняется только если соединения открывались больше чем с одного ip
+type Report struct {
+	Success     int `json:"success" yaml:"success"`
+	Canceled    int `json:"canceled" yaml:"canceled"`
+	Errors      int `json:"errors" yaml:"errors"`
+	All         int `json:"all" yaml:"all"`
+	AvgRespTime int `json:"avgRespTime" yaml:"avgRespTime"`
+
+	Host             string            `json:"host,omitempty" yaml:"host,omitempty"`
+	Duration         float64           `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Rps              float64           `json:"rps,omitempty" yaml:"rps,omitempty"`
+	Thresholds       []ThresholdResult `json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+	Latency          *LatencyStats     `json:"latency,omitempty" yaml:"latency,omitempty"`
+	CorrectedLatency *LatencyStats     `json:"correctedLatency,omitempty" yaml:"correctedLatency,omitempty"`
+	ProxyConnect     *LatencyStats     `json:"proxyConnect,omitempty" yaml:"proxyConnect,omitempty"`
+	StatusCodes      map[int]int       `json:"statusCodes,omitempty" yaml:"statusCodes,omitempty"`
+	GrpcCodes        map[string]int    `json:"grpcCodes,omitempty" yaml:"grpcCodes,omitempty"`
+	Operations       []GroupStats      `json:"operations,omitempty" yaml:"operations,omitempty"`
+	Addresses        []GroupStats      `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	Sources          []GroupStats      `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Timeline         []TimelinePoint   `json:"timeline,omitempty" yaml:"timeline,omitempty"`
+	ErrorSamples     []ErrorSample     `json:"errorSamples,omitempty" yaml:"errorSamples,omitempty"`
+	Adaptive         *AdaptiveStats    `json:"adaptive,omitempty" yaml:"adaptive,omitempty"`
+	Warmup           *WarmupStats      `json:"warmup,omitempty" yaml:"warmup,omitempty"`
+	Retry            *RetryStats       `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Throttle         *ThrottleStats    `json:"throttle,omitempty" yaml:"throttle,omitempty"`
+	Connections      *ConnectionStats  `json:"connections,omitempty" yaml:"connections,omitempty"`
+	Socket           *SocketStats      `json:"socket,omitempty" yaml:"socket,omitempty"`
+
+	// Baseline предыдущий отчёт для сравнения, не сериализуется
+	Baseline *Report `json:"-" yaml:"-"`
+}
+
+// LatencyStats распределение времени ответа, все значения в миллисекундах
+type LatencyStats struct {
+	Min       float64        `json:"min" yaml:"min"`
+	Mean      float64        `json:"mean" yaml:"mean"`
+	P50       float64        `json:"p50" yaml:"p50"`
+	P90       float64        `json:"p90" yaml:"p90"`
+	P95       float64        `json:"p95" yaml:"p95"`
+	P99       float64        `json:"p99" yaml:"p99"`
+	Max       float64        `json:"max" yaml:"max"`
+	Histogram []HistogramBar `json:"histogram,omitempty" yaml:"histogram,omitempty"`
+}
+
+// AdaptiveStats устойчивая нагрузка при целевом p95, TargetP95 в миллисекундах
+type AdaptiveStats struct {
+	TargetP95   float64 `json:"targetP95" yaml:"targetP95"`
+	Concurrency int     `json:"concurrency" yaml:"concurrency"`
+	Rps         float64 `json:"rps" yaml:"rps"`
+}
+
+// WarmupStats статистика прогрева, запросы прогрева не входят в остальные поля отчёта
+// Errors включает отменённые по таймауту запросы, Duration в секундах
+type WarmupStats struct {
+	All      int           `json:"all" yaml:"all"`
+	Errors   int           `json:"errors" yaml:"errors"`
+	Duration float64       `json:"duration" yaml:"duration"`
+	Rps      float64       `json:"rps" yaml:"rps"`
+	Latency  *LatencyStats `json:"latency,omitempty" yaml:"latency,omitempty"`
+}
+
+// RetryStats итог запросов с повторами: успешные с первой попытки, успешные после повтора,
+// неуспешные после всех попыток и количество повторных запросов
+type RetryStats struct {
+	FirstTry   int `json:"firstTry" yaml:"firstTry"`
+	AfterRetry int `json:"afterRetry" yaml:"afterRetry"`
+	Failed     int `json:"failed" yaml:"failed"`
+	Retries    int `json:"retries" yaml:"retries"`
+}
+
+// ThrottleStats ограничение скорости сервером: количество ответов 429 и 503 с Retry-After,
+// суммарная пауза исполнителей по ним в секундах и rps запросов, принятых без ограничения
+type ThrottleStats struct {
+	Events        int     `json:"events" yaml:"events"`
+	ThrottledTime float64 `json:"throttledTime" yaml:"throttledTime"`
+	AllowedRps    float64 `json:"allowedRps" yaml:"allowedRps"`
+}
+
+// ConnectionStats статистика долгоживущих соединений, например WebSocket, ConnectTime в миллисекундах
+// Failed - соединения, которые не удалось открыть, Dropped - разорванные сервером или сетью до конца нагрузки
+// InterEventP50 и InterEventP99 - перцентили времени между событиями потокового ответа в миллисекундах
+type ConnectionStats struct {
+	Opened        int     `json:"opened" yaml:"opened"`
+	Failed        int     `json:"failed" yaml:"failed"`
+	Dropped       int     `json:"dropped" yaml:"dropped"`
+	ConnectTime   float64 `json:"connectTime" yaml:"connectTime"`
+	Messages      int     `json:"messages" yaml:"messages"`
+	MessageRps    float64 `json:"messageRps" yaml:"messageRps"`
+	InterEventP50 float64 `json:"interEventP50,omitempty" yaml:"interEventP50,omitempty"`
+	InterEventP99 float64 `json:"interEventP99,omitempty" yaml:"interEventP99,omitempty"`
+}
+
+// GroupStats запросы одной операции GraphQL, одного адреса сервера или локального ip, перцентили в миллисекундах
+// Errors у операций включает ответы с ошибками GraphQL в errors
+type GroupStats struct {
+	Name     string  `json:"name" yaml:"name"`
+	Requests int     `json:"requests" yaml:"requests"`
+	Errors   int     `json:"errors" yaml:"errors"`
+	P50      float64 `json:"p50" yaml:"p50"`
+	P95      float64 `json:"p95" yaml:"p95"`
+	P99      float64 `json:"p99" yaml:"p99"`
+}
+
+// SocketStats нагрузка по TCP или UDP: отправлено запросов, потеряно пакетов UDP без ответа за таймаут
+// и их доля в процентах, отправлено и получено байт, Throughput - байт в секунду в обе стороны
+type SocketStats struct {
+	Sent          int     `json:"sent" yaml:"sent"`
+	Lost          int     `json:"lost" yaml:"lost"`
+	LossRate      float64 `json:"lossRate" yaml:"lossRate"`
+	BytesSent     int64   `json:"bytesSent" yaml:"bytesSent"`
+	BytesReceived int64   `json:"bytesReceived" yaml:"bytesReceived"`
+	Throughput    float64 `json:"throughput" yaml:"throughput"`
+}
+
+type HistogramBar struct {
+	From  float64 `json:"from" yaml:"from"`
+	To    float64 `json:"to" yaml:"to"`
+	Count uint64  `json:"count" yaml:"count"`
+}
+
+// TimelinePoint статистика за одну секунду нагрузки, перцентили в миллисекундах
+type TimelinePoint struct {
+	Second   int     `json:"second" yaml:"second"`
+	Requests int     `json:"requests" yaml:"requests"`
+	Errors   int     `json:"errors" yaml:"errors"`
+	P50      float64 `json:"p50" yaml:"p50"`
+	P90      float64 `json:"p90" yaml:"p90"`
+	P99      float64 `json:"p99" yaml:"p99"`
+}
+
+type ErrorSample struct {
+	Second int    `json:"second" yaml:"second"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Error  string `json:"error" yaml:"error"`
+}
+
+// ThresholdResult результат проверки порога, Name - порог в исходном виде, например "p99<500"
+// Message - почему порог не пройден, если метрики нет в отчёте, Value при этом не заполняется
+type ThresholdResult struct {
+	Name    string  `json:"name" yaml:"name"`
+	Value   float64 `json:"value" yaml:"value"`
+	Passed  bool    `json:"passed" yaml:"passed"`
+	Message string  `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// FailedThresholds названия непройденных порогов
+func (rep Report) FailedThresholds() []string {
+	var failed []string
+	for _, th := range rep.Thresholds {
+		if !th.Passed {
+			failed = append(failed, th.Name)
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,210 @@
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// subBucketBits задаёт точность гистограммы: 2^7 = 128 подкорзин на порядок,
+// погрешность значения не превышает ~1%
+const (
+	subBucketBits  = 7
+	subBucketCount = 1 << subBucketBits
+	subBucketHalf  = subBucketCount / 2
+)
+
+// Histogram гистограмма времени ответа с логарифмическими корзинами (по мотивам HdrHistogram)
+// значения хранятся в микросекундах, две гистограммы можно объединить через Merge
+// поля экспортированы, чтобы гистограмму можно было сериализовать и передать по сети
+type Histogram struct {
+	Counts []uint64 `json:"counts"`
+	Total  uint64   `json:"total"`
+	Min    int64    `json:"min"`
+	Max    int64    `json:"max"`
+	Sum    int64    `json:"sum"`
+}
+
+// Bucket корзина гистограммы для отображения: значения в диапазоне [From, To)
+type Bucket struct {
+	From  time.Duration
+	To    time.Duration
+	Count uint64
+}
+
+func New() *Histogram {
+	return &Histogram{}
+}
+
+// Record добавляет значение в гистограмму, отрицательные значения считаются нулём
+func (h *Histogram) Record(d time.Duration) {
+	h.recordN(d.Microseconds(), 1)
+}
+
+// RecordCorrected добавляет значение с коррекцией coordinated omission, как recordCorrectedValue в HdrHistogram
+// если значение больше ожидаемого интервала между запросами, добавляются значения запросов,
+// которые должны были быть отправлены за это время: d-expected, d-2*expected и так далее, пока не меньше expected
+// при expected <= 0 работает как Record
+func (h *Histogram) RecordCorrected(d, expected time.Duration) {
+	h.Record(d)
+	if expected <= 0 {
+		return
+	}
+
+	for missing := d - expected; missing >= expected; missing -= expected {
+		h.Record(missing)
+	}
+}
+
+func (h *Histogram) recordN(v int64, n uint64) {
+	if v < 0 {
+		v = 0
+	}
+
+	idx := index(v)
+	if idx >= len(h.Counts) {
+		counts := make([]uint64, idx+1)
+		copy(counts, h.Counts)
+		h.Counts = counts
+	}
+	h.Counts[idx] += n
+
+	if h.Total == 0 || v < h.Min {
+		h.Min = v
+	}
+	if v > h.Max {
+		h.Max = v
+	}
+	h.Total += n
+	h.Sum += v * int64(n)
+}
+
+// Merge добавляет в гистограмму все значения из other
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.Total == 0 {
+		return
+	}
+
+	if len(other.Counts) > len(h.Counts) {
+		counts := make([]uint64, len(other.Counts))
+		copy(counts, h.Counts)
+		h.Counts = counts
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+
+	if h.Total == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Total += other.Total
+	h.Sum += other.Sum
+}
+
+// Quantile возвращает значение, ниже которого лежит доля q (от 0 до 1) записанных значений
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return toDuration(h.Min)
+	}
+	if q >= 1 {
+		return toDuration(h.Max)
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.Total)))
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank {
+			v := upperBound(i)
+			if v > h.Max {
+				v = h.Max
+			}
+			if v < h.Min {
+				v = h.Min
+			}
+			return toDuration(v)
+		}
+	}
+
+	return toDuration(h.Max)
+}
+
+// Mean среднее значение
+func (h *Histogram) Mean() time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+
+	return toDuration(int64(math.Round(float64(h.Sum) / float64(h.Total))))
+}
+
+// Buckets разбивает диапазон [Min, Max] на n равных корзин для построения графика
+func (h *Histogram) Buckets(n int) []Bucket {
+	if h.Total == 0 || n <= 0 {
+		return nil
+	}
+
+	width := (h.Max - h.Min + int64(n)) / int64(n)
+	if width < 1 {
+		width = 1
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		from := h.Min + int64(i)*width
+		buckets[i] = Bucket{From: toDuration(from), To: toDuration(from + width)}
+	}
+
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		pos := int((valueAt(i) - h.Min) / width)
+		if pos < 0 {
+			pos = 0
+		}
+		if pos >= n {
+			pos = n - 1
+		}
+		buckets[pos].Count += c
+	}
+
+	return buckets
+}
+
+func index(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - subBucketBits
+
+	return shift*subBucketHalf + int(v>>uint(shift))
+}
+
+func lowerBound(idx int) int64 {
+	if idx < subBucketCount {
+		return int64(idx)
+	}
+	shift := (idx - subBucketHalf) / subBucketHalf
+
+	return int64(idx-shift*subBucketHalf) << uint(shift)
+}
+
+func upperBound(idx int) int64 {
+	return lowerBound(idx+1) - 1
+}
+
+// valueAt значение, которым представлена корзина - середина её диапазона
+func valueAt(idx int) int64 {
+	return (lowerBound(idx) + upperBound(idx)) / 2
+}
+
+func toDuration(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
@@ -0,0 +1,118 @@
+package histogram
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestIndexBounds(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 129, 255, 256, 1000, 123456, 3600000000} {
+		idx := index(v)
+		require.LessOrEqual(t, lowerBound(idx), v, "value %d", v)
+		require.GreaterOrEqual(t, upperBound(idx), v, "value %d", v)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	type testCase struct {
+		name     string
+		values   []time.Duration
+		quantile float64
+		expected time.Duration
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "empty histogram",
+			quantile: 0.5,
+		},
+		{
+			name:     "single value",
+			values:   []time.Duration{10 * time.Millisecond},
+			quantile: 0.99,
+			expected: 10 * time.Millisecond,
+		},
+		{
+			name:     "median of small values is exact",
+			values:   []time.Duration{10 * time.Microsecond, 20 * time.Microsecond, 30 * time.Microsecond},
+			quantile: 0.5,
+			expected: 20 * time.Microsecond,
+		},
+		{
+			name:     "max",
+			values:   []time.Duration{time.Millisecond, time.Second},
+			quantile: 1,
+			expected: time.Second,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New()
+			for _, v := range tc.values {
+				h.Record(v)
+			}
+
+			require.Equal(t, tc.expected, h.Quantile(tc.quantile))
+		})
+	}
+
+	t.Run("precision within one percent", func(t *testing.T) {
+		h := New()
+		for i := 1; i <= 1000; i++ {
+			h.Record(time.Duration(i) * time.Millisecond)
+		}
+
+		require.InDelta(t, float64(990*time.Millisecond), float64(h.Quantile(0.99)), float64(10*time.Millisecond))
+		require.InDelta(t, float64(500*time.Millisecond), float64(h.Quantile(0.5)), float64(5*time.Millisecond))
+	})
+}
+
+func TestRecordCorrected(t *testing.T) {
+	h := New()
+	h.RecordCorrected(100*time.Microsecond, 30*time.Microsecond)
+
+	require.Equal(t, uint64(3), h.Total, "100, 70 and 40")
+	require.Equal(t, int64(210), h.Sum)
+	require.Equal(t, int64(40), h.Min)
+
+	h = New()
+	h.RecordCorrected(100*time.Microsecond, 0)
+	h.RecordCorrected(20*time.Microsecond, 30*time.Microsecond)
+	require.Equal(t, uint64(2), h.Total, "no correction without interval or for fast responses")
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(), New()
+	a.Record(time.Millisecond)
+	a.Record(2 * time.Millisecond)
+	b.Record(time.Second)
+
+	a.Merge(b)
+	a.Merge(nil)
+
+	require.Equal(t, uint64(3), a.Total)
+	require.Equal(t, time.Millisecond, a.Quantile(0))
+	require.Equal(t, time.Second, a.Quantile(1))
+	require.Equal(t, 334333*time.Microsecond, a.Mean())
+}
+
+func TestBuckets(t *testing.T) {
+	h := New()
+	require.Nil(t, h.Buckets(10))
+
+	for i := 0; i < 10; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	buckets := h.Buckets(5)
+	require.Len(t, buckets, 5)
+
+	var total uint64
+	for _, b := range buckets {
+		require.Equal(t, uint64(2), b.Count)
+		total += b.Count
+	}
+	require.Equal(t, h.Total, total)
+}
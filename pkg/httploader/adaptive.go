@@ -0,0 +1,278 @@
+package httploader
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultAdaptiveLimit верхняя граница одновременных запросов, если c не задано
+	defaultAdaptiveLimit = 1000
+	// minAdaptiveWindow минимальное количество запросов, по которым считается p95 окна
+	minAdaptiveWindow = 20
+	// adaptiveDecrease во сколько раз уменьшается количество одновременных запросов при превышении цели
+	adaptiveDecrease = 0.9
+)
+
+// AdaptiveReport результат нагрузки с подстройкой количества одновременных запросов под целевой p95
+// Concurrency и Rps посчитаны по окнам после slow start, в которых p95 не превысил Target,
+// окна slow start с заведомо малым ограничением занижали бы устойчивую нагрузку
+// если таких окон не было, Concurrency и Rps равны 0
+type AdaptiveReport struct {
+	Target      time.Duration
+	Concurrency int
+	Rps         float64
+}
+
+// WithLatencyTarget включает подстройку количества одновременных запросов так, чтобы p95 держался на target
+// до первого превышения цели количество одновременных запросов удваивается после каждого окна (slow start, как в TCP),
+// затем растёт на 1, пока p95 окна не больше target, и уменьшается в 0.9 раза, если больше (AIMD)
+// аргумент c в New становится верхней границей количества одновременных запросов
+func WithLatencyTarget(target time.Duration) Option {
+	return func(l *consistent) {
+		l.latencyTarget = target
+	}
+}
+
+// adaptive concurrency Loader, у которого requestsPerTime - верхняя граница количества одновременных запросов
+type adaptive struct {
+	concurrency
+}
+
+// Load отсылает запросы к host, подстраивая количество одновременных запросов под целевой p95
+// при прерывании контекстом перестаёт слать запросы и дожидается выполнения уже запущенных
+func (l *adaptive) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
+	req, mix, err := l.newRequest(host, headers, body)
+	if err != nil {
+		return Report{}, err
+	}
+
+	ctrl := newAdaptiveController(l.latencyTarget, l.requestsPerTime, l.requests, time.Now())
+	wg := sync.WaitGroup{}
+	bp := l.newBackpressure(time.Now())
+	responseList := make([]*concurrencyResp, l.requests)
+	for i := 0; i < l.requests; i++ {
+		select {
+		case <-ctx.Done():
+			goto wait
+		default:
+		}
+
+		worker := ctrl.acquire()
+		wg.Add(1)
+		go func(index, worker int) {
+			var (
+				s     Sample
+				start time.Time
+			)
+			defer func() {
+				// как в concurrency, think time и pacing ждёт только исполнитель, которому обещан следующий запрос
+				if ctrl.reserve() {
+					l.pause(ctx, start, s, bp)
+					ctrl.release(worker)
+				} else {
+					bp.hold(ctx, s, 0)
+					ctrl.retire()
+				}
+				wg.Done()
+			}()
+
+			s, start = l.send(ctx, req, mix, worker, bp)
+			ctrl.observe(s)
+
+			reqResult := concurrencyResp{sample: s}
+			switch s.ErrorClass() {
+			case ErrClassNone:
+				reqResult.success = true
+				reqResult.respTime = s.Latency
+			case ErrClassTimeout:
+				reqResult.cancelled = true
+			default:
+				reqResult.error = true
+			}
+			responseList[index] = &reqResult
+		}(i, worker)
+	}
+
+wait:
+	wg.Wait()
+
+	rep := l.calcReport(responseList, bp)
+	rep.Adaptive = ctrl.report()
+
+	return rep, nil
+}
+
+// adaptiveController ограничивает количество одновременных запросов и меняет ограничение по окнам запросов
+type adaptiveController struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	target   time.Duration
+	maxLimit int
+	limit    int
+	inFlight int
+	// запросы, ещё не обещанные исполнителям
+	left int
+	// свободные номера исполнителей, каждому из них обещан запрос, новые номера выдаются по порядку
+	free       []int
+	nextWorker int
+
+	windowStart time.Time
+	latencies   []time.Duration
+	failed      bool
+	slowStart   bool
+
+	// окна после slow start, в которых p95 не превысил цель
+	goodRequests int
+	goodTime     time.Duration
+	goodLimits   int
+	goodWindows  int
+}
+
+func newAdaptiveController(target time.Duration, maxLimit, requests int, start time.Time) *adaptiveController {
+	c := &adaptiveController{target: target, maxLimit: maxLimit, left: requests, limit: 1, windowStart: start, slowStart: true}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// acquire ждёт, пока количество выполняющихся запросов станет меньше ограничения, и возвращает номер исполнителя
+// если все оставшиеся запросы обещаны исполнителям, ждёт одного из них
+func (c *adaptiveController) acquire() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.inFlight >= c.limit || (len(c.free) == 0 && c.left <= 0) {
+		c.cond.Wait()
+	}
+	c.inFlight++
+
+	if n := len(c.free); n > 0 {
+		worker := c.free[n-1]
+		c.free = c.free[:n-1]
+		return worker
+	}
+	c.left--
+	c.nextWorker++
+
+	return c.nextWorker - 1
+}
+
+// reserve обещает исполнителю следующий запрос, если остались необещанные
+func (c *adaptiveController) reserve() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.left <= 0 {
+		return false
+	}
+	c.left--
+
+	return true
+}
+
+// retire освобождает место исполнителя, которому не обещано больше запросов
+func (c *adaptiveController) retire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	c.cond.Signal()
+}
+
+func (c *adaptiveController) release(worker int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+	c.free = append(c.free, worker)
+	c.cond.Signal()
+}
+
+// observe учитывает время ответа в текущем окне и меняет ограничение, когда окно заполнено
+func (c *adaptiveController) observe(s Sample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// ошибка считается превышением цели, иначе быстрые отказы сервера увеличивали бы нагрузку
+	if s.ErrorClass() != ErrClassNone {
+		c.failed = true
+	}
+	c.latencies = append(c.latencies, s.Latency)
+
+	window := c.limit
+	if window < minAdaptiveWindow {
+		window = minAdaptiveWindow
+	}
+	if len(c.latencies) >= window {
+		c.adjust(time.Now())
+		// ограничение могло вырасти, ждущих запросов может стать несколько
+		c.cond.Broadcast()
+	}
+}
+
+// adjust закрывает окно и меняет ограничение, вызывается под мьютексом
+func (c *adaptiveController) adjust(now time.Time) {
+	p95 := percentile(c.latencies, 0.95)
+	if !c.failed && p95 <= c.target {
+		if !c.slowStart {
+			c.goodRequests += len(c.latencies)
+			c.goodTime += now.Sub(c.windowStart)
+			c.goodLimits += c.limit
+			c.goodWindows++
+		}
+
+		if c.slowStart {
+			c.limit *= 2
+		} else {
+			c.limit++
+		}
+		// верхняя граница тоже заканчивает slow start, дальше ограничение не растёт
+		if c.limit >= c.maxLimit {
+			c.limit = c.maxLimit
+			c.slowStart = false
+		}
+	} else {
+		c.slowStart = false
+		c.limit = int(float64(c.limit) * adaptiveDecrease)
+		if c.limit < 1 {
+			c.limit = 1
+		}
+	}
+
+	c.windowStart = now
+	c.latencies = c.latencies[:0]
+	c.failed = false
+}
+
+func (c *adaptiveController) report() *AdaptiveReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rep := &AdaptiveReport{Target: c.target}
+	if c.goodWindows > 0 {
+		rep.Concurrency = int(math.Round(float64(c.goodLimits) / float64(c.goodWindows)))
+	}
+	if c.goodTime > 0 {
+		rep.Rps = math.Round(float64(c.goodRequests)/c.goodTime.Seconds()*100) / 100
+	}
+
+	return rep
+}
+
+func percentile(values []time.Duration, q float64) time.Duration {
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx]
+}
@@ -0,0 +1,111 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLoader(t *testing.T) {
+	t.Run("concurrency settles at latency target", func(t *testing.T) {
+		var inFlight int64
+		// время ответа растёт с количеством одновременных запросов, цель достижима примерно при 5
+		serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			n := atomic.AddInt64(&inFlight, 1)
+			defer atomic.AddInt64(&inFlight, -1)
+			time.Sleep(time.Duration(n) * 4 * time.Millisecond)
+		}))
+		defer serv.Close()
+
+		loader := New(time.Second, http.MethodGet, 600, 50, WithLatencyTarget(20*time.Millisecond))
+
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, 600, rep.All)
+		require.Equal(t, 600, rep.Success)
+		require.NotNil(t, rep.Adaptive)
+		require.Equal(t, 20*time.Millisecond, rep.Adaptive.Target)
+		require.GreaterOrEqual(t, rep.Adaptive.Concurrency, 1)
+		require.LessOrEqual(t, rep.Adaptive.Concurrency, 8)
+		require.Greater(t, rep.Adaptive.Rps, 0.0)
+	})
+
+	t.Run("cancel context", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			time.Sleep(10 * time.Millisecond)
+		}))
+		defer serv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		loader := New(time.Second, http.MethodGet, 100000, 10, WithLatencyTarget(time.Second))
+
+		rep, err := loader.Load(ctx, serv.URL, nil, nil)
+
+		require.NoError(t, err)
+		require.Less(t, rep.All, 100000)
+		require.Equal(t, rep.All, rep.Success)
+	})
+}
+
+func TestAdaptiveController(t *testing.T) {
+	ok := func(latency time.Duration) Sample {
+		return Sample{Status: http.StatusOK, Latency: latency}
+	}
+	window := func(c *adaptiveController, s Sample) {
+		for i := 0; i < minAdaptiveWindow; i++ {
+			worker := c.acquire()
+			c.observe(s)
+			c.release(worker)
+		}
+	}
+
+	c := newAdaptiveController(10*time.Millisecond, 20, 1000, time.Now())
+
+	window(c, ok(time.Millisecond))
+	require.Equal(t, 2, c.limit, "slow start doubles limit")
+	window(c, ok(time.Millisecond))
+	require.Equal(t, 4, c.limit)
+
+	window(c, ok(20*time.Millisecond))
+	require.Equal(t, 3, c.limit, "limit decreases when target is exceeded")
+
+	window(c, ok(time.Millisecond))
+	require.Equal(t, 4, c.limit, "additive increase after first decrease")
+
+	window(c, Sample{Status: http.StatusInternalServerError, Latency: time.Millisecond})
+	require.Equal(t, 3, c.limit, "errors are treated as exceeded target")
+
+	rep := c.report()
+	require.Equal(t, 10*time.Millisecond, rep.Target)
+	require.Equal(t, 3, rep.Concurrency, "only windows after slow start are counted")
+
+	for i := 0; i < 30; i++ {
+		window(c, ok(time.Millisecond))
+	}
+	require.Equal(t, 20, c.limit, "limit is capped")
+
+	// цель не превышена ни разу, slow start заканчивается на верхней границе
+	c = newAdaptiveController(10*time.Millisecond, 4, 1000, time.Now())
+	window(c, ok(time.Millisecond))
+	window(c, ok(time.Millisecond))
+	require.Equal(t, 0, c.report().Concurrency, "slow start windows are not counted")
+	window(c, ok(time.Millisecond))
+	window(c, ok(time.Millisecond))
+	require.Equal(t, 4, c.report().Concurrency)
+	require.Greater(t, c.report().Rps, 0.0)
+}
+
+func TestPercentile(t *testing.T) {
+	values := []time.Duration{5, 1, 4, 2, 3}
+
+	require.Equal(t, time.Duration(5), percentile(values, 0.95))
+	require.Equal(t, time.Duration(3), percentile(values, 0.5))
+	require.Equal(t, time.Duration(1), percentile(values, 0))
+	require.Equal(t, []time.Duration{5, 1, 4, 2, 3}, values, "values are not sorted in place")
+}
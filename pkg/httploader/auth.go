@@ -0,0 +1,73 @@
+package httploader
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Authenticator авторизует или подписывает запрос перед каждой отправкой, в том числе перед повтором
+// body - тело запроса, заголовки req принадлежат только этому запросу и их можно менять
+// реализация должна быть безопасна для нескольких горутин
+type Authenticator interface {
+	Authenticate(ctx context.Context, req *http.Request, body []byte) error
+}
+
+// Refresher Authenticator, которому нужно знать об ответах 401, например чтобы сбросить отозванный токен
+type Refresher interface {
+	Unauthorized(req *http.Request)
+}
+
+// WithAuth запросы http и потоков авторизуются a, время авторизации не входит во время ответа,
+// при ошибке авторизации запрос не отправляется и считается ошибкой ErrClassAuth
+func WithAuth(a Authenticator) Option {
+	return func(l *consistent) {
+		l.auth = a
+	}
+}
+
+// authError ошибка авторизации запроса
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return "auth: " + e.err.Error()
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// authorize авторизует req через WithAuth, заголовки копируются, так как запрос может быть общим для исполнителей
+func (l *consistent) authorize(req *http.Request) error {
+	var body []byte
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return &authError{err: err}
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return &authError{err: err}
+		}
+	}
+
+	req.Header = req.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if err := l.auth.Authenticate(req.Context(), req, body); err != nil {
+		return &authError{err: err}
+	}
+
+	return nil
+}
+
+// unauthorized сообщает Refresher об ответе 401
+func (l *consistent) unauthorized(req *http.Request, status int) {
+	if r, ok := l.auth.(Refresher); ok && status == http.StatusUnauthorized {
+		r.Unauthorized(req)
+	}
+}
@@ -0,0 +1,113 @@
+package httploader
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingAuth подписывает запрос номером вызова и запоминает тела, err - ошибка каждого вызова
+type countingAuth struct {
+	mu           sync.Mutex
+	calls        int
+	bodies       []string
+	unauthorized int
+	err          error
+}
+
+func (a *countingAuth) Authenticate(_ context.Context, req *http.Request, body []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.err != nil {
+		return a.err
+	}
+	a.calls++
+	a.bodies = append(a.bodies, string(body))
+	req.Header.Set("Authorization", "Token "+strconv.Itoa(a.calls))
+	return nil
+}
+
+func (a *countingAuth) Unauthorized(*http.Request) {
+	a.mu.Lock()
+	a.unauthorized++
+	a.mu.Unlock()
+}
+
+func TestWithAuth(t *testing.T) {
+	t.Run("every attempt is authorized", func(t *testing.T) {
+		// первый запрос с каждым токеном получает 503
+		var (
+			mu   sync.Mutex
+			seen = map[string]bool{}
+		)
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			token := r.Header.Get("Authorization")
+			if token == "" || seen[token] {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			seen[token] = true
+			if len(seen)%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer serv.Close()
+
+		a := &countingAuth{}
+		headers := http.Header{"X-Trace": {"1"}}
+		rep, err := New(time.Second, http.MethodPost, 3, 1, WithAuth(a), WithRetry(RetryPolicy{
+			MaxAttempts: 2,
+			Statuses:    []int{http.StatusServiceUnavailable},
+		})).Load(context.Background(), serv.URL, &headers, []byte(`{"a":1}`))
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Success)
+		require.Equal(t, 6, a.calls)
+		require.Equal(t, []string{`{"a":1}`, `{"a":1}`, `{"a":1}`, `{"a":1}`, `{"a":1}`, `{"a":1}`}, a.bodies)
+		require.Equal(t, http.Header{"X-Trace": {"1"}}, headers, "shared headers are not changed")
+	})
+
+	t.Run("auth error", func(t *testing.T) {
+		var received int64
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt64(&received, 1)
+		}))
+		defer serv.Close()
+
+		var sample Sample
+		rep, err := New(time.Second, http.MethodGet, 1, 1, WithAuth(&countingAuth{err: errors.New("token endpoint is down")}), WithObserver(func(s Sample) {
+			sample = s
+		})).Load(context.Background(), serv.URL, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, rep.Success)
+		require.Equal(t, int64(0), received, "request is not sent")
+		require.Equal(t, ErrClassAuth, sample.ErrorClass())
+		require.True(t, strings.HasPrefix(sample.Err.Error(), "auth: "))
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "Token 1" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}))
+		defer serv.Close()
+
+		a := &countingAuth{}
+		rep, err := New(time.Second, http.MethodGet, 2, 1, WithAuth(a)).Load(context.Background(), serv.URL, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, rep.Success)
+		require.Equal(t, 1, a.unauthorized)
+	})
+}
@@ -0,0 +1,120 @@
+package httploader
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultThrottlePause пауза исполнителя после 429 без заголовка Retry-After
+const defaultThrottlePause = time.Second
+
+// ThrottleReport ограничение скорости сервером при нагрузке с WithBackpressure
+// Events - количество ответов 429 и 503 с Retry-After, ThrottledTime - суммарная пауза всех исполнителей по этим ответам
+// AllowedRate - сколько запросов в секунду сервер принял без ограничения
+type ThrottleReport struct {
+	Events        int
+	ThrottledTime time.Duration
+	AllowedRate   float64
+}
+
+// WithBackpressure включает реакцию на ограничение скорости сервером:
+// после 429 или 503 с заголовком Retry-After исполнитель ждёт время из заголовка перед следующим запросом,
+// после 429 без заголовка - секунду
+func WithBackpressure() Option {
+	return func(l *consistent) {
+		l.backpressure = true
+	}
+}
+
+// backpressure учёт ограничения скорости за одну нагрузку, методы безопасны для нескольких горутин
+// у nil backpressure методы ничего не делают
+type backpressure struct {
+	mu sync.Mutex
+
+	start     time.Time
+	attempts  int
+	events    int
+	throttled time.Duration
+}
+
+// newBackpressure учёт ограничения скорости с момента start, если включён WithBackpressure, иначе nil
+func (l *consistent) newBackpressure(start time.Time) *backpressure {
+	if !l.backpressure {
+		return nil
+	}
+
+	return &backpressure{start: start}
+}
+
+// observe учитывает попытку запроса s
+func (b *backpressure) observe(s Sample) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.attempts++
+	if throttledBy(s) {
+		b.events++
+	}
+}
+
+// delay пауза, которую просит сервер после ответа s, 0 если ответ не ограничивает скорость
+func (b *backpressure) delay(s Sample) time.Duration {
+	if b == nil || !throttledBy(s) {
+		return 0
+	}
+	if s.RetryAfter > 0 {
+		return s.RetryAfter
+	}
+
+	return defaultThrottlePause
+}
+
+// hold ждёт d, но не меньше, чем просит сервер после ответа s, или до отмены контекста
+// время, которое исполнитель ждал по требованию сервера, учитывается в ThrottledTime
+func (b *backpressure) hold(ctx context.Context, s Sample, d time.Duration) {
+	throttle := b.delay(s)
+	if throttle > d {
+		d = throttle
+	}
+
+	slept := sleep(ctx, d)
+	if b == nil || throttle == 0 {
+		return
+	}
+	if slept > throttle {
+		slept = throttle
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.throttled += slept
+}
+
+func (b *backpressure) report(now time.Time) *ThrottleReport {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rep := &ThrottleReport{Events: b.events, ThrottledTime: b.throttled}
+	if elapsed := now.Sub(b.start); elapsed > 0 {
+		rep.AllowedRate = math.Round(float64(b.attempts-b.events)/elapsed.Seconds()*100) / 100
+	}
+
+	return rep
+}
+
+// throttledBy ответ s - сигнал снизить нагрузку: 429 или 503 с заголовком Retry-After
+func throttledBy(s Sample) bool {
+	return s.Status == http.StatusTooManyRequests || s.Status == http.StatusServiceUnavailable && s.RetryAfter > 0
+}
@@ -0,0 +1,79 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBackpressureDelay(t *testing.T) {
+	bp := &backpressure{}
+
+	require.Equal(t, time.Duration(0), bp.delay(Sample{Status: http.StatusOK}))
+	require.Equal(t, defaultThrottlePause, bp.delay(Sample{Status: http.StatusTooManyRequests}))
+	require.Equal(t, 2*time.Second, bp.delay(Sample{Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}))
+	require.Equal(t, time.Duration(0), bp.delay(Sample{Status: http.StatusServiceUnavailable}), "503 without Retry-After is an error, not throttling")
+	require.Equal(t, time.Second, bp.delay(Sample{Status: http.StatusServiceUnavailable, RetryAfter: time.Second}))
+
+	var disabled *backpressure
+	require.Equal(t, time.Duration(0), disabled.delay(Sample{Status: http.StatusTooManyRequests}))
+	require.Nil(t, disabled.report(time.Now()))
+}
+
+func TestBackpressureReport(t *testing.T) {
+	start := time.Now()
+	bp := &backpressure{start: start}
+	throttledSample := Sample{Status: http.StatusTooManyRequests, RetryAfter: 20 * time.Millisecond}
+
+	for i := 0; i < 9; i++ {
+		bp.observe(Sample{Status: http.StatusOK})
+	}
+	bp.observe(throttledSample)
+
+	bp.hold(context.Background(), throttledSample, time.Millisecond)
+	bp.hold(context.Background(), Sample{Status: http.StatusOK}, 10*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond, "the longest of the pauses")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bp.hold(ctx, throttledSample, 0)
+
+	rep := bp.report(start.Add(3 * time.Second))
+	require.InDelta(t, 20*time.Millisecond, rep.ThrottledTime, float64(time.Millisecond), "cancelled pause is almost zero")
+	rep.ThrottledTime = 0
+	require.Equal(t, &ThrottleReport{Events: 1, AllowedRate: 3}, rep)
+}
+
+func TestWithBackpressure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("server asks to wait for a second")
+	}
+
+	var received int64
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt64(&received, 1) == 1 {
+			writer.Header().Set("Retry-After", "1")
+			writer.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer serv.Close()
+
+	loader := New(time.Second, http.MethodGet, 3, 0, WithBackpressure())
+
+	start := time.Now()
+	rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, elapsed, time.Second)
+	require.Equal(t, 3, rep.All)
+	require.Equal(t, 1, rep.Errors)
+	require.NotNil(t, rep.Throttle)
+	require.Equal(t, 1, rep.Throttle.Events)
+	require.Equal(t, time.Second, rep.Throttle.ThrottledTime)
+	require.InDelta(t, 2/elapsed.Seconds(), rep.Throttle.AllowedRate, 0.1)
+}
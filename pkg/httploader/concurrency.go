@@ -1,11 +1,8 @@
 package httploader
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"net/http"
-	"net/url"
 	"sync"
 	"time"
 )
@@ -18,22 +15,27 @@ type concurrency struct {
 type concurrencyResp struct {
 	cancelled, success, error bool
 	respTime                  time.Duration
+	sample                    Sample
 }
 
 // Load отсылает параллельные запросы к host
 // при прерывании контекстом перестаёт слать запросы и дождидается выполнения всех, уже запущенных запросов
 // поддерживает graceful shutdown
 func (l *concurrency) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
-	req, err := http.NewRequest(l.method, host, bytes.NewBuffer(body))
+	req, mix, err := l.newRequest(host, headers, body)
 	if err != nil {
-		return Report{}, fmt.Errorf("create request: %w", err)
+		return Report{}, err
 	}
 
-	if headers != nil {
-		req.Header = *headers
+	// свободные номера исполнителей, канал ограничивает количество одновременных запросов
+	workers := make(chan int, l.requestsPerTime)
+	for w := 0; w < l.requestsPerTime; w++ {
+		workers <- w
 	}
-	throttle := make(chan struct{}, l.requestsPerTime)
+	// запросы, ещё не обещанные исполнителям, первые requestsPerTime обещаны исполнителям в канале
+	left := int64(l.requests - l.requestsPerTime)
 	wg := sync.WaitGroup{}
+	bp := l.newBackpressure(time.Now())
 
 	responseList := make([]*concurrencyResp, l.requests)
 	for i := 0; i < l.requests; i++ {
@@ -44,52 +46,49 @@ func (l *concurrency) Load(ctx context.Context, host string, headers *http.Heade
 		default:
 		}
 
-		throttle <- struct{}{}
+		worker := <-workers
 		wg.Add(1)
-		go func(index int) {
+		go func(index, worker int) {
+			var (
+				s     Sample
+				start time.Time
+			)
 			defer func() {
+				// исполнитель освобождается после паузы, чтобы следующий его запрос учитывал think time и pacing,
+				// после последнего запроса исполнителя ждать нужно только backpressure, Load дожидается пауз,
+				// чтобы время ожидания backpressure попало в отчёт
+				if reserve(&left) {
+					l.pause(ctx, start, s, bp)
+					workers <- worker
+				} else {
+					bp.hold(ctx, s, 0)
+				}
 				wg.Done()
-				<-throttle
 			}()
 
-			cli := http.Client{Timeout: l.timeout}
-			now := time.Now()
-
-			reqResult := concurrencyResp{}
-			resp, err := cli.Do(req)
-			if err != nil {
-				if urlErr, ok := err.(*url.Error); ok {
-					if urlErr.Timeout() {
-						reqResult.cancelled = true
-					} else {
-						reqResult.error = true
-					}
-				} else {
-					reqResult.error = true
-				}
-				responseList[index] = &reqResult
-				return
-			}
-			resp.Body.Close()
+			s, start = l.send(ctx, req, mix, worker, bp)
 
-			if resp.StatusCode != http.StatusOK {
+			reqResult := concurrencyResp{sample: s}
+			switch s.ErrorClass() {
+			case ErrClassNone:
+				reqResult.success = true
+				reqResult.respTime = s.Latency
+			case ErrClassTimeout:
+				reqResult.cancelled = true
+			default:
 				reqResult.error = true
 			}
-
-			reqResult.success = true
-			reqResult.respTime = time.Since(now)
 			responseList[index] = &reqResult
-		}(i)
+		}(i, worker)
 	}
 
 wait:
 	wg.Wait()
-	close(throttle)
 
-	return l.calcReport(responseList), nil
+	return l.calcReport(responseList, bp), nil
 }
 
-func (l *concurrency) calcReport(respList []*concurrencyResp) Report {
+func (l *concurrency) calcReport(respList []*concurrencyResp, bp *backpressure) Report {
 	var (
 		all       int
 		success   int
@@ -98,10 +97,12 @@ func (l *concurrency) calcReport(respList []*concurrencyResp) Report {
 
 		avgRespTime float64
 	)
+	retry := l.newRetryReport()
 
 	for _, res := range respList {
 		if res != nil {
 			all++
+			retry.add(res.sample)
 			if res.cancelled {
 				cancelled++
 				continue
@@ -126,5 +127,7 @@ func (l *concurrency) calcReport(respList []*concurrencyResp) Report {
 		All:       all,
 
 		AvgResponseTime: calcResponseTime(success, avgRespTime),
+		Retry:           retry,
+		Throttle:        bp.report(time.Now()),
 	}
 }
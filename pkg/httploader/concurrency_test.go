@@ -34,6 +34,25 @@ func TestConcurrencyLoader(t *testing.T) {
 		require.Equal(t, expectedRep, rep)
 	})
 
+	t.Run("observer receives each request", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+		serv.Close()
+
+		var observed int64
+		obs := func(s Sample) {
+			if s.Err != nil && s.Status == 0 {
+				atomic.AddInt64(&observed, 1)
+			}
+		}
+		loader := concurrency{consistent: consistent{timeout: time.Second, requests: 10, method: http.MethodGet, observers: []Observer{obs}}, requestsPerTime: 5}
+
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+
+		require.Equal(t, nil, err)
+		require.Equal(t, Report{All: 10, Errors: 10}, rep)
+		require.Equal(t, int64(10), observed)
+	})
+
 	t.Run("all requests are cancelled", func(t *testing.T) {
 		timeOut := 1
 
@@ -88,6 +107,28 @@ func TestConcurrencyLoader(t *testing.T) {
 		require.Equal(t, expectedRep, rep)
 	})
 
+	t.Run("last backpressure pause is in report", func(t *testing.T) {
+		if testing.Short() {
+			t.Skip("server asks to wait for a second")
+		}
+
+		serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer serv.Close()
+
+		loader := concurrency{consistent: consistent{timeout: time.Second, requests: 2, method: http.MethodGet, backpressure: true}, requestsPerTime: 2}
+
+		start := time.Now()
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), defaultThrottlePause)
+		require.NotNil(t, rep.Throttle)
+		require.Equal(t, 2, rep.Throttle.Events)
+		require.Equal(t, 2*defaultThrottlePause, rep.Throttle.ThrottledTime)
+	})
+
 	t.Run("cancel context", func(t *testing.T) {
 		if testing.Short() {
 			t.Skipf("test execute over 4 seconds")
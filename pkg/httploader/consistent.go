@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"math"
 	"net/http"
-	"net/url"
+	"sync"
 	"time"
 )
 
@@ -14,18 +14,29 @@ type consistent struct {
 	timeout  time.Duration
 	method   string
 	requests int
+
+	observers     []Observer
+	latencyTarget time.Duration
+	thinkTime     Delay
+	pacing        time.Duration
+	retry         *RetryPolicy
+	backpressure  bool
+	idField       string
+	grpc          grpcOptions
+
+	streamDuration time.Duration
+	framing        Framing
+	graphql        bool
+	dialer         *dialer
+	auth           Authenticator
 }
 
 // Load посылает последовательный запрос к host
 // перед отправкой каждого запроса проверяет контекст, что делает метод способным поддерживать graceful shutdown
 func (l *consistent) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
-	req, err := http.NewRequest(l.method, host, bytes.NewBuffer(body))
+	req, mix, err := l.newRequest(host, headers, body)
 	if err != nil {
-		return Report{}, fmt.Errorf("create request: %w", err)
-	}
-
-	if headers != nil {
-		req.Header = *headers
+		return Report{}, err
 	}
 
 	var (
@@ -36,44 +47,66 @@ func (l *consistent) Load(ctx context.Context, host string, headers *http.Header
 
 		responseTime float64
 	)
+	retry, bp := l.newRetryReport(), l.newBackpressure(time.Now())
 
+	var (
+		last      Sample
+		lastStart time.Time
+	)
 	for all < l.requests {
+		if all > 0 {
+			l.pause(ctx, lastStart, last, bp)
+		}
+
 		select {
 		case <-ctx.Done():
-			return l.formReport(success, canceled, errors, all, responseTime), nil
+			return l.formReport(success, canceled, errors, all, responseTime, retry, bp), nil
 		default:
 		}
 		all++
-		cli := http.Client{Timeout: l.timeout}
-
-		now := time.Now()
-		resp, err := cli.Do(req)
-		if err != nil {
-			if urlErr, ok := err.(*url.Error); ok {
-				if urlErr.Timeout() {
-					canceled++
-				} else {
-					errors++
-				}
-			} else {
-				errors++
-			}
-			continue
-		}
 
-		if resp.StatusCode != http.StatusOK {
+		s, start := l.send(ctx, req, mix, 0, bp)
+		retry.add(s)
+		last, lastStart = s, start
+
+		switch s.ErrorClass() {
+		case ErrClassNone:
+			responseTime += s.Latency.Seconds()
+			success++
+		case ErrClassTimeout:
+			canceled++
+		default:
 			errors++
-			continue
 		}
-		responseTime += time.Since(now).Seconds()
+	}
 
-		success++
+	return l.formReport(success, canceled, errors, all, responseTime, retry, bp), nil
+}
+
+// newRequest запрос, который отправляют исполнители, и смесь операций из body, если включён WithGraphQL
+func (l *consistent) newRequest(host string, headers *http.Header, body []byte) (*http.Request, *graphQLMix, error) {
+	var mix *graphQLMix
+	if l.graphql {
+		var err error
+		if mix, err = newGraphQLMix(body); err != nil {
+			return nil, nil, fmt.Errorf("parse graphql operations: %w", err)
+		}
+		body = nil
+	}
+
+	req, err := http.NewRequest(l.method, host, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+
+	if headers != nil {
+		req.Header = *headers
 	}
 
-	return l.formReport(success, canceled, errors, all, responseTime), nil
+	return req, mix, nil
 }
 
-func (l *consistent) formReport(success, canceled, errors, all int, avgRespTime float64) Report {
+func (l *consistent) formReport(success, canceled, errors, all int, avgRespTime float64, retry *RetryReport, bp *backpressure) Report {
 	respTime := calcResponseTime(success, avgRespTime)
 	return Report{
 		Success:         success,
@@ -81,9 +114,88 @@ func (l *consistent) formReport(success, canceled, errors, all int, avgRespTime
 		Errors:          errors,
 		All:             all,
 		AvgResponseTime: respTime,
+		Retry:           retry,
+		Throttle:        bp.report(time.Now()),
+	}
+}
+
+func (l *consistent) observe(s Sample) {
+	for _, obs := range l.observers {
+		obs(s)
 	}
 }
 
 func calcResponseTime(success int, avgRespTime float64) time.Duration {
 	return time.Duration(math.Round(avgRespTime/float64(success))) * time.Second
 }
+
+// dispatch раздаёт requests запросов workers исполнителям, исполнитель выполняет запрос номер i через do
+// и делает паузу WithThinkTime и WithPacing перед каждым следующим, после прерывания ctx новые запросы не начинаются
+func (l *consistent) dispatch(ctx context.Context, workers int, do func(worker, i int) Sample, t *tally) {
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			var last Sample
+			for i := range jobs {
+				if !last.Start.IsZero() {
+					l.pause(ctx, last.Start, last, nil)
+				}
+				last = do(worker, i)
+				l.observe(last)
+				t.add(last)
+			}
+		}(w)
+	}
+
+loop:
+	for i := 0; i < l.requests && ctx.Err() == nil; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// tally подсчёт результатов запросов для Report, безопасен для нескольких горутин
+type tally struct {
+	mu sync.Mutex
+
+	success, canceled, errors, all int
+	responseTime                   float64
+}
+
+func (t *tally) add(s Sample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.all++
+	switch s.ErrorClass() {
+	case ErrClassNone:
+		t.success++
+		t.responseTime += s.Latency.Seconds()
+	case ErrClassTimeout:
+		t.canceled++
+	default:
+		t.errors++
+	}
+}
+
+func (t *tally) report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Report{
+		Success:         t.success,
+		Cancelled:       t.canceled,
+		Errors:          t.errors,
+		All:             t.all,
+		AvgResponseTime: calcResponseTime(t.success, t.responseTime),
+	}
+}
@@ -33,6 +33,35 @@ func TestLoadConsistent(t *testing.T) {
 		require.Equal(t, expectedRep, rep)
 	})
 
+	t.Run("observer receives each request", func(t *testing.T) {
+		okHandler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusAccepted)
+			writer.Write([]byte("accepted"))
+		})
+
+		serv := httptest.NewServer(okHandler)
+		defer serv.Close()
+
+		var samples []Sample
+		obs := func(s Sample) {
+			samples = append(samples, s)
+		}
+		loader := consistent{requests: 3, method: http.MethodGet, observers: []Observer{obs}}
+
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+
+		require.Equal(t, nil, err)
+		require.Equal(t, Report{All: 3, Errors: 3}, rep)
+		require.Len(t, samples, 3)
+		for _, s := range samples {
+			require.Equal(t, http.StatusAccepted, s.Status)
+			require.Equal(t, int64(len("accepted")), s.Bytes)
+			require.Equal(t, ErrClassStatus, s.ErrorClass())
+			require.NoError(t, s.Err)
+			require.False(t, s.Start.IsZero())
+		}
+	})
+
 	t.Run("50 percents is errors", func(t *testing.T) {
 		count := 0
 
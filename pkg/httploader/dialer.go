@@ -0,0 +1,293 @@
+package httploader
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WithResolve соединения с hostPort ("host:port") открываются на addrs вместо адресов из DNS, как curl --resolve
+// заголовок Host и SNI остаются от хоста запроса, hostPort без порта игнорируется
+func WithResolve(hostPort string, addrs ...string) Option {
+	return func(l *consistent) {
+		_, port, err := net.SplitHostPort(hostPort)
+		if err != nil {
+			return
+		}
+
+		d := l.customDialer()
+		for _, addr := range addrs {
+			d.overrides[hostPort] = append(d.overrides[hostPort], net.JoinHostPort(addr, port))
+		}
+	}
+}
+
+// WithDNSServer имена разрешаются через DNS сервер server ("host:port") вместо системного
+func WithDNSServer(server string) Option {
+	return func(l *consistent) {
+		var nd net.Dialer
+		l.customDialer().resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return nd.DialContext(ctx, network, server)
+			},
+		}
+	}
+}
+
+// WithDialContext соединения открываются функцией dial вместо net.Dialer, например на unix сокет
+// адрес для dial учитывает WithResolve, имя хоста разрешается до dial только с WithDNSServer или WithDNSCache
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(l *consistent) {
+		l.customDialer().dial = dial
+	}
+}
+
+// WithSourceIPs соединения открываются с локальных адресов ips, каждое следующее - со следующего по кругу,
+// так нагрузка не упирается в эфемерные порты одного адреса, не действует вместе с WithDialContext
+func WithSourceIPs(ips ...net.IP) Option {
+	return func(l *consistent) {
+		d := l.customDialer()
+		d.sources = append(d.sources, ips...)
+	}
+}
+
+// WithDNSCache имя каждого хоста разрешается один раз за нагрузку, без опции - при каждом новом соединении
+func WithDNSCache() Option {
+	return func(l *consistent) {
+		l.customDialer().cache = true
+	}
+}
+
+// dialer открывает соединения по адресам из WithResolve, WithDNSServer и WithDNSCache функцией из WithDialContext
+// или через прокси из WithProxy и WithEnvProxy,
+// каждое новое соединение с хостом открывается на следующий по кругу адрес хоста, при ошибке пробуются остальные
+// безопасен для нескольких горутин
+type dialer struct {
+	net.Dialer
+	dial  func(ctx context.Context, network, addr string) (net.Conn, error)
+	proxy func(ctx context.Context, addr string) (*url.URL, error)
+	// httpProxy прокси транспорта http, если его выбирает сам транспорт, а не dialer
+	httpProxy func(req *http.Request) (*url.URL, error)
+	sources   []net.IP
+	resolver  *net.Resolver
+	overrides map[string][]string
+	cache     bool
+
+	mu         sync.Mutex
+	cached     map[string][]string
+	next       map[string]int
+	nextSource int
+
+	once sync.Once
+	http *http.Transport
+}
+
+func (l *consistent) customDialer() *dialer {
+	if l.dialer == nil {
+		l.dialer = &dialer{
+			overrides: make(map[string][]string),
+			cached:    make(map[string][]string),
+			next:      make(map[string]int),
+		}
+	}
+
+	return l.dialer
+}
+
+// dial открывает соединение через dialer, если он настроен опциями, иначе как net.Dialer
+func (l *consistent) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if l.dialer != nil {
+		return l.dialer.DialContext(ctx, network, addr)
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// httpClient клиент для запросов http, соединения одного Loader переиспользуются между запросами
+func (l *consistent) httpClient(timeout time.Duration) *http.Client {
+	cli := &http.Client{Timeout: timeout}
+	if l.dialer != nil {
+		cli.Transport = l.dialer.transport()
+	}
+
+	return cli
+}
+
+// transport транспорт http, открывающий соединения через dialer, создаётся один раз
+func (d *dialer) transport() *http.Transport {
+	d.once.Do(func() {
+		d.http = http.DefaultTransport.(*http.Transport).Clone()
+		d.http.DialContext = d.DialContext
+		if d.proxy != nil && d.httpProxy == nil {
+			// прокси из WithProxy выбирает dialer
+			d.http.Proxy = nil
+			return
+		}
+
+		httpProxy := d.httpProxy
+		if httpProxy == nil {
+			// без опций прокси запросы http идут через прокси из окружения, как у http.DefaultTransport
+			httpProxy = envProxy()
+		}
+		if d.remaps() {
+			// транспорт передал бы прокси имя хоста, и адреса из WithResolve, WithDNSServer и WithDNSCache не применились бы,
+			// поэтому dialer сам открывает туннель до адреса хоста через выбранный прокси, в том числе для http
+			d.http.Proxy = nil
+			d.http.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				proxyURL, err := httpProxy(&http.Request{URL: &url.URL{Scheme: proxyScheme(ctx), Host: addr}})
+				if err != nil {
+					return nil, err
+				}
+				return d.dialVia(ctx, network, addr, proxyURL)
+			}
+			return
+		}
+
+		// транспорт сам ходит через прокси, в том числе без CONNECT для http, dialer соединяется напрямую
+		d.http.Proxy = httpProxy
+		d.http.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return d.dialVia(ctx, network, addr, nil)
+		}
+	})
+
+	return d.http
+}
+
+// remaps адреса хостов выбирает dialer, а не DNS прокси или системы
+func (d *dialer) remaps() bool {
+	return len(d.overrides) > 0 || d.resolver != nil || d.cache
+}
+
+// DialContext открывает соединение с addr ("host:port") на следующий по кругу адрес хоста
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyURL, err := d.proxyFor(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.dialVia(ctx, network, addr, proxyURL)
+}
+
+// dialVia открывает соединение с addr через прокси proxyURL, nil - напрямую
+func (d *dialer) dialVia(ctx context.Context, network, addr string, proxyURL *url.URL) (net.Conn, error) {
+	addrs, err := d.lookup(ctx, addr, proxyURL != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	first := d.next[addr] % len(addrs)
+	d.next[addr] = first + 1
+	d.mu.Unlock()
+
+	for i := range addrs {
+		var conn net.Conn
+		conn, err = d.connect(ctx, network, addrs[(first+i)%len(addrs)], proxyURL)
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+	}
+
+	return nil, err
+}
+
+// proxyFor прокси для соединения с addr, nil - соединение открывается напрямую
+// через прокси идут только соединения TCP
+func (d *dialer) proxyFor(ctx context.Context, network, addr string) (*url.URL, error) {
+	if d.proxy == nil || (network != "tcp" && network != "tcp4" && network != "tcp6") {
+		return nil, nil
+	}
+
+	return d.proxy(ctx, addr)
+}
+
+func (d *dialer) connect(ctx context.Context, network, addr string, proxyURL *url.URL) (net.Conn, error) {
+	if proxyURL != nil {
+		return d.viaProxy(ctx, proxyURL, addr)
+	}
+
+	return d.direct(ctx, network, addr)
+}
+
+func (d *dialer) direct(ctx context.Context, network, addr string) (net.Conn, error) {
+	if d.dial != nil {
+		return d.dial(ctx, network, addr)
+	}
+
+	nd := d.Dialer
+	nd.LocalAddr = d.source(network)
+	return nd.DialContext(ctx, network, addr)
+}
+
+// source следующий по кругу локальный адрес из WithSourceIPs, nil - адрес выбирает система
+func (d *dialer) source(network string) net.Addr {
+	if len(d.sources) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	ip := d.sources[d.nextSource%len(d.sources)]
+	d.nextSource++
+	d.mu.Unlock()
+
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return &net.TCPAddr{IP: ip}
+	case "udp", "udp4", "udp6":
+		return &net.UDPAddr{IP: ip}
+	default:
+		return nil
+	}
+}
+
+// lookup адреса "ip:port", на которые можно открыть соединение с addr
+// proxied - соединение идёт через прокси, который сам разрешает имя, если не задан свой DNS сервер или кэш
+func (d *dialer) lookup(ctx context.Context, addr string, proxied bool) ([]string, error) {
+	if addrs, ok := d.overrides[addr]; ok {
+		return addrs, nil
+	}
+	// прокси и функция из WithDialContext сами решают, куда соединяться по имени
+	if (proxied || d.dial != nil) && d.resolver == nil && !d.cache {
+		return []string{addr}, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return []string{addr}, nil
+	}
+
+	if d.cache {
+		d.mu.Lock()
+		addrs, ok := d.cached[addr]
+		d.mu.Unlock()
+		if ok {
+			return addrs, nil
+		}
+	}
+
+	resolver := d.resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.JoinHostPort(ip, port))
+	}
+
+	if d.cache {
+		d.mu.Lock()
+		d.cached[addr] = addrs
+		d.mu.Unlock()
+	}
+
+	return addrs, nil
+}
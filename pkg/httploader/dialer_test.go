@@ -0,0 +1,207 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dnsTestServer DNS сервер, отвечающий на любой A запрос адресом ip, queries считает запросы
+func dnsTestServer(t *testing.T, ip [4]byte) (addr string, queries *int64, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	queries = new(int64)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var msg dnsmessage.Message
+			if msg.Unpack(buf[:n]) != nil || len(msg.Questions) == 0 {
+				continue
+			}
+			q := msg.Questions[0]
+			msg.Header.Response = true
+			msg.Header.Authoritative = true
+			if q.Type == dnsmessage.TypeA {
+				atomic.AddInt64(queries, 1)
+				msg.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: ip},
+				}}
+			}
+			resp, err := msg.Pack()
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(resp, from)
+		}
+	}()
+
+	return conn.LocalAddr().String(), queries, func() { conn.Close() }
+}
+
+// hostTestServer http сервер на ip:port, отвечающий заголовком Host запроса
+func hostTestServer(t *testing.T, addr string) (stop func()) {
+	lis, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+
+	serv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	serv.Listener = lis
+	serv.Start()
+
+	return serv.Close
+}
+
+func TestDialer(t *testing.T) {
+	first, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := strconv.Itoa(first.Addr().(*net.TCPAddr).Port)
+	first.Close()
+
+	for _, ip := range []string{"127.0.0.1", "127.0.0.2"} {
+		defer hostTestServer(t, net.JoinHostPort(ip, port))()
+	}
+	host := "http://backend.test:" + port
+
+	collect := func() (Option, func() map[string]int) {
+		var mu sync.Mutex
+		byAddr := make(map[string]int)
+		return WithObserver(func(s Sample) {
+				mu.Lock()
+				byAddr[s.Addr]++
+				mu.Unlock()
+			}), func() map[string]int {
+				mu.Lock()
+				defer mu.Unlock()
+				return byAddr
+			}
+	}
+
+	t.Run("resolve overrides are used round-robin per connection", func(t *testing.T) {
+		observer, byAddr := collect()
+		loader := New(time.Second, http.MethodGet, 8, 4, observer, WithResolve("backend.test:"+port, "127.0.0.1", "127.0.0.2"))
+
+		rep, err := loader.Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 8, rep.Success)
+
+		addrs := byAddr()
+		require.Len(t, addrs, 2)
+		require.Equal(t, 8, addrs["127.0.0.1:"+port]+addrs["127.0.0.2:"+port])
+	})
+
+	t.Run("unreachable override falls back to next address", func(t *testing.T) {
+		observer, byAddr := collect()
+		loader := New(time.Second, http.MethodGet, 2, 1, observer, WithResolve("backend.test:"+port, "127.0.0.3", "127.0.0.2"))
+
+		rep, err := loader.Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 2, rep.Success)
+		require.Equal(t, map[string]int{"127.0.0.2:" + port: 2}, byAddr())
+	})
+
+	t.Run("custom dns server with cache", func(t *testing.T) {
+		dns, queries, stop := dnsTestServer(t, [4]byte{127, 0, 0, 2})
+		defer stop()
+
+		observer, byAddr := collect()
+		loader := New(time.Second, http.MethodGet, 6, 3, observer, WithDNSServer(dns), WithDNSCache())
+
+		rep, err := loader.Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 6, rep.Success)
+		require.Equal(t, map[string]int{"127.0.0.2:" + port: 6}, byAddr())
+		require.LessOrEqual(t, atomic.LoadInt64(queries), int64(3), "concurrent connections may resolve before the first answer is cached")
+	})
+
+	t.Run("dns error", func(t *testing.T) {
+		dns, _, stop := dnsTestServer(t, [4]byte{127, 0, 0, 2})
+		stop()
+
+		var class string
+		loader := New(100*time.Millisecond, http.MethodGet, 1, 1, WithDNSServer(dns), WithObserver(func(s Sample) {
+			class = s.ErrorClass()
+		}))
+
+		rep, err := loader.Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, rep.All-rep.Success)
+		require.Contains(t, []string{ErrClassDNS, ErrClassTimeout}, class)
+	})
+
+	t.Run("custom dial context to unix socket", func(t *testing.T) {
+		sock := filepath.Join(t.TempDir(), "app.sock")
+		lis, err := net.Listen("unix", sock)
+		require.NoError(t, err)
+		serv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host))
+		}))
+		serv.Listener = lis
+		serv.Start()
+		defer serv.Close()
+
+		var d net.Dialer
+		observer, byAddr := collect()
+		loader := New(time.Second, http.MethodGet, 3, 2, observer, WithDialContext(func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", sock)
+		}))
+
+		rep, err := loader.Load(context.Background(), "http://sidecar/health", nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Success)
+		require.Equal(t, map[string]int{sock: 3}, byAddr(), "name is not resolved before custom dial")
+	})
+
+	t.Run("source ips round-robin per connection", func(t *testing.T) {
+		var (
+			mu      sync.Mutex
+			remotes = make(map[string]bool)
+		)
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			mu.Lock()
+			remotes[host] = true
+			mu.Unlock()
+		}))
+		defer serv.Close()
+
+		var sources sync.Map
+		loader := New(time.Second, http.MethodGet, 8, 4, WithSourceIPs(net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")), WithObserver(func(s Sample) {
+			sources.Store(s.Source, true)
+		}))
+
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 8, rep.Success)
+		require.Equal(t, map[string]bool{"127.0.0.2": true, "127.0.0.3": true}, remotes)
+		for _, ip := range []string{"127.0.0.2", "127.0.0.3"} {
+			_, ok := sources.Load(ip)
+			require.True(t, ok, ip)
+		}
+	})
+
+	t.Run("websocket and tcp use the same dialer", func(t *testing.T) {
+		dl := &dialer{overrides: map[string][]string{"backend.test:" + port: {"127.0.0.2:" + port}}, next: map[string]int{}}
+		l := consistent{dialer: dl}
+		conn, err := l.dial(context.Background(), "tcp", "backend.test:"+port)
+		require.NoError(t, err)
+		require.Equal(t, "127.0.0.2:"+port, conn.RemoteAddr().String())
+		conn.Close()
+	})
+}
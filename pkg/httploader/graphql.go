@@ -0,0 +1,183 @@
+package httploader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// graphQLOperationName имя операции из её объявления в запросе
+var graphQLOperationName = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// WithGraphQL тело запроса - операция GraphQL или смесь операций в json:
+// одна операция в формате тела запроса GraphQL ({"query": ..., "variables": ..., "operationName": ...}), см. GraphQLBody,
+// или массив таких операций с полем "weight" - относительной частотой операции, по умолчанию 1
+// операции отправляются POST запросами, имя операции попадает в Sample.Operation,
+// ответ со статусом 200 и непустым массивом errors считается ошибкой ErrClassGraphQL
+func WithGraphQL() Option {
+	return func(l *consistent) {
+		l.graphql = true
+	}
+}
+
+// graphQLOperation операция GraphQL из тела запроса WithGraphQL
+type graphQLOperation struct {
+	Name      string          `json:"operationName,omitempty"`
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+	Weight    int             `json:"weight,omitempty"`
+}
+
+// GraphQLBody тело запроса GraphQL из текста операции query и переменных variables - объекта json, пустые variables опускаются
+func GraphQLBody(query, variables []byte) ([]byte, error) {
+	op := graphQLOperation{Query: string(query)}
+	if trimmed := bytes.TrimSpace(variables); len(trimmed) > 0 {
+		var value interface{}
+		if err := json.Unmarshal(trimmed, &value); err != nil {
+			return nil, fmt.Errorf("invalid variables: %w", err)
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return nil, errors.New("variables must be a json object")
+		}
+		op.Variables = trimmed
+	}
+
+	return json.Marshal(op)
+}
+
+// graphQLError ответ GraphQL со статусом 200 и ошибками в поле errors
+type graphQLError struct {
+	messages []string
+}
+
+func (e *graphQLError) Error() string {
+	return "graphql errors: " + strings.Join(e.messages, "; ")
+}
+
+// graphQLMix выбирает операции пропорционально весам, равномерно перемешанными (smooth weighted round-robin),
+// безопасен для нескольких горутин
+type graphQLMix struct {
+	names   []string
+	bodies  [][]byte
+	weights []int
+	total   int
+
+	mu      sync.Mutex
+	current []int
+}
+
+// newGraphQLMix разбирает операции из body, см. WithGraphQL
+func newGraphQLMix(body []byte) (*graphQLMix, error) {
+	var ops []graphQLOperation
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &ops); err != nil {
+			return nil, err
+		}
+	} else {
+		var op graphQLOperation
+		if err := json.Unmarshal(trimmed, &op); err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+	if len(ops) == 0 {
+		return nil, errors.New("no operations")
+	}
+
+	mix := &graphQLMix{current: make([]int, len(ops))}
+	for i, op := range ops {
+		if strings.TrimSpace(op.Query) == "" {
+			return nil, fmt.Errorf("operation %d has empty query", i+1)
+		}
+		if op.Weight < 0 {
+			return nil, fmt.Errorf("operation %d has negative weight %d", i+1, op.Weight)
+		}
+		if op.Weight == 0 {
+			op.Weight = 1
+		}
+		mix.weights = append(mix.weights, op.Weight)
+		mix.total += op.Weight
+
+		name := op.Name
+		if m := graphQLOperationName.FindStringSubmatch(op.Query); name == "" && m != nil {
+			name = m[1]
+		}
+		if name == "" {
+			name = "operation " + strconv.Itoa(i+1)
+		}
+		mix.names = append(mix.names, name)
+
+		op.Weight = 0
+		body, err := json.Marshal(op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: %w", name, err)
+		}
+		mix.bodies = append(mix.bodies, body)
+	}
+
+	return mix, nil
+}
+
+// pick номер следующей операции: каждая операция выбирается пропорционально весу,
+// выборы одной операции распределены равномерно, память не зависит от весов
+func (m *graphQLMix) pick() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	best := 0
+	for i, w := range m.weights {
+		m.current[i] += w
+		if m.current[i] > m.current[best] {
+			best = i
+		}
+	}
+	m.current[best] -= m.total
+
+	return best
+}
+
+// request следующая операция смеси: копия req с её телом и её имя
+func (m *graphQLMix) request(req *http.Request) (*http.Request, string) {
+	i := m.pick()
+	body := m.bodies[i]
+
+	r := req.Clone(req.Context())
+	r.Method = http.MethodPost
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	return r, m.names[i]
+}
+
+// checkGraphQL ошибка, если в ответе GraphQL есть непустой массив errors или ответ не json
+func checkGraphQL(body []byte) error {
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return &graphQLError{messages: []string{"invalid json response"}}
+	}
+	if len(resp.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		messages = append(messages, e.Message)
+	}
+
+	return &graphQLError{messages: messages}
+}
@@ -0,0 +1,190 @@
+package httploader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// graphQLTestHandler отвечает на операцию Fail ошибкой в errors, на Broken - не json, на остальные - данными
+func graphQLTestHandler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "1", r.Header.Get("X-Request-Id"))
+
+		var req struct {
+			Query         string                 `json:"query"`
+			Variables     map[string]interface{} `json:"variables"`
+			OperationName string                 `json:"operationName"`
+			Weight        *int                   `json:"weight"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Nil(t, req.Weight)
+
+		switch {
+		case req.Variables["id"] == "fail":
+			fmt.Fprint(w, `{"data": null, "errors": [{"message": "user not found"}, {"message": "access denied"}]}`)
+		case req.Variables["id"] == "broken":
+			fmt.Fprint(w, `<html>gateway error</html>`)
+		default:
+			fmt.Fprint(w, `{"data": {"user": {"id": "1"}}, "errors": []}`)
+		}
+	}
+}
+
+func TestGraphQLLoader(t *testing.T) {
+	type testCase struct {
+		name       string
+		body       string
+		n, c       int
+		expected   Report
+		operations map[string]int
+		errors     []string
+		loadError  string
+	}
+
+	cases := [...]testCase{
+		{
+			name:       "single operation in graphql request format",
+			body:       `{"query": "query GetUser($id: ID!) { user(id: $id) { id } }", "variables": {"id": "1"}}`,
+			n:          3,
+			expected:   Report{All: 3, Success: 3},
+			operations: map[string]int{"GetUser": 3},
+		},
+		{
+			name: "weighted operation mix",
+			body: `[
+				{"query": "query GetUser { user(id: 1) { id } }", "weight": 3},
+				{"query": "{ users { id } }", "operationName": "ListUsers"},
+				{"query": "mutation { touch }", "weight": 2}
+			]`,
+			n:          12,
+			c:          3,
+			expected:   Report{All: 12, Success: 12},
+			operations: map[string]int{"GetUser": 6, "ListUsers": 2, "operation 3": 4},
+		},
+		{
+			name: "errors in successful responses are failures",
+			body: `[
+				{"query": "query Fail($id: ID!) { user(id: $id) { id } }", "variables": {"id": "fail"}},
+				{"query": "query Broken($id: ID!) { user(id: $id) { id } }", "variables": {"id": "broken"}}
+			]`,
+			n:          2,
+			expected:   Report{All: 2, Errors: 2},
+			operations: map[string]int{"Fail": 1, "Broken": 1},
+			errors:     []string{"graphql errors: user not found; access denied", "graphql errors: invalid json response"},
+		},
+		{
+			name:      "invalid operations",
+			body:      `[{"query": "{ users { id } }", "weight": -1}]`,
+			n:         1,
+			loadError: "parse graphql operations: operation 1 has negative weight -1",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := httptest.NewServer(graphQLTestHandler(t))
+			defer serv.Close()
+
+			var (
+				mu       sync.Mutex
+				observed []Sample
+			)
+			loader := New(time.Second, http.MethodGet, tc.n, tc.c, WithGraphQL(), WithObserver(func(s Sample) {
+				mu.Lock()
+				observed = append(observed, s)
+				mu.Unlock()
+			}))
+
+			rep, err := loader.Load(context.Background(), serv.URL, &http.Header{"X-Request-Id": []string{"1"}}, []byte(tc.body))
+			if tc.loadError != "" {
+				require.EqualError(t, err, tc.loadError)
+				return
+			}
+			require.NoError(t, err)
+
+			rep.AvgResponseTime = 0
+			require.Equal(t, tc.expected, rep)
+
+			operations := make(map[string]int)
+			var errs []string
+			for _, s := range observed {
+				operations[s.Operation]++
+				require.Equal(t, http.StatusOK, s.Status)
+				if s.Err != nil {
+					require.Equal(t, ErrClassGraphQL, s.ErrorClass())
+					errs = append(errs, s.Err.Error())
+				}
+			}
+			require.Equal(t, tc.operations, operations)
+			require.ElementsMatch(t, tc.errors, errs)
+		})
+	}
+}
+
+func TestNewGraphQLMix(t *testing.T) {
+	for body, expected := range map[string]string{
+		``:                          "unexpected end of JSON input",
+		`[]`:                        "no operations",
+		`{"variables": {"id": 1}}`:  "operation 1 has empty query",
+		`[{"query": "{ a }"}, {}]`:  "operation 2 has empty query",
+		`{"query": "{ a }", "x": 1`: "unexpected end of JSON input",
+	} {
+		_, err := newGraphQLMix([]byte(body))
+		require.EqualError(t, err, expected, body)
+	}
+
+	mix, err := newGraphQLMix([]byte(`{"query": "subscription OnEvent { event }", "operationName": "Events", "variables": {"a": 1}}`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"Events"}, mix.names)
+	require.JSONEq(t, `{"query": "subscription OnEvent { event }", "operationName": "Events", "variables": {"a": 1}}`, string(mix.bodies[0]))
+}
+
+func TestGraphQLMixPick(t *testing.T) {
+	order := func(weights []int, n int) []int {
+		m := &graphQLMix{weights: weights, current: make([]int, len(weights))}
+		for _, w := range weights {
+			m.total += w
+		}
+		picked := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			picked = append(picked, m.pick())
+		}
+		return picked
+	}
+
+	require.Equal(t, []int{0, 1, 0, 2, 1, 0, 0, 1, 0, 2, 1, 0}, order([]int{30, 20, 10}, 12))
+	require.Equal(t, []int{0, 0}, order([]int{5}, 2))
+	require.Equal(t, []int{0, 1, 0, 1}, order([]int{1, 1}, 4))
+
+	// огромные веса не требуют памяти под весь порядок операций
+	counts := make([]int, 2)
+	for _, i := range order([]int{100000, 99999}, 1000) {
+		counts[i]++
+	}
+	require.Equal(t, []int{500, 500}, counts)
+}
+
+func TestGraphQLBody(t *testing.T) {
+	body, err := GraphQLBody([]byte("query GetUser($id: ID!) { user(id: $id) { name } }\n"), []byte(`{"id": "42"}`))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query": "query GetUser($id: ID!) { user(id: $id) { name } }\n", "variables": {"id": "42"}}`, string(body))
+
+	body, err = GraphQLBody([]byte("{ users { id } }"), nil)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"query": "{ users { id } }"}`, string(body))
+
+	_, err = GraphQLBody([]byte("{ users { id } }"), []byte(`[1]`))
+	require.EqualError(t, err, "variables must be a json object")
+
+	_, err = GraphQLBody([]byte("{ users { id } }"), []byte(`{"id":`))
+	require.Error(t, err)
+}
@@ -0,0 +1,328 @@
+package httploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/jhump/protoreflect/dynamic/grpcdynamic"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MethodGRPC значение Sample.Method у вызовов gRPC
+const MethodGRPC = "GRPC"
+
+// GRPCIDPlaceholder в json шаблоне сообщения gRPC заменяется на id сообщения
+const GRPCIDPlaceholder = "{{id}}"
+
+// grpcOptions настройки gRPC Loader
+type grpcOptions struct {
+	protoFiles     []string
+	importPaths    []string
+	tls            *tls.Config
+	streamMessages int
+}
+
+// WithProtoFiles описание методов gRPC берётся из .proto файлов files, а не через server reflection
+// importPaths - директории, в которых ищутся files и их импорты
+func WithProtoFiles(importPaths []string, files ...string) Option {
+	return func(l *consistent) {
+		l.grpc.importPaths = importPaths
+		l.grpc.protoFiles = files
+	}
+}
+
+// WithGRPCTLS соединение gRPC устанавливается по TLS с настройками cfg, без опции - без шифрования
+func WithGRPCTLS(cfg *tls.Config) Option {
+	return func(l *consistent) {
+		l.grpc.tls = cfg
+	}
+}
+
+// WithStreamMessages количество сообщений, которое отправляется в каждом вызове с потоком от клиента, по умолчанию 1
+func WithStreamMessages(n int) Option {
+	return func(l *consistent) {
+		l.grpc.streamMessages = n
+	}
+}
+
+// grpcLoader Loader, вызывающий метод gRPC из workers исполнителей по одному соединению
+type grpcLoader struct {
+	consistent
+	call    string
+	workers int
+}
+
+// NewGRPC Loader, который выполняет requests вызовов метода call ("package.Service/Method") из c исполнителей
+// тело запроса - json шаблон сообщения, GRPCIDPlaceholder в нём заменяется на id сообщения "<номер вызова>-<номер сообщения>",
+// заголовки передаются как metadata
+// поддерживаются унарные методы и методы с потоками, вызов со своими потоками считается одним запросом:
+// в поток от клиента отправляется WithStreamMessages сообщений, поток от сервера читается до конца
+// timeOut ограничивает каждый вызов целиком
+func NewGRPC(timeOut time.Duration, call string, requests, c int, opts ...Option) Loader {
+	l := consistent{
+		method:   MethodGRPC,
+		requests: requests,
+		timeout:  timeOut,
+		grpc:     grpcOptions{streamMessages: 1},
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	if c < 1 {
+		c = 1
+	}
+
+	return &grpcLoader{consistent: l, call: call, workers: c}
+}
+
+// Load соединяется с host ("host:port"), получает описание метода и выполняет вызовы
+// при прерывании контекстом перестаёт начинать новые вызовы и дожидается уже начатых
+func (l *grpcLoader) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
+	dialOpts := []grpc.DialOption{grpc.WithTransportCredentials(l.credentials())}
+	if l.dialer != nil {
+		scheme := "http"
+		if l.grpc.tls != nil {
+			scheme = "https"
+		}
+		dialOpts = append(dialOpts, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return l.dialer.DialContext(withProxyScheme(ctx, scheme), "tcp", addr)
+		}))
+	}
+	conn, err := grpc.DialContext(ctx, host, dialOpts...)
+	if err != nil {
+		return Report{}, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	md, err := l.resolve(ctx, conn)
+	if err != nil {
+		return Report{}, fmt.Errorf("resolve %s: %w", l.call, err)
+	}
+	// шаблон проверяется до нагрузки, чтобы ошибка в нём не превратилась в requests неуспешных вызовов
+	if _, err = grpcMessages(md.GetInputType(), body, 0, 1); err != nil {
+		return Report{}, fmt.Errorf("parse message template: %w", err)
+	}
+
+	// начатые вызовы не прерываются вместе с ctx, их ограничивает только таймаут
+	callCtx := context.Background()
+	if headers != nil {
+		callCtx = metadata.NewOutgoingContext(callCtx, grpcMetadata(*headers))
+	}
+
+	stub := grpcdynamic.NewStub(conn)
+	st := &tally{}
+	l.dispatch(ctx, l.workers, func(worker, i int) Sample {
+		return l.invoke(callCtx, stub, md, body, worker, i, host)
+	}, st)
+
+	return st.report(), nil
+}
+
+func (l *grpcLoader) credentials() credentials.TransportCredentials {
+	if l.grpc.tls == nil {
+		return insecure.NewCredentials()
+	}
+
+	return credentials.NewTLS(l.grpc.tls)
+}
+
+// resolve описание метода из .proto файлов или через server reflection
+func (l *grpcLoader) resolve(ctx context.Context, conn *grpc.ClientConn) (*desc.MethodDescriptor, error) {
+	service, method, err := splitGRPCCall(l.call)
+	if err != nil {
+		return nil, err
+	}
+
+	var sd *desc.ServiceDescriptor
+	if len(l.grpc.protoFiles) > 0 {
+		parser := protoparse.Parser{ImportPaths: l.grpc.importPaths}
+		fds, err := parser.ParseFiles(l.grpc.protoFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("parse proto files: %w", err)
+		}
+		for _, fd := range fds {
+			if sd = fd.FindService(service); sd != nil {
+				break
+			}
+		}
+		if sd == nil {
+			return nil, fmt.Errorf("service %s not found in proto files", service)
+		}
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+
+		client := grpcreflect.NewClient(ctx, rpb.NewServerReflectionClient(conn))
+		defer client.Reset()
+		if sd, err = client.ResolveService(service); err != nil {
+			return nil, fmt.Errorf("server reflection: %w", err)
+		}
+	}
+
+	md := sd.FindMethodByName(method)
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", method, service)
+	}
+
+	return md, nil
+}
+
+// splitGRPCCall разделяет "package.Service/Method" или "package.Service.Method" на сервис и метод
+func splitGRPCCall(call string) (service, method string, err error) {
+	call = strings.TrimPrefix(call, "/")
+	i := strings.LastIndexAny(call, "/.")
+	if i <= 0 || i == len(call)-1 {
+		return "", "", fmt.Errorf("invalid call %q, expected package.Service/Method", call)
+	}
+
+	return call[:i], call[i+1:], nil
+}
+
+// invoke выполняет вызов номер i и возвращает его результат, сообщения собираются до начала замера
+func (l *grpcLoader) invoke(ctx context.Context, stub grpcdynamic.Stub, md *desc.MethodDescriptor, template []byte, worker, i int, host string) Sample {
+	count := 1
+	if md.IsClientStreaming() {
+		count = l.grpc.streamMessages
+	}
+	msgs, err := grpcMessages(md.GetInputType(), template, i, count)
+
+	s := Sample{Start: time.Now(), Worker: worker, URL: host + "/" + md.GetFullyQualifiedName(), Method: l.method}
+	if err == nil {
+		ctx, cancel := context.WithTimeout(ctx, l.timeout)
+		s.Bytes, err = callGRPC(ctx, stub, md, msgs)
+		cancel()
+	}
+	s.Latency = time.Since(s.Start)
+	s.Err = err
+	s.Code = status.Code(err).String()
+
+	return s
+}
+
+// callGRPC выполняет вызов подходящего вида и возвращает суммарный размер ответов
+func callGRPC(ctx context.Context, stub grpcdynamic.Stub, md *desc.MethodDescriptor, msgs []proto.Message) (int64, error) {
+	switch {
+	case md.IsClientStreaming() && md.IsServerStreaming():
+		stream, err := stub.InvokeRpcBidiStream(ctx, md)
+		if err != nil {
+			return 0, err
+		}
+		// отправка идёт параллельно с чтением, чтобы сервер мог отвечать на каждое сообщение
+		go func() {
+			for _, msg := range msgs {
+				if stream.SendMsg(msg) != nil {
+					return
+				}
+			}
+			stream.CloseSend()
+		}()
+		return receiveAll(stream.RecvMsg)
+	case md.IsClientStreaming():
+		stream, err := stub.InvokeRpcClientStream(ctx, md)
+		if err != nil {
+			return 0, err
+		}
+		for _, msg := range msgs {
+			// io.EOF означает, что сервер уже завершил вызов, его статус вернёт CloseAndReceive
+			if err = stream.SendMsg(msg); err == io.EOF {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		resp, err := stream.CloseAndReceive()
+		if err != nil {
+			return 0, err
+		}
+		return int64(proto.Size(resp)), nil
+	case md.IsServerStreaming():
+		stream, err := stub.InvokeRpcServerStream(ctx, md, msgs[0])
+		if err != nil {
+			return 0, err
+		}
+		return receiveAll(stream.RecvMsg)
+	default:
+		resp, err := stub.InvokeRpc(ctx, md, msgs[0])
+		if err != nil {
+			return 0, err
+		}
+		return int64(proto.Size(resp)), nil
+	}
+}
+
+// receiveAll читает поток ответов до конца
+func receiveAll(recv func() (proto.Message, error)) (int64, error) {
+	var size int64
+	for {
+		resp, err := recv()
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		size += int64(proto.Size(resp))
+	}
+}
+
+// grpcMessages count сообщений вызова call из json шаблона, пустой шаблон - пустое сообщение
+func grpcMessages(md *desc.MessageDescriptor, template []byte, call, count int) ([]proto.Message, error) {
+	msgs := make([]proto.Message, 0, count)
+	for i := 0; i < count; i++ {
+		msg := dynamic.NewMessage(md)
+		if len(bytes.TrimSpace(template)) > 0 {
+			id := strconv.Itoa(call) + "-" + strconv.Itoa(i)
+			if err := msg.UnmarshalJSON(bytes.ReplaceAll(template, []byte(GRPCIDPlaceholder), []byte(id))); err != nil {
+				return nil, err
+			}
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// grpcMetadata заголовки запроса в виде metadata, ключи metadata в нижнем регистре
+func grpcMetadata(headers http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range headers {
+		md.Append(key, values...)
+	}
+
+	return md
+}
+
+// classifyCode классифицирует код статуса gRPC так же, как ответы http:
+// DeadlineExceeded - таймаут, Unavailable - ошибка соединения, остальные коды кроме OK - ошибочный статус
+func classifyCode(code string) string {
+	switch code {
+	case codes.OK.String():
+		return ErrClassNone
+	case codes.DeadlineExceeded.String():
+		return ErrClassTimeout
+	case codes.Unavailable.String():
+		return ErrClassNetwork
+	default:
+		return ErrClassStatus
+	}
+}
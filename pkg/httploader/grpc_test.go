@@ -0,0 +1,313 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// echoMsg сообщение echo.Msg из testdata/echo.proto, совпадает с HealthCheckRequest по формату,
+// поэтому тестовому серверу не нужен сгенерированный код
+type echoMsg = healthpb.HealthCheckRequest
+
+// echoReply ответ echo.Echo: "fail" - ошибка NotFound, "slow" - ответ позже таймаута тестов
+func echoReply(in *echoMsg) (*echoMsg, error) {
+	switch in.Service {
+	case "fail":
+		return nil, status.Error(codes.NotFound, "not found")
+	case "slow":
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	return in, nil
+}
+
+// grpcTestServer сервер с health и server reflection и сервисом echo.Echo, описанным только в testdata/echo.proto
+// received считает сообщения, полученные echo.Echo из потоков клиента
+func grpcTestServer(t *testing.T) (addr string, received *int64, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	received = new(int64)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "echo.Echo",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Say",
+			Handler: func(_ interface{}, _ context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := &echoMsg{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				return echoReply(in)
+			},
+		}},
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Repeat",
+				ServerStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					in := &echoMsg{}
+					if err := stream.RecvMsg(in); err != nil {
+						return err
+					}
+					for i := 0; i < 3; i++ {
+						if err := stream.SendMsg(in); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			},
+			{
+				StreamName:    "Collect",
+				ClientStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					var count int
+					for {
+						if err := stream.RecvMsg(&echoMsg{}); err == io.EOF {
+							break
+						} else if err != nil {
+							return err
+						}
+						count++
+						atomic.AddInt64(received, 1)
+					}
+					return stream.SendMsg(&echoMsg{Service: strconv.Itoa(count)})
+				},
+			},
+			{
+				StreamName:    "Chat",
+				ClientStreams: true,
+				ServerStreams: true,
+				Handler: func(_ interface{}, stream grpc.ServerStream) error {
+					for {
+						in := &echoMsg{}
+						if err := stream.RecvMsg(in); err == io.EOF {
+							return nil
+						} else if err != nil {
+							return err
+						}
+						atomic.AddInt64(received, 1)
+						out, err := echoReply(in)
+						if err != nil {
+							return err
+						}
+						if err = stream.SendMsg(out); err != nil {
+							return err
+						}
+					}
+				},
+			},
+		},
+	}, &struct{}{})
+
+	go srv.Serve(lis)
+
+	return lis.Addr().String(), received, srv.Stop
+}
+
+func TestGRPCLoader(t *testing.T) {
+	type testCase struct {
+		name      string
+		call      string
+		template  string
+		headers   *http.Header
+		n, c      int
+		proto     bool
+		stopped   bool
+		opts      []Option
+		expected  Report
+		code      string
+		received  int64
+		loadError string
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "unary call by server reflection",
+			call:     "grpc.health.v1.Health/Check",
+			headers:  &http.Header{"X-Request-Id": []string{"1"}},
+			n:        5,
+			c:        2,
+			expected: Report{All: 5, Success: 5},
+			code:     "OK",
+		},
+		{
+			name:     "error status code",
+			call:     "grpc.health.v1.Health.Check",
+			template: `{"service": "unknown"}`,
+			n:        3,
+			expected: Report{All: 3, Errors: 3},
+			code:     "NotFound",
+		},
+		{
+			name:     "unary call from proto file",
+			call:     "echo.Echo/Say",
+			template: `{"text": "{{id}}"}`,
+			n:        4,
+			proto:    true,
+			expected: Report{All: 4, Success: 4},
+			code:     "OK",
+		},
+		{
+			name:     "deadline exceeded",
+			call:     "echo.Echo/Say",
+			template: `{"text": "slow"}`,
+			n:        2,
+			proto:    true,
+			expected: Report{All: 2, Cancelled: 2},
+			code:     "DeadlineExceeded",
+		},
+		{
+			name:     "server streaming",
+			call:     "echo.Echo/Repeat",
+			template: `{"text": "{{id}}"}`,
+			n:        3,
+			proto:    true,
+			expected: Report{All: 3, Success: 3},
+			code:     "OK",
+		},
+		{
+			name:     "client streaming",
+			call:     "echo.Echo/Collect",
+			template: `{"text": "{{id}}"}`,
+			n:        2,
+			proto:    true,
+			opts:     []Option{WithStreamMessages(3)},
+			expected: Report{All: 2, Success: 2},
+			code:     "OK",
+			received: 6,
+		},
+		{
+			name:     "bidirectional streaming",
+			call:     "echo.Echo/Chat",
+			template: `{"text": "{{id}}"}`,
+			n:        2,
+			c:        2,
+			proto:    true,
+			opts:     []Option{WithStreamMessages(4)},
+			expected: Report{All: 2, Success: 2},
+			code:     "OK",
+			received: 8,
+		},
+		{
+			name:     "error status in stream",
+			call:     "echo.Echo/Chat",
+			template: `{"text": "fail"}`,
+			n:        2,
+			proto:    true,
+			opts:     []Option{WithStreamMessages(4)},
+			expected: Report{All: 2, Errors: 2},
+			code:     "NotFound",
+			received: 2,
+		},
+		{
+			name:     "server unavailable",
+			call:     "echo.Echo/Say",
+			n:        2,
+			proto:    true,
+			stopped:  true,
+			expected: Report{All: 2, Errors: 2},
+			code:     "Unavailable",
+		},
+		{
+			name:      "reflection of unknown service",
+			call:      "echo.Echo/Say",
+			n:         1,
+			loadError: "resolve echo.Echo/Say: server reflection: Service not found: echo.Echo",
+		},
+		{
+			name:      "unknown method",
+			call:      "echo.Echo/Shout",
+			n:         1,
+			proto:     true,
+			loadError: "resolve echo.Echo/Shout: method Shout not found in service echo.Echo",
+		},
+		{
+			name:      "invalid template",
+			call:      "echo.Echo/Say",
+			template:  `{"unknown": 1}`,
+			n:         1,
+			proto:     true,
+			loadError: `parse message template: message type echo.Msg has no known field named unknown`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, received, stop := grpcTestServer(t)
+			defer stop()
+			if tc.stopped {
+				stop()
+			}
+
+			var (
+				mu       sync.Mutex
+				observed []Sample
+			)
+			opts := append([]Option{WithObserver(func(s Sample) {
+				mu.Lock()
+				observed = append(observed, s)
+				mu.Unlock()
+			})}, tc.opts...)
+			if tc.proto {
+				opts = append(opts, WithProtoFiles([]string{"testdata"}, "echo.proto"))
+			}
+			loader := NewGRPC(100*time.Millisecond, tc.call, tc.n, tc.c, opts...)
+
+			rep, err := loader.Load(context.Background(), addr, tc.headers, []byte(tc.template))
+			if tc.loadError != "" {
+				require.EqualError(t, err, tc.loadError)
+				return
+			}
+			require.NoError(t, err)
+
+			rep.AvgResponseTime = 0
+			require.Equal(t, tc.expected, rep)
+			require.Equal(t, tc.received, atomic.LoadInt64(received))
+
+			require.Len(t, observed, tc.n)
+			for _, s := range observed {
+				require.Equal(t, MethodGRPC, s.Method)
+				require.Equal(t, tc.code, s.Code)
+				require.Greater(t, s.Latency, time.Duration(0))
+				if tc.code == "OK" {
+					require.NoError(t, s.Err)
+				} else {
+					require.Error(t, s.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitGRPCCall(t *testing.T) {
+	for _, call := range []string{"pkg.Service/Method", "/pkg.Service/Method", "pkg.Service.Method"} {
+		service, method, err := splitGRPCCall(call)
+		require.NoError(t, err)
+		require.Equal(t, "pkg.Service", service)
+		require.Equal(t, "Method", method)
+	}
+
+	for _, call := range []string{"", "Method", "pkg.Service/", "/Method"} {
+		_, _, err := splitGRPCCall(call)
+		require.Error(t, err, call)
+	}
+}
@@ -10,28 +10,121 @@ import (
 
 // Report отчёт по нагрузке на сервер
 // AvgResponseTime в секундах, если ответ был меньше 0.5 секунд, то в AvgResponseTime будет равен 0
+// Adaptive заполняется только при нагрузке с WithLatencyTarget, Retry - только с WithRetry, Throttle - только с WithBackpressure
+// Connections заполняется только у Loader с долгоживущими соединениями, например NewWebSocket и NewStream
 type Report struct {
 	Success         int
 	Cancelled       int
 	Errors          int
 	All             int
 	AvgResponseTime time.Duration
+	Adaptive        *AdaptiveReport
+	Retry           *RetryReport
+	Throttle        *ThrottleReport
+	Connections     *ConnectionReport
+	Socket          *SocketReport
 }
 
+// ConnectionReport статистика долгоживущих соединений
+// Failed - соединения, которые не удалось открыть, Dropped - разорванные до конца нагрузки
+// ConnectTime - среднее время установления соединения, Messages - количество полученных сообщений
+// InterEventP50 и InterEventP99 - перцентили времени между событиями одного потока, заполняются только у NewStream
+type ConnectionReport struct {
+	Opened        int
+	Failed        int
+	Dropped       int
+	ConnectTime   time.Duration
+	Messages      int
+	MessageRate   float64
+	InterEventP50 time.Duration
+	InterEventP99 time.Duration
+}
+
+// SocketReport статистика нагрузки по TCP или UDP
+// Lost - запросы UDP, ответ на которые не пришёл за таймаут, LossRate - их доля в процентах
+// Throughput - байт в секунду в обе стороны
+type SocketReport struct {
+	Sent          int
+	Lost          int
+	LossRate      float64
+	BytesSent     int64
+	BytesReceived int64
+	Throughput    float64
+}
+
+// Sample результат отдельного запроса
+// Status равен 0, если ответ от сервера не был получен, в этом случае Err содержит причину,
+// или если протокол не http, например у сообщений WebSocket
+// Worker - номер исполнителя, отправившего запрос, у consistent Loader всегда 0
+// Bytes - размер тела ответа
+// Attempt - номер попытки запроса, начиная с 1, больше 1 только у повторов с WithRetry
+// Retried - после попытки запрос повторён, итог запроса в Report даёт только последняя попытка
+// RetryAfter - значение заголовка Retry-After ответа, 0 если заголовка нет
+// Code - код статуса gRPC в виде codes.Code.String(), пустой у других протоколов
+// Operation - имя операции GraphQL у WithGraphQL
+// Addr - адрес сервера "ip:port" или путь unix сокета, с которым было открыто соединение запроса, заполняется у http, TCP и UDP, через прокси - адрес прокси
+// Source - локальный ip, с которого было открыто соединение запроса, заполняется там же, где Addr
+type Sample struct {
+	Start   time.Time
+	Worker  int
+	URL     string
+	Method  string
+	Status  int
+	Latency time.Duration
+	Phases  Phases
+	Bytes   int64
+	Err     error
+
+	Attempt    int
+	Retried    bool
+	RetryAfter time.Duration
+	Code       string
+	Operation  string
+	Addr       string
+	Source     string
+}
+
+// Observer получает результат каждого выполненного запроса
+// concurrency Loader вызывает Observer из нескольких горутин одновременно
+type Observer func(s Sample)
+
 type Loader interface {
 	Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error)
 }
 
+// Option дополнительная настройка Loader
+type Option func(l *consistent)
+
+// WithObserver подписывает obs на результаты всех запросов
+// опцию можно передать несколько раз, наблюдатели вызываются в порядке подписки
+func WithObserver(obs Observer) Option {
+	return func(l *consistent) {
+		l.observers = append(l.observers, obs)
+	}
+}
+
 // New создание инстанса объекта, поддерживающего Loader
 // аргумент с - количество одновременных запросов к серверу
 // если аргумент c будет больше 1, то будет concurrency Loader
-func New(timeOut time.Duration, method string, requests, c int) Loader {
+// с опцией WithLatencyTarget c - верхняя граница одновременных запросов, при c <= 1 граница 1000
+func New(timeOut time.Duration, method string, requests, c int, opts ...Option) Loader {
 	consistentLoader := consistent{
 		method:   method,
 		requests: requests,
 		timeout:  timeOut,
 	}
 
+	for _, opt := range opts {
+		opt(&consistentLoader)
+	}
+
+	if consistentLoader.latencyTarget > 0 {
+		if c <= 1 {
+			c = defaultAdaptiveLimit
+		}
+		return &adaptive{concurrency{consistent: consistentLoader, requestsPerTime: c}}
+	}
+
 	if c > 1 {
 		return &concurrency{consistent: consistentLoader, requestsPerTime: c}
 	} else {
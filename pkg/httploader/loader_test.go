@@ -15,6 +15,7 @@ func TestNew(t *testing.T) {
 		timeOut  time.Duration
 		requests int
 		c        int
+		opts     []Option
 	}
 
 	cases := [...]testCase{
@@ -29,11 +30,24 @@ func TestNew(t *testing.T) {
 			c:              10,
 			expectedLoader: &concurrency{requestsPerTime: 10},
 		},
+
+		{
+			name:           "get adaptive loader",
+			c:              10,
+			opts:           []Option{WithLatencyTarget(time.Second)},
+			expectedLoader: &adaptive{concurrency{consistent: consistent{latencyTarget: time.Second}, requestsPerTime: 10}},
+		},
+
+		{
+			name:           "get adaptive loader with default limit",
+			opts:           []Option{WithLatencyTarget(time.Second)},
+			expectedLoader: &adaptive{concurrency{consistent: consistent{latencyTarget: time.Second}, requestsPerTime: defaultAdaptiveLimit}},
+		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			res := New(tc.timeOut, tc.method, tc.requests, tc.c)
+			res := New(tc.timeOut, tc.method, tc.requests, tc.c, tc.opts...)
 
 			require.Equal(t, tc.expectedLoader, res)
 		})
@@ -0,0 +1,199 @@
+package httploader
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WithProxy соединения TCP всех протоколов открываются через прокси proxyURL:
+// http(s)://[user:password@]host:port - туннелем HTTP CONNECT, socks5://[user:password@]host:port - через SOCKS5
+// переменные окружения HTTP_PROXY, HTTPS_PROXY и NO_PROXY при этом не учитываются
+func WithProxy(proxyURL *url.URL) Option {
+	return func(l *consistent) {
+		d := l.customDialer()
+		d.httpProxy = nil
+		d.proxy = func(context.Context, string) (*url.URL, error) {
+			return proxyURL, nil
+		}
+	}
+}
+
+// WithEnvProxy honor - соединения WebSocket, TCP и gRPC тоже идут через прокси из переменных окружения:
+// туннелем до адресов https и wss через HTTPS_PROXY, до остальных через HTTP_PROXY, с исключениями из NO_PROXY,
+// иначе переменные окружения не учитываются даже запросами http
+// запросы http ходят через прокси из окружения сами, как у http.DefaultTransport, в том числе без опции
+func WithEnvProxy(honor bool) Option {
+	return func(l *consistent) {
+		d := l.customDialer()
+		if !honor {
+			d.httpProxy = func(*http.Request) (*url.URL, error) {
+				return nil, nil
+			}
+			d.proxy = func(context.Context, string) (*url.URL, error) {
+				return nil, nil
+			}
+			return
+		}
+
+		httpProxy := envProxy()
+		d.httpProxy = httpProxy
+		d.proxy = func(ctx context.Context, addr string) (*url.URL, error) {
+			return httpProxy(&http.Request{URL: &url.URL{Scheme: proxyScheme(ctx), Host: addr}})
+		}
+	}
+}
+
+// envProxy прокси из HTTP_PROXY, HTTPS_PROXY и NO_PROXY для запроса, переменные читаются один раз при вызове envProxy
+func envProxy() func(req *http.Request) (*url.URL, error) {
+	proxyFunc := httpproxy.FromEnvironment().ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// proxySchemeKey ключ контекста соединения со схемой адреса, по которой выбирается прокси из окружения
+type proxySchemeKey struct{}
+
+// withProxyScheme соединения из ctx открываются к адресу со схемой scheme, http или https
+func withProxyScheme(ctx context.Context, scheme string) context.Context {
+	return context.WithValue(ctx, proxySchemeKey{}, scheme)
+}
+
+func proxyScheme(ctx context.Context) string {
+	if scheme, ok := ctx.Value(proxySchemeKey{}).(string); ok {
+		return scheme
+	}
+
+	return "http"
+}
+
+// proxyTracerKey ключ контекста запроса с phaseTracer, в который dialer записывает время открытия туннеля
+type proxyTracerKey struct{}
+
+// viaProxy открывает соединение с addr через прокси proxyURL
+func (d *dialer) viaProxy(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	start := time.Now()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch proxyURL.Scheme {
+	case "http", "https":
+		conn, err = d.tunnel(ctx, proxyURL, addr)
+	case "socks5":
+		conn, err = d.socks5(ctx, proxyURL, addr)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", proxyURL.Host, err)
+	}
+
+	if tracer, ok := ctx.Value(proxyTracerKey{}).(*phaseTracer); ok {
+		tracer.proxied(start, time.Now())
+	}
+
+	return conn, nil
+}
+
+// tunnel открывает туннель HTTP CONNECT до addr, с прокси https соединение с самим прокси идёт по TLS
+func (d *dialer) tunnel(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), map[string]string{"http": "80", "https": "443"}[proxyURL.Scheme])
+	}
+	conn, err := d.direct(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("CONNECT %s: %s", addr, resp.Status)
+	}
+
+	// сервер мог ответить сразу после туннеля, и часть ответа уже прочитана в br
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// socks5 открывает соединение с addr через прокси SOCKS5
+func (d *dialer) socks5(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{User: user.Username(), Password: password}
+	}
+
+	socks, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, directDialer{d})
+	if err != nil {
+		return nil, err
+	}
+
+	return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+}
+
+// directDialer соединение с самим прокси открывается напрямую
+type directDialer struct {
+	d *dialer
+}
+
+func (dd directDialer) Dial(network, addr string) (net.Conn, error) {
+	return dd.d.direct(context.Background(), network, addr)
+}
+
+func (dd directDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return dd.d.direct(ctx, network, addr)
+}
+
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
@@ -0,0 +1,328 @@
+package httploader
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// proxyTargets адреса, к которым тестовый прокси открывал соединения
+type proxyTargets struct {
+	mu    sync.Mutex
+	addrs []string
+}
+
+func (p *proxyTargets) add(addr string) {
+	p.mu.Lock()
+	p.addrs = append(p.addrs, addr)
+	p.mu.Unlock()
+}
+
+func (p *proxyTargets) list() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.addrs...)
+}
+
+// pipe пересылает данные между соединениями, пока одно из них не закроется
+func pipe(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b)
+		a.Close()
+	}()
+	io.Copy(b, a)
+	b.Close()
+}
+
+// connectTestProxy прокси HTTP CONNECT, открывающий все туннели на backend, auth - ожидаемые "user:password"
+func connectTestProxy(t *testing.T, backend, auth string) (*url.URL, *proxyTargets) {
+	targets := &proxyTargets{}
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if auth != "" && r.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)) {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		targets.add(r.Host)
+
+		upstream, err := net.Dial("tcp", backend)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		pipe(conn, upstream)
+	}))
+	t.Cleanup(serv.Close)
+
+	u, err := url.Parse(serv.URL)
+	require.NoError(t, err)
+
+	return u, targets
+}
+
+// socks5TestProxy прокси SOCKS5 с авторизацией user:password, открывающий все соединения на backend
+func socks5TestProxy(t *testing.T, backend, user, password string) (*url.URL, *proxyTargets) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	targets := &proxyTargets{}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+
+		buf := make([]byte, 262)
+		// приветствие: версия, количество и список способов авторизации, выбирается логин и пароль
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		conn.Write([]byte{5, 2})
+
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		gotUser := make([]byte, buf[1])
+		io.ReadFull(conn, gotUser)
+		io.ReadFull(conn, buf[:1])
+		gotPassword := make([]byte, buf[0])
+		io.ReadFull(conn, gotPassword)
+		if string(gotUser) != user || string(gotPassword) != password {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+
+		// запрос CONNECT с адресом в виде имени
+		if _, err := io.ReadFull(conn, buf[:5]); err != nil || buf[3] != 3 {
+			return
+		}
+		host := make([]byte, buf[4])
+		io.ReadFull(conn, host)
+		io.ReadFull(conn, buf[:2])
+		targets.add(net.JoinHostPort(string(host), strconv.Itoa(int(binary.BigEndian.Uint16(buf[:2])))))
+
+		upstream, err := net.Dial("tcp", backend)
+		if err != nil {
+			conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		pipe(conn, upstream)
+	}
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return &url.URL{Scheme: "socks5", Host: lis.Addr().String(), User: url.UserPassword(user, password)}, targets
+}
+
+func TestProxy(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer backend.Close()
+	backendAddr := backend.Listener.Addr().String()
+	// имя разрешает только прокси
+	host := "http://staging.internal:8080/health"
+
+	collect := func() (Option, func() []Phases) {
+		var (
+			mu     sync.Mutex
+			phases []Phases
+		)
+		return WithObserver(func(s Sample) {
+				mu.Lock()
+				phases = append(phases, s.Phases)
+				mu.Unlock()
+			}), func() []Phases {
+				mu.Lock()
+				defer mu.Unlock()
+				return phases
+			}
+	}
+
+	t.Run("http connect with auth", func(t *testing.T) {
+		proxyURL, targets := connectTestProxy(t, backendAddr, "bench:secret")
+		proxyURL.User = url.UserPassword("bench", "secret")
+		observer, phases := collect()
+
+		rep, err := New(time.Second, http.MethodGet, 4, 2, observer, WithProxy(proxyURL)).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 4, rep.Success)
+		require.NotEmpty(t, targets.list())
+		for _, target := range targets.list() {
+			require.Equal(t, "staging.internal:8080", target)
+		}
+
+		var tunnels int
+		for _, p := range phases() {
+			if p.Proxy > 0 {
+				tunnels++
+				require.GreaterOrEqual(t, p.Proxy, p.Connect, "tunnel time includes connection to proxy")
+			}
+		}
+		require.NotZero(t, tunnels)
+		require.LessOrEqual(t, tunnels, len(targets.list()), "proxy time is reported only for new connections")
+	})
+
+	t.Run("http connect with wrong auth", func(t *testing.T) {
+		proxyURL, _ := connectTestProxy(t, backendAddr, "bench:secret")
+		proxyURL.User = url.UserPassword("bench", "wrong")
+
+		var sampleErr error
+		rep, err := New(time.Second, http.MethodGet, 1, 1, WithProxy(proxyURL), WithObserver(func(s Sample) {
+			sampleErr = s.Err
+		})).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, rep.Success)
+		require.Error(t, sampleErr)
+		require.Contains(t, sampleErr.Error(), "407 Proxy Authentication Required")
+	})
+
+	t.Run("socks5 with auth", func(t *testing.T) {
+		proxyURL, targets := socks5TestProxy(t, backendAddr, "bench", "secret")
+
+		rep, err := New(time.Second, http.MethodGet, 3, 1, WithProxy(proxyURL)).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Success)
+		require.Equal(t, []string{"staging.internal:8080"}, targets.list(), "keep-alive connection is reused")
+	})
+
+	t.Run("socks5 wrong auth", func(t *testing.T) {
+		proxyURL, _ := socks5TestProxy(t, backendAddr, "bench", "secret")
+		proxyURL.User = url.UserPassword("bench", "wrong")
+
+		rep, err := New(time.Second, http.MethodGet, 1, 1, WithProxy(proxyURL)).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, rep.Success)
+	})
+
+	t.Run("tcp socket through proxy", func(t *testing.T) {
+		proxyURL, targets := socks5TestProxy(t, backendAddr, "bench", "secret")
+		l := consistent{}
+		WithProxy(proxyURL)(&l)
+
+		conn, err := l.dial(context.Background(), "tcp", "staging.internal:8080")
+		require.NoError(t, err)
+		conn.Close()
+		require.Equal(t, []string{"staging.internal:8080"}, targets.list())
+	})
+
+	t.Run("environment proxy", func(t *testing.T) {
+		// прокси для http получает запросы в absolute-form и сам отвечает на них
+		var (
+			mu        sync.Mutex
+			forwarded []string
+		)
+		forward := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			forwarded = append(forwarded, r.Method+" "+r.URL.String())
+			mu.Unlock()
+		}))
+		defer forward.Close()
+		tunnelURL, targets := connectTestProxy(t, backendAddr, "")
+		t.Setenv("HTTP_PROXY", forward.URL)
+		t.Setenv("HTTPS_PROXY", tunnelURL.String())
+		t.Setenv("NO_PROXY", "")
+
+		rep, err := New(time.Second, http.MethodGet, 1, 1, WithEnvProxy(true)).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, rep.Success)
+		require.Equal(t, []string{"GET http://staging.internal:8080/health"}, forwarded, "http is forwarded without CONNECT")
+		require.Empty(t, targets.list())
+
+		l := consistent{}
+		WithEnvProxy(true)(&l)
+		conn, err := l.dial(withProxyScheme(context.Background(), "https"), "tcp", "staging.internal:8443")
+		require.NoError(t, err)
+		conn.Close()
+		require.Equal(t, []string{"staging.internal:8443"}, targets.list(), "https proxy is chosen by scheme, not port")
+
+		proxied, err := l.dialer.proxyFor(context.Background(), "tcp", "staging.internal:443")
+		require.NoError(t, err)
+		require.Equal(t, forward.URL, proxied.String(), "connections without scheme use HTTP_PROXY")
+
+		l = consistent{}
+		WithEnvProxy(false)(&l)
+		proxied, err = l.dialer.transport().Proxy(httptest.NewRequest(http.MethodGet, host, nil))
+		require.NoError(t, err)
+		require.Nil(t, proxied, "environment is ignored by http too")
+		proxied, err = l.dialer.proxyFor(context.Background(), "tcp", "staging.internal:8080")
+		require.NoError(t, err)
+		require.Nil(t, proxied)
+	})
+
+	t.Run("environment proxy with resolve", func(t *testing.T) {
+		// прокси из окружения получает туннель до адреса из -resolve, а не имя хоста
+		_, port, err := net.SplitHostPort(backendAddr)
+		require.NoError(t, err)
+		target := "http://staging.internal:" + port + "/health"
+		resolve := WithResolve("staging.internal:"+port, "127.0.0.1")
+		tunnelURL, targets := connectTestProxy(t, backendAddr, "")
+		t.Setenv("HTTP_PROXY", tunnelURL.String())
+		t.Setenv("NO_PROXY", "")
+
+		for _, opts := range [][]Option{{resolve}, {resolve, WithEnvProxy(true)}} {
+			rep, err := New(time.Second, http.MethodGet, 1, 1, opts...).Load(context.Background(), target, nil, nil)
+			require.NoError(t, err)
+			require.Equal(t, 1, rep.Success)
+		}
+		require.Equal(t, []string{backendAddr, backendAddr}, targets.list())
+
+		rep, err := New(time.Second, http.MethodGet, 1, 1, resolve, WithEnvProxy(false)).Load(context.Background(), target, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, rep.Success)
+		require.Len(t, targets.list(), 2, "without environment proxy the resolved address is dialed directly")
+	})
+
+	t.Run("https proxy", func(t *testing.T) {
+		tlsProxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer tlsProxy.Close()
+		proxyURL, err := url.Parse(tlsProxy.URL)
+		require.NoError(t, err)
+
+		l := consistent{}
+		WithProxy(proxyURL)(&l)
+		_, err = l.dial(context.Background(), "tcp", "staging.internal:8080")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "certificate", "connection to proxy is made over tls")
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		var sampleErr error
+		rep, err := New(time.Second, http.MethodGet, 1, 1, WithProxy(&url.URL{Scheme: "ftp", Host: "127.0.0.1:21"}), WithObserver(func(s Sample) {
+			sampleErr = s.Err
+		})).Load(context.Background(), host, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, rep.Success)
+		require.Contains(t, sampleErr.Error(), `unsupported proxy scheme "ftp"`)
+	})
+}
@@ -0,0 +1,251 @@
+package httploader
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// классы ошибок запроса, см. Sample.ErrorClass
+const (
+	ErrClassNone    = ""
+	ErrClassTimeout = "timeout"
+	ErrClassDNS     = "dns"
+	ErrClassNetwork = "network"
+	ErrClassStatus  = "status"
+	ErrClassGraphQL = "graphql"
+	ErrClassAuth    = "auth"
+)
+
+// Phases длительность фаз запроса
+// DNS, Connect, Proxy и TLS равны 0, если соединение было переиспользовано
+// Proxy время открытия туннеля через прокси вместе с соединением с прокси, Connect при этом - соединение с прокси
+// FirstByte время от начала запроса до первого байта ответа
+type Phases struct {
+	DNS       time.Duration
+	Connect   time.Duration
+	Proxy     time.Duration
+	TLS       time.Duration
+	FirstByte time.Duration
+}
+
+// ErrorClass классифицирует результат запроса
+// успешным (ErrClassNone) считается только ответ со статусом 200 или ответ без статуса по протоколу не http,
+// у WithGraphQL ответ 200 с ошибками в errors - ErrClassGraphQL, у WithAuth неудачная авторизация запроса - ErrClassAuth
+// вызовы gRPC классифицируются по Code
+func (s Sample) ErrorClass() string {
+	if s.Code != "" {
+		return classifyCode(s.Code)
+	}
+
+	if s.Err != nil {
+		return classifyErr(s.Err)
+	}
+
+	if s.Status != http.StatusOK && s.Status != 0 {
+		return ErrClassStatus
+	}
+
+	return ErrClassNone
+}
+
+// Answered получен ли ответ на запрос, возможно с ошибкой
+// вызов gRPC считается отвеченным, если сервер вернул код статуса, а не истёк таймаут или не удалось соединиться
+func (s Sample) Answered() bool {
+	if s.Code != "" {
+		class := s.ErrorClass()
+		return class != ErrClassTimeout && class != ErrClassNetwork
+	}
+
+	return s.Status != 0 || s.Err == nil
+}
+
+func classifyErr(err error) string {
+	var authErr *authError
+	if errors.As(err, &authErr) {
+		return ErrClassAuth
+	}
+
+	var gqlErr *graphQLError
+	if errors.As(err, &gqlErr) {
+		return ErrClassGraphQL
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return ErrClassTimeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrClassDNS
+	}
+
+	return ErrClassNetwork
+}
+
+// do выполняет запрос req от имени worker и замеряет его фазы
+// тело ответа вычитывается полностью, чтобы учесть размер ответа и переиспользовать соединение
+func (l *consistent) do(req *http.Request, worker int) Sample {
+	s := Sample{
+		Worker: worker,
+		URL:    req.URL.String(),
+		Method: req.Method,
+	}
+
+	tracer := &phaseTracer{}
+	ctx := context.WithValue(withProxyScheme(req.Context(), req.URL.Scheme), proxyTracerKey{}, tracer)
+	req = req.WithContext(httptrace.WithClientTrace(ctx, tracer.trace()))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			req.Body = body
+		}
+	}
+
+	if l.auth != nil {
+		if err := l.authorize(req); err != nil {
+			s.Start, s.Err = time.Now(), err
+			return s
+		}
+	}
+
+	cli := l.httpClient(l.timeout)
+
+	s.Start = time.Now()
+	resp, err := cli.Do(req)
+	if err != nil {
+		s.Latency = time.Since(s.Start)
+		s.Phases, s.Addr, s.Source = tracer.phases(s.Start), tracer.remoteAddr(), tracer.localIP()
+		s.Err = err
+		return s
+	}
+
+	if l.graphql {
+		var buf bytes.Buffer
+		s.Bytes, err = io.Copy(&buf, resp.Body)
+		if err == nil && resp.StatusCode == http.StatusOK {
+			err = checkGraphQL(buf.Bytes())
+		}
+	} else {
+		s.Bytes, err = io.Copy(io.Discard, resp.Body)
+	}
+	resp.Body.Close()
+	s.Latency = time.Since(s.Start)
+	s.Phases, s.Addr, s.Source = tracer.phases(s.Start), tracer.remoteAddr(), tracer.localIP()
+	s.Status = resp.StatusCode
+	if l.auth != nil {
+		l.unauthorized(req, resp.StatusCode)
+	}
+	s.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if err != nil {
+		s.Err = err
+	}
+
+	return s
+}
+
+// phaseTracer собирает тайминги httptrace, колбэки могут вызываться из горутин транспорта
+type phaseTracer struct {
+	mu sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	proxyStart, proxyDone     time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	addr, source              string
+}
+
+func (t *phaseTracer) trace() *httptrace.ClientTrace {
+	mark := func(dst *time.Time) {
+		t.mu.Lock()
+		if dst.IsZero() {
+			*dst = time.Now()
+		}
+		t.mu.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		GotFirstResponseByte: func() { mark(&t.firstByte) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.addr = info.Conn.RemoteAddr().String()
+			t.source = ipOf(info.Conn.LocalAddr())
+			t.mu.Unlock()
+		},
+	}
+}
+
+func (t *phaseTracer) phases(start time.Time) Phases {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Phases{
+		DNS:       between(t.dnsStart, t.dnsDone),
+		Connect:   between(t.connectStart, t.connectDone),
+		Proxy:     between(t.proxyStart, t.proxyDone),
+		TLS:       between(t.tlsStart, t.tlsDone),
+		FirstByte: between(start, t.firstByte),
+	}
+}
+
+// proxied отмечает открытие туннеля через прокси, вызывается dialer
+func (t *phaseTracer) proxied(start, done time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.proxyStart.IsZero() {
+		t.proxyStart, t.proxyDone = start, done
+	}
+}
+
+// remoteAddr адрес сервера соединения, по которому шёл запрос, пустой если соединение не открылось
+func (t *phaseTracer) remoteAddr() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.addr
+}
+
+// localIP локальный ip соединения, по которому шёл запрос
+func (t *phaseTracer) localIP() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.source
+}
+
+// ipOf ip адреса соединения TCP или UDP, пустая строка для остальных
+func ipOf(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	default:
+		return ""
+	}
+}
+
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() {
+		return 0
+	}
+
+	return to.Sub(from)
+}
@@ -0,0 +1,120 @@
+package httploader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestSampleErrorClass(t *testing.T) {
+	type testCase struct {
+		name     string
+		sample   Sample
+		expected string
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "success",
+			sample:   Sample{Status: http.StatusOK},
+			expected: ErrClassNone,
+		},
+		{
+			name:     "answer without status, for example WebSocket message",
+			sample:   Sample{Method: MethodWebSocket},
+			expected: ErrClassNone,
+		},
+		{
+			name:     "unexpected status",
+			sample:   Sample{Status: http.StatusNotFound},
+			expected: ErrClassStatus,
+		},
+		{
+			name:     "timeout",
+			sample:   Sample{Err: &url.Error{Op: "Get", URL: "host", Err: timeoutErr{}}},
+			expected: ErrClassTimeout,
+		},
+		{
+			name:     "dns",
+			sample:   Sample{Err: &url.Error{Op: "Get", URL: "host", Err: &net.DNSError{Err: "no such host"}}},
+			expected: ErrClassDNS,
+		},
+		{
+			name:     "graphql errors in successful response",
+			sample:   Sample{Status: http.StatusOK, Err: &graphQLError{messages: []string{"not found"}}},
+			expected: ErrClassGraphQL,
+		},
+		{
+			name:     "network",
+			sample:   Sample{Err: errors.New("connection refused")},
+			expected: ErrClassNetwork,
+		},
+		{
+			name:     "grpc ok",
+			sample:   Sample{Method: MethodGRPC, Code: "OK"},
+			expected: ErrClassNone,
+		},
+		{
+			name:     "grpc error code",
+			sample:   Sample{Method: MethodGRPC, Code: "NotFound", Err: errors.New("rpc error: code = NotFound")},
+			expected: ErrClassStatus,
+		},
+		{
+			name:     "grpc deadline",
+			sample:   Sample{Method: MethodGRPC, Code: "DeadlineExceeded", Err: errors.New("rpc error: code = DeadlineExceeded")},
+			expected: ErrClassTimeout,
+		},
+		{
+			name:     "grpc unavailable",
+			sample:   Sample{Method: MethodGRPC, Code: "Unavailable", Err: errors.New("rpc error: code = Unavailable")},
+			expected: ErrClassNetwork,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.sample.ErrorClass())
+		})
+	}
+}
+
+func TestDo(t *testing.T) {
+	var bodies []string
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		buf := make([]byte, 64)
+		n, _ := request.Body.Read(buf)
+		bodies = append(bodies, string(buf[:n]))
+		writer.Write([]byte("pong"))
+	}))
+	defer serv.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, serv.URL, bytes.NewBufferString("ping"))
+	require.NoError(t, err)
+
+	l := consistent{timeout: time.Second}
+	first := l.do(req, 3)
+	second := l.do(req, 3)
+
+	require.Equal(t, []string{"ping", "ping"}, bodies, "body is sent with each request")
+	require.Equal(t, 3, first.Worker)
+	require.Equal(t, serv.URL, first.URL)
+	require.Equal(t, http.MethodPost, first.Method)
+	require.Equal(t, int64(4), first.Bytes)
+	require.Greater(t, int64(first.Phases.Connect), int64(0), "new connection")
+	require.Greater(t, int64(first.Phases.FirstByte), int64(0))
+	require.LessOrEqual(t, first.Phases.FirstByte, first.Latency)
+	require.Equal(t, time.Duration(0), second.Phases.Connect, "connection reused")
+}
@@ -0,0 +1,172 @@
+package httploader
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy повтор неуспешных запросов, как у клиентов нагружаемого сервера
+// MaxAttempts - количество попыток вместе с первой, при MaxAttempts <= 1 запросы не повторяются
+// пауза перед i-м повтором Backoff * 2^(i-1), но не больше MaxBackoff (0 - без ограничения),
+// Jitter от 0 до 1 - доля паузы, на которую она случайно уменьшается, чтобы повторы исполнителей не совпадали
+// Statuses и ErrorClasses - коды ответа и классы ошибок (ErrClassTimeout, ErrClassNetwork, ...), при которых запрос повторяется
+// с RespectRetryAfter пауза после 429 и 503 берётся из заголовка Retry-After, если он есть
+type RetryPolicy struct {
+	MaxAttempts       int
+	Backoff           time.Duration
+	MaxBackoff        time.Duration
+	Jitter            float64
+	Statuses          []int
+	ErrorClasses      []string
+	RespectRetryAfter bool
+}
+
+// RetryReport итог запросов с повторами, каждый запрос попадает ровно в одно из FirstTry, AfterRetry и Failed
+// Retries - количество повторных попыток
+type RetryReport struct {
+	FirstTry   int
+	AfterRetry int
+	Failed     int
+	Retries    int
+}
+
+// WithRetry включает повтор запросов по policy
+// Report.All и остальные поля Report учитывают итог запроса после всех попыток,
+// наблюдатели получают каждую попытку, номер попытки в Sample.Attempt, у повторённых попыток Sample.Retried
+func WithRetry(policy RetryPolicy) Option {
+	return func(l *consistent) {
+		if policy.MaxAttempts > 1 {
+			l.retry = &policy
+		}
+	}
+}
+
+// send выполняет запрос с повторами по политике l.retry и возвращает результат последней попытки
+// и начало первой, от которого WithPacing отсчитывает следующий запрос
+// каждая попытка учитывается в bp, повторы прекращаются при отмене контекста
+// с mix запрос - следующая операция GraphQL, все попытки повторяют её же
+func (l *consistent) send(ctx context.Context, req *http.Request, mix *graphQLMix, worker int, bp *backpressure) (Sample, time.Time) {
+	var operation string
+	if mix != nil {
+		req, operation = mix.request(req)
+	}
+
+	var start time.Time
+	for attempt := 1; ; attempt++ {
+		s := l.do(req, worker)
+		s.Attempt, s.Operation = attempt, operation
+		bp.observe(s)
+		if attempt == 1 {
+			start = s.Start
+		}
+
+		if l.retry == nil || attempt >= l.retry.MaxAttempts || !l.retry.retryable(s) {
+			l.observe(s)
+			return s, start
+		}
+
+		// попытка отдаётся наблюдателям после паузы, когда известно, будет ли повтор
+		bp.hold(ctx, s, l.retry.delay(s, attempt))
+		if ctx.Err() != nil {
+			l.observe(s)
+			return s, start
+		}
+		s.Retried = true
+		l.observe(s)
+	}
+}
+
+func (p *RetryPolicy) retryable(s Sample) bool {
+	class := s.ErrorClass()
+	if class == ErrClassNone {
+		return false
+	}
+
+	if class == ErrClassStatus {
+		for _, status := range p.Statuses {
+			if s.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, c := range p.ErrorClasses {
+		if class == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// delay пауза перед повтором после attempt-й попытки, результат которой s
+func (p *RetryPolicy) delay(s Sample, attempt int) time.Duration {
+	if p.RespectRetryAfter && s.RetryAfter > 0 && throttled(s.Status) {
+		return s.RetryAfter
+	}
+
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	return d - time.Duration(p.Jitter*rand.Float64()*float64(d))
+}
+
+// throttled статус ответа, которым сервер просит клиента снизить нагрузку
+func throttled(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter значение заголовка Retry-After: количество секунд или http дата, 0 если заголовка нет или он некорректен
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+// add учитывает итог запроса s, у nil RetryReport ничего не делает
+func (r *RetryReport) add(s Sample) {
+	if r == nil {
+		return
+	}
+
+	r.Retries += s.Attempt - 1
+	switch {
+	case s.ErrorClass() != ErrClassNone:
+		r.Failed++
+	case s.Attempt > 1:
+		r.AfterRetry++
+	default:
+		r.FirstTry++
+	}
+}
+
+// newRetryReport пустой RetryReport, если включены повторы, иначе nil
+func (l *consistent) newRetryReport() *RetryReport {
+	if l.retry == nil {
+		return nil
+	}
+
+	return &RetryReport{}
+}
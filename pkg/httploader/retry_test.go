@@ -0,0 +1,159 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	require.Equal(t, 10*time.Millisecond, policy.delay(Sample{}, 1))
+	require.Equal(t, 20*time.Millisecond, policy.delay(Sample{}, 2))
+	require.Equal(t, 40*time.Millisecond, policy.delay(Sample{}, 3))
+	require.Equal(t, 50*time.Millisecond, policy.delay(Sample{}, 10), "backoff is limited by MaxBackoff")
+
+	throttledSample := Sample{Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}
+	require.Equal(t, 10*time.Millisecond, policy.delay(throttledSample, 1), "Retry-After is ignored by default")
+	policy.RespectRetryAfter = true
+	require.Equal(t, 2*time.Second, policy.delay(throttledSample, 1))
+	require.Equal(t, 10*time.Millisecond, policy.delay(Sample{Status: http.StatusBadGateway, RetryAfter: 2 * time.Second}, 1))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := policy.delay(Sample{}, 1)
+		require.GreaterOrEqual(t, d, 5*time.Millisecond)
+		require.LessOrEqual(t, d, 10*time.Millisecond)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := RetryPolicy{Statuses: []int{http.StatusServiceUnavailable}, ErrorClasses: []string{ErrClassTimeout}}
+
+	require.False(t, policy.retryable(Sample{Status: http.StatusOK}))
+	require.True(t, policy.retryable(Sample{Status: http.StatusServiceUnavailable}))
+	require.False(t, policy.retryable(Sample{Status: http.StatusInternalServerError}))
+	require.False(t, policy.retryable(Sample{Err: context.Canceled}), "network errors are not in ErrorClasses")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	require.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("-3", now))
+	require.Equal(t, 90*time.Second, parseRetryAfter("Fri, 01 Jan 2021 00:01:30 GMT", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("Thu, 31 Dec 2020 00:00:00 GMT", now), "date in the past")
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestWithRetry(t *testing.T) {
+	// сервер отвечает 503 на каждый нечётный запрос
+	var received int64
+	flaky := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt64(&received, 1)%2 == 1 {
+			writer.Header().Set("Retry-After", "0")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer flaky.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Statuses: []int{http.StatusServiceUnavailable, http.StatusInternalServerError}, RespectRetryAfter: true}
+
+	type testCase struct {
+		name     string
+		host     string
+		c        int
+		policy   RetryPolicy
+		expected Report
+		attempts int64
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "consistent loader, every request succeeds after one retry",
+			host:     flaky.URL,
+			policy:   policy,
+			expected: Report{All: 4, Success: 4, Retry: &RetryReport{AfterRetry: 4, Retries: 4}},
+			attempts: 8,
+		},
+		{
+			name:     "consistent loader, every request fails after all attempts",
+			host:     broken.URL,
+			policy:   policy,
+			expected: Report{All: 4, Errors: 4, Retry: &RetryReport{Failed: 4, Retries: 8}},
+			attempts: 12,
+		},
+		{
+			name:     "concurrency loader, every request fails after all attempts",
+			host:     broken.URL,
+			c:        2,
+			policy:   policy,
+			expected: Report{All: 4, Errors: 4, Retry: &RetryReport{Failed: 4, Retries: 8}},
+			attempts: 12,
+		},
+		{
+			name:     "status is not retried",
+			host:     broken.URL,
+			policy:   RetryPolicy{MaxAttempts: 3, Statuses: []int{http.StatusServiceUnavailable}},
+			expected: Report{All: 4, Errors: 4, Retry: &RetryReport{Failed: 4}},
+			attempts: 4,
+		},
+		{
+			name:     "single attempt disables retries",
+			host:     broken.URL,
+			policy:   RetryPolicy{MaxAttempts: 1, Statuses: []int{http.StatusInternalServerError}},
+			expected: Report{All: 4, Errors: 4},
+			attempts: 4,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt64(&received, 0)
+
+			var attempts, finals, maxAttempt int64
+			obs := func(s Sample) {
+				atomic.AddInt64(&attempts, 1)
+				if !s.Retried {
+					atomic.AddInt64(&finals, 1)
+				}
+				if int64(s.Attempt) > atomic.LoadInt64(&maxAttempt) {
+					atomic.StoreInt64(&maxAttempt, int64(s.Attempt))
+				}
+			}
+			loader := New(time.Second, http.MethodGet, 4, tc.c, WithRetry(tc.policy), WithObserver(obs))
+
+			rep, err := loader.Load(context.Background(), tc.host, nil, nil)
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, rep)
+			require.Equal(t, tc.attempts, attempts)
+			require.Equal(t, int64(tc.expected.All), finals)
+			require.LessOrEqual(t, maxAttempt, int64(tc.policy.MaxAttempts))
+		})
+	}
+
+	t.Run("retries stop when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		loader := New(time.Second, http.MethodGet, 1, 0, WithRetry(RetryPolicy{MaxAttempts: 100, Backoff: time.Hour, Statuses: []int{http.StatusInternalServerError}}))
+
+		start := time.Now()
+		rep, err := loader.Load(ctx, broken.URL, nil, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, Report{All: 1, Errors: 1, Retry: &RetryReport{Failed: 1}}, rep)
+		require.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,257 @@
+package httploader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// значения Sample.Method у запросов по сырому сокету
+const (
+	MethodTCP = "TCP"
+	MethodUDP = "UDP"
+)
+
+// udpMaxDatagram размер буфера для ответа UDP, больше не бывает
+const udpMaxDatagram = 64 << 10
+
+// Framing как выделить ответ из потока TCP
+// Delimiter - ответ заканчивается этой последовательностью байт,
+// LengthPrefix - ответ начинается с длины остальной части в LengthPrefix байтах big-endian (1, 2, 4 или 8)
+// без Delimiter и LengthPrefix ответом считается то, что вернуло одно чтение из соединения
+type Framing struct {
+	Delimiter    []byte
+	LengthPrefix int
+}
+
+// WithFraming способ выделить ответ TCP, на UDP не влияет: ответ UDP - одна датаграмма
+func WithFraming(f Framing) Option {
+	return func(l *consistent) {
+		l.framing = f
+	}
+}
+
+// socketLoader Loader, отправляющий тело запроса как есть по TCP или UDP
+type socketLoader struct {
+	consistent
+	network string
+	workers int
+}
+
+// NewSocket Loader для собственных протоколов поверх network ("tcp" или "udp")
+// c исполнителей отправляют requests запросов, у каждого исполнителя своё соединение, которое
+// открывается заново после ошибки, запрос - тело без изменений, ответ выделяется по WithFraming
+// Sample.Latency - время от отправки запроса до получения ответа целиком, timeOut ограничивает запрос с ответом
+func NewSocket(timeOut time.Duration, network string, requests, c int, opts ...Option) Loader {
+	l := consistent{
+		method:   map[string]string{"tcp": MethodTCP, "udp": MethodUDP}[network],
+		requests: requests,
+		timeout:  timeOut,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	if c < 1 {
+		c = 1
+	}
+
+	return &socketLoader{consistent: l, network: network, workers: c}
+}
+
+// Load отправляет body на host ("tcp://host:port", "udp://host:port" или "host:port"), заголовки не используются
+func (l *socketLoader) Load(ctx context.Context, host string, _ *http.Header, body []byte) (Report, error) {
+	addr := host
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		if u.Scheme != l.network {
+			return Report{}, fmt.Errorf("unsupported %s scheme %q", l.network, u.Scheme)
+		}
+		addr = u.Host
+	}
+
+	st := &socketStats{connStats: connStats{start: time.Now()}}
+	conns := make([]*socketConn, l.workers)
+	defer func() {
+		for _, conn := range conns {
+			conn.close()
+		}
+	}()
+
+	l.dispatch(ctx, l.workers, func(worker, _ int) Sample {
+		return l.request(addr, &conns[worker], body, worker, st)
+	}, &st.tally)
+
+	return st.report(time.Now()), nil
+}
+
+// request отправляет payload по соединению исполнителя, открывая его при необходимости
+// после ошибки TCP соединение закрывается, потому что в нём мог остаться недочитанный ответ
+func (l *socketLoader) request(addr string, conn **socketConn, payload []byte, worker int, st *socketStats) Sample {
+	s := Sample{Worker: worker, URL: l.network + "://" + addr, Method: l.method}
+
+	if *conn == nil {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		c, err := l.dial(ctx, l.network, addr)
+		cancel()
+		if err != nil {
+			st.connected(0, err)
+			s.Start, s.Latency = start, time.Since(start)
+			s.Err = &url.Error{Op: "Dial", URL: s.URL, Err: err}
+			return s
+		}
+		st.connected(time.Since(start), nil)
+		*conn = &socketConn{conn: c, br: bufio.NewReader(c)}
+	}
+	c := *conn
+
+	if c.stale {
+		c.drain()
+	}
+
+	s.Start, s.Addr, s.Source = time.Now(), c.conn.RemoteAddr().String(), ipOf(c.conn.LocalAddr())
+	c.conn.SetDeadline(s.Start.Add(l.timeout))
+	_, err := c.conn.Write(payload)
+	st.sent(len(payload))
+	if err == nil {
+		s.Bytes, err = l.readResponse(c)
+	}
+	s.Latency = time.Since(s.Start)
+	if err == nil {
+		st.received(s.Bytes)
+		return s
+	}
+
+	s.Err = &url.Error{Op: "Read", URL: s.URL, Err: err}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && l.network == "udp" {
+		// ответ мог прийти позже, перед следующим запросом его нужно выбросить
+		c.stale = true
+		st.lose()
+		return s
+	}
+	st.drop()
+	c.close()
+	*conn = nil
+
+	return s
+}
+
+// readResponse читает один ответ и возвращает его размер
+func (l *socketLoader) readResponse(c *socketConn) (int64, error) {
+	if l.network == "udp" {
+		n, err := c.conn.Read(make([]byte, udpMaxDatagram))
+		return int64(n), err
+	}
+
+	switch f := l.framing; {
+	case len(f.Delimiter) > 0:
+		var resp []byte
+		for !bytes.HasSuffix(resp, f.Delimiter) {
+			b, err := c.br.ReadByte()
+			if err != nil {
+				return int64(len(resp)), err
+			}
+			resp = append(resp, b)
+			if len(resp) > wsMaxMessage {
+				return int64(len(resp)), fmt.Errorf("response is larger than %d bytes", wsMaxMessage)
+			}
+		}
+		return int64(len(resp)), nil
+	case f.LengthPrefix > 0:
+		prefix := make([]byte, 8)
+		if _, err := io.ReadFull(c.br, prefix[8-f.LengthPrefix:]); err != nil {
+			return 0, err
+		}
+		n, err := io.CopyN(io.Discard, c.br, int64(binary.BigEndian.Uint64(prefix)))
+		return int64(f.LengthPrefix) + n, err
+	default:
+		n, err := c.br.Read(make([]byte, c.br.Size()))
+		return int64(n), err
+	}
+}
+
+// socketConn соединение исполнителя, используется только из его горутины
+// stale - в сокете UDP может лежать опоздавший ответ на предыдущий запрос
+type socketConn struct {
+	conn  net.Conn
+	br    *bufio.Reader
+	stale bool
+}
+
+// drain выбрасывает уже пришедшие опоздавшие ответы UDP
+func (c *socketConn) drain() {
+	c.conn.SetReadDeadline(time.Now())
+	buf := make([]byte, udpMaxDatagram)
+	for {
+		if _, err := c.conn.Read(buf); err != nil {
+			break
+		}
+	}
+	c.stale = false
+}
+
+func (c *socketConn) close() {
+	if c != nil {
+		c.conn.Close()
+	}
+}
+
+// socketStats статистика нагрузки по сокету, безопасна для нескольких горутин
+type socketStats struct {
+	connStats
+
+	mu                       sync.Mutex
+	requests, lost           int
+	bytesSent, bytesReceived int64
+}
+
+func (st *socketStats) sent(n int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.requests++
+	st.bytesSent += int64(n)
+}
+
+func (st *socketStats) received(n int64) {
+	st.connStats.received()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.bytesReceived += n
+}
+
+func (st *socketStats) lose() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.lost++
+}
+
+func (st *socketStats) report(now time.Time) Report {
+	rep := st.connStats.report(now)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	socket := &SocketReport{Sent: st.requests, Lost: st.lost, BytesSent: st.bytesSent, BytesReceived: st.bytesReceived}
+	if st.requests > 0 {
+		socket.LossRate = math.Round(float64(st.lost)/float64(st.requests)*10000) / 100
+	}
+	if elapsed := now.Sub(st.start); elapsed > 0 {
+		socket.Throughput = math.Round(float64(st.bytesSent+st.bytesReceived)/elapsed.Seconds()*100) / 100
+	}
+	rep.Socket = socket
+
+	return rep
+}
@@ -0,0 +1,210 @@
+package httploader
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tcpTestServer tcp сервер, который обрабатывает каждое соединение через handle
+func tcpTestServer(t *testing.T, handle func(conn net.Conn)) (addr string, stop func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	return lis.Addr().String(), func() { lis.Close() }
+}
+
+// udpTestServer udp сервер, отвечающий эхом на каждую датаграмму, кроме тех, что начинаются с "drop"
+func udpTestServer(t *testing.T) (addr string, stop func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		buf := make([]byte, udpMaxDatagram)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n >= 4 && string(buf[:4]) == "drop" {
+				continue
+			}
+			conn.WriteTo(buf[:n], from)
+		}
+	}()
+
+	return conn.LocalAddr().String(), func() { conn.Close() }
+}
+
+func TestSocketLoader(t *testing.T) {
+	type testCase struct {
+		name     string
+		handle   func(conn net.Conn)
+		payload  string
+		n, c     int
+		opts     []Option
+		expected Report
+		// received - ожидаемый размер каждого ответа
+		received int64
+	}
+
+	// lines отвечает на каждую строку двумя строками "ok\r\n", которые приходят отдельными записями
+	lines := func(conn net.Conn) {
+		br := bufio.NewReader(conn)
+		for {
+			if _, err := br.ReadString('\n'); err != nil {
+				return
+			}
+			conn.Write([]byte("ok\r"))
+			time.Sleep(time.Millisecond)
+			conn.Write([]byte("\nok\r\n"))
+		}
+	}
+	// prefixed отвечает на каждые 4 байта сообщением из 5 байт с длиной в 2 байтах
+	prefixed := func(conn net.Conn) {
+		for {
+			if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+				return
+			}
+			resp := make([]byte, 7)
+			binary.BigEndian.PutUint16(resp, 5)
+			conn.Write(resp[:3])
+			time.Sleep(time.Millisecond)
+			conn.Write(resp[3:])
+		}
+	}
+	silent := func(conn net.Conn) {
+		io.Copy(io.Discard, conn)
+	}
+	hangup := func(conn net.Conn) {
+		conn.Read(make([]byte, 4))
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "delimiter framing",
+			handle:   lines,
+			payload:  "ping\n",
+			n:        6,
+			c:        2,
+			opts:     []Option{WithFraming(Framing{Delimiter: []byte("\r\n")})},
+			expected: Report{All: 6, Success: 6, Connections: &ConnectionReport{Opened: 2, Messages: 6}},
+			received: 4,
+		},
+		{
+			name:     "length-prefixed framing",
+			handle:   prefixed,
+			payload:  "ping",
+			n:        4,
+			opts:     []Option{WithFraming(Framing{LengthPrefix: 2})},
+			expected: Report{All: 4, Success: 4, Connections: &ConnectionReport{Opened: 1, Messages: 4}},
+			received: 7,
+		},
+		{
+			name:     "response timeout drops connection",
+			handle:   silent,
+			payload:  "ping",
+			n:        2,
+			expected: Report{All: 2, Cancelled: 2, Connections: &ConnectionReport{Opened: 2, Dropped: 2}},
+		},
+		{
+			name:     "connection closed by server is reopened",
+			handle:   hangup,
+			payload:  "ping",
+			n:        3,
+			expected: Report{All: 3, Errors: 3, Connections: &ConnectionReport{Opened: 3, Dropped: 3}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, stop := tcpTestServer(t, tc.handle)
+			defer stop()
+
+			var (
+				mu       sync.Mutex
+				observed []Sample
+			)
+			opts := append([]Option{WithObserver(func(s Sample) {
+				mu.Lock()
+				observed = append(observed, s)
+				mu.Unlock()
+			})}, tc.opts...)
+			loader := NewSocket(50*time.Millisecond, "tcp", tc.n, tc.c, opts...)
+
+			rep, err := loader.Load(context.Background(), "tcp://"+addr, nil, []byte(tc.payload))
+			require.NoError(t, err)
+
+			socket := rep.Socket
+			require.Equal(t, tc.n, socket.Sent)
+			require.Equal(t, 0, socket.Lost)
+			require.Equal(t, int64(tc.n*len(tc.payload)), socket.BytesSent)
+			require.Equal(t, int64(tc.expected.Success)*tc.received, socket.BytesReceived)
+			require.Greater(t, socket.Throughput, 0.0)
+
+			rep.Socket, rep.AvgResponseTime = nil, 0
+			rep.Connections.ConnectTime, rep.Connections.MessageRate = 0, 0
+			require.Equal(t, tc.expected, rep)
+
+			require.Len(t, observed, tc.n)
+			for _, s := range observed {
+				require.Equal(t, MethodTCP, s.Method)
+				require.Equal(t, "tcp://"+addr, s.URL)
+				require.Equal(t, tc.received, s.Bytes)
+			}
+		})
+	}
+
+	t.Run("udp packet loss", func(t *testing.T) {
+		addr, stop := udpTestServer(t)
+		defer stop()
+
+		rep, err := NewSocket(50*time.Millisecond, "udp", 4, 2).Load(context.Background(), "udp://"+addr, nil, []byte("ping"))
+		require.NoError(t, err)
+		require.Equal(t, 4, rep.Success)
+		require.Equal(t, &SocketReport{Sent: 4, BytesSent: 16, BytesReceived: 16, Throughput: rep.Socket.Throughput}, rep.Socket)
+
+		rep, err = NewSocket(20*time.Millisecond, "udp", 3, 1).Load(context.Background(), addr, nil, []byte("drop"))
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Cancelled)
+		require.Equal(t, 0, rep.Connections.Dropped)
+		require.Equal(t, 1, rep.Connections.Opened)
+		require.Equal(t, 3, rep.Socket.Lost)
+		require.Equal(t, 100.0, rep.Socket.LossRate)
+	})
+
+	t.Run("unavailable server", func(t *testing.T) {
+		addr, stop := tcpTestServer(t, silent)
+		stop()
+
+		rep, err := NewSocket(50*time.Millisecond, "tcp", 2, 1).Load(context.Background(), addr, nil, []byte("ping"))
+		require.NoError(t, err)
+		require.Equal(t, 2, rep.Errors)
+		require.Equal(t, 2, rep.Connections.Failed)
+		require.Equal(t, 0, rep.Socket.Sent)
+	})
+
+	t.Run("scheme of another network", func(t *testing.T) {
+		_, err := NewSocket(time.Second, "tcp", 1, 1).Load(context.Background(), "udp://127.0.0.1:1", nil, nil)
+		require.EqualError(t, err, `unsupported tcp scheme "udp"`)
+	})
+}
@@ -0,0 +1,230 @@
+package httploader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+// errStreamIdle следующее событие потока не пришло за таймаут
+const errStreamIdle = timeoutError("stream idle timeout")
+
+// errStreamDropped сервер закрыл поток раньше WithStreamDuration
+var errStreamDropped = errors.New("stream closed by server")
+
+// WithStreamDuration сколько держать открытым каждый поток NewStream, без опции - пока его не закроет сервер
+func WithStreamDuration(d time.Duration) Option {
+	return func(l *consistent) {
+		l.streamDuration = d
+	}
+}
+
+// streamLoader Loader, читающий потоковые ответы из workers исполнителей
+type streamLoader struct {
+	consistent
+	workers int
+}
+
+// NewStream Loader для долгоживущих потоковых ответов (Server-Sent Events, NDJSON и другие построчные chunked ответы)
+// выполняет requests запросов из c исполнителей, каждый ответ читается как поток событий:
+// у text/event-stream событие - блок строк до пустой строки, комментарии не считаются событиями,
+// у остальных ответов событие - непустая строка
+// Sample.Latency - время до первого события или, если событий не было, длительность потока
+// timeOut ограничивает ожидание ответа и каждого следующего события, поток без событий дольше timeOut разрывается
+// разорванным считается поток, который закрыт сервером раньше WithStreamDuration или оборвался с ошибкой
+func NewStream(timeOut time.Duration, method string, requests, c int, opts ...Option) Loader {
+	l := consistent{
+		method:   method,
+		requests: requests,
+		timeout:  timeOut,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	if c < 1 {
+		c = 1
+	}
+
+	return &streamLoader{consistent: l, workers: c}
+}
+
+// Load открывает потоки к host и читает их, при прерывании контекстом закрывает открытые потоки
+// и не считает их разорванными
+func (l *streamLoader) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
+	if _, err := http.NewRequest(l.method, host, nil); err != nil {
+		return Report{}, fmt.Errorf("create request: %w", err)
+	}
+
+	st := &connStats{start: time.Now()}
+	l.dispatch(ctx, l.workers, func(worker, _ int) Sample {
+		return l.read(ctx, host, headers, body, worker, st)
+	}, &st.tally)
+
+	return st.report(time.Now()), nil
+}
+
+// read открывает один поток и читает его до конца
+func (l *streamLoader) read(ctx context.Context, host string, headers *http.Header, body []byte, worker int, st *connStats) Sample {
+	s := Sample{Worker: worker, URL: host, Method: l.method}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var idle, ended int32
+	idleTimer := time.AfterFunc(l.timeout, func() {
+		atomic.StoreInt32(&idle, 1)
+		cancel()
+	})
+	defer idleTimer.Stop()
+	if l.streamDuration > 0 {
+		endTimer := time.AfterFunc(l.streamDuration, func() {
+			atomic.StoreInt32(&ended, 1)
+			cancel()
+		})
+		defer endTimer.Stop()
+	}
+
+	req, err := http.NewRequestWithContext(streamCtx, l.method, host, bytes.NewReader(body))
+	if err != nil {
+		s.Start, s.Err = time.Now(), err
+		st.connected(0, err)
+		return s
+	}
+	if headers != nil {
+		req.Header = headers.Clone()
+	}
+	if l.auth != nil {
+		if err = l.authorize(req); err != nil {
+			s.Start, s.Err = time.Now(), err
+			st.connected(0, err)
+			return s
+		}
+	}
+
+	s.Start = time.Now()
+	cli := l.httpClient(0)
+	resp, err := cli.Do(req)
+	if err != nil {
+		s.Latency = time.Since(s.Start)
+		s.Err = err
+		if atomic.LoadInt32(&idle) == 1 {
+			s.Err = &url.Error{Op: "Get", URL: host, Err: errStreamIdle}
+		}
+		st.connected(0, err)
+		return s
+	}
+	defer resp.Body.Close()
+
+	s.Status = resp.StatusCode
+	if l.auth != nil {
+		l.unauthorized(req, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		s.Latency = time.Since(s.Start)
+		st.connected(0, fmt.Errorf("unexpected status %d", resp.StatusCode))
+		return s
+	}
+	st.connected(time.Since(s.Start), nil)
+
+	events := newEventReader(resp.Body, resp.Header.Get("Content-Type"))
+	last := s.Start
+	for {
+		n, err := events.next()
+		s.Bytes += n
+		if err != nil {
+			s.Err = l.streamErr(ctx, host, err, atomic.LoadInt32(&idle) == 1, atomic.LoadInt32(&ended) == 1)
+			break
+		}
+
+		now := time.Now()
+		idleTimer.Reset(l.timeout)
+		if s.Latency == 0 {
+			s.Latency = now.Sub(s.Start)
+		} else {
+			st.gap(now.Sub(last))
+		}
+		last = now
+		st.received()
+	}
+
+	if s.Latency == 0 {
+		s.Latency = time.Since(s.Start)
+	}
+	if s.Err != nil {
+		st.drop()
+	}
+
+	return s
+}
+
+// streamErr причина окончания потока, nil если поток закончился штатно:
+// истекло WithStreamDuration, нагрузка прервана или сервер закрыл поток, а длительность не задана
+func (l *streamLoader) streamErr(ctx context.Context, host string, err error, idle, ended bool) error {
+	switch {
+	case idle:
+		return &url.Error{Op: "Read", URL: host, Err: errStreamIdle}
+	case ended || ctx.Err() != nil:
+		return nil
+	case err == io.EOF && l.streamDuration == 0:
+		return nil
+	case err == io.EOF:
+		return errStreamDropped
+	default:
+		return err
+	}
+}
+
+// eventReader разбирает поток ответа на события
+type eventReader struct {
+	br  *bufio.Reader
+	sse bool
+}
+
+func newEventReader(r io.Reader, contentType string) *eventReader {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return &eventReader{br: bufio.NewReader(r), sse: mediaType == "text/event-stream"}
+}
+
+// next читает следующее событие и возвращает количество прочитанных байт
+func (r *eventReader) next() (int64, error) {
+	var (
+		read   int64
+		fields bool
+	)
+	for {
+		line, err := r.br.ReadBytes('\n')
+		read += int64(len(line))
+		line = bytes.TrimRight(line, "\r\n")
+		if err != nil {
+			// последняя строка без перевода строки тоже событие
+			if err == io.EOF && !r.sse && len(bytes.TrimSpace(line)) > 0 {
+				return read, nil
+			}
+			return read, err
+		}
+
+		if !r.sse {
+			if len(bytes.TrimSpace(line)) > 0 {
+				return read, nil
+			}
+			continue
+		}
+		if len(line) == 0 {
+			if fields {
+				return read, nil
+			}
+			continue
+		}
+		if line[0] != ':' {
+			fields = true
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package httploader
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamLoader(t *testing.T) {
+	type testCase struct {
+		name     string
+		handler  http.HandlerFunc
+		n, c     int
+		opts     []Option
+		expected Report
+		// gaps - есть ли перцентили времени между событиями
+		gaps bool
+	}
+
+	send := func(w http.ResponseWriter, event string) {
+		fmt.Fprint(w, event)
+		w.(http.Flusher).Flush()
+	}
+	sse := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+		send(w, ": keep-alive\n\n")
+		for i := 0; i < 3; i++ {
+			time.Sleep(5 * time.Millisecond)
+			send(w, fmt.Sprintf("event: tick\ndata: %d\n\n", i))
+		}
+	}
+	endless := func(w http.ResponseWriter, r *http.Request) {
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+				send(w, `{"event":"tick"}`+"\n")
+			}
+		}
+	}
+	closedEarly := func(w http.ResponseWriter, r *http.Request) {
+		send(w, `{"event":"tick"}`+"\n")
+	}
+	stalled := func(w http.ResponseWriter, r *http.Request) {
+		send(w, `{"event":"tick"}`+"\n")
+		<-r.Context().Done()
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "server-sent events until server closes the stream",
+			handler:  sse,
+			n:        4,
+			c:        2,
+			expected: Report{All: 4, Success: 4, Connections: &ConnectionReport{Opened: 4, Messages: 12}},
+			gaps:     true,
+		},
+		{
+			name:     "stream is kept open for stream duration",
+			handler:  endless,
+			n:        2,
+			opts:     []Option{WithStreamDuration(50 * time.Millisecond)},
+			expected: Report{All: 2, Success: 2, Connections: &ConnectionReport{Opened: 2}},
+			gaps:     true,
+		},
+		{
+			name:     "stream closed before stream duration is dropped",
+			handler:  closedEarly,
+			n:        2,
+			opts:     []Option{WithStreamDuration(time.Second)},
+			expected: Report{All: 2, Errors: 2, Connections: &ConnectionReport{Opened: 2, Dropped: 2, Messages: 2}},
+		},
+		{
+			name:     "stalled stream is dropped by timeout",
+			handler:  stalled,
+			n:        1,
+			expected: Report{All: 1, Cancelled: 1, Connections: &ConnectionReport{Opened: 1, Dropped: 1, Messages: 1}},
+		},
+		{
+			name:     "unexpected status",
+			handler:  func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			n:        2,
+			expected: Report{All: 2, Errors: 2, Connections: &ConnectionReport{Failed: 2}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := httptest.NewServer(tc.handler)
+			defer serv.Close()
+
+			var (
+				mu       sync.Mutex
+				observed []Sample
+			)
+			opts := append([]Option{WithObserver(func(s Sample) {
+				mu.Lock()
+				observed = append(observed, s)
+				mu.Unlock()
+			})}, tc.opts...)
+			loader := NewStream(50*time.Millisecond, http.MethodGet, tc.n, tc.c, opts...)
+
+			rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+			require.NoError(t, err)
+
+			conns := rep.Connections
+			require.Equal(t, tc.gaps, conns.InterEventP99 > 0)
+			require.GreaterOrEqual(t, conns.InterEventP99, conns.InterEventP50)
+			if tc.expected.Connections.Messages == 0 && tc.expected.Success > 0 {
+				// количество событий за время потока заранее неизвестно
+				require.Greater(t, conns.Messages, 2)
+				conns.Messages = 0
+			}
+			conns.ConnectTime, conns.MessageRate, conns.InterEventP50, conns.InterEventP99 = 0, 0, 0, 0
+			rep.AvgResponseTime = 0
+			require.Equal(t, tc.expected, rep)
+
+			require.Len(t, observed, tc.n)
+			for _, s := range observed {
+				require.Greater(t, s.Latency, time.Duration(0))
+			}
+		})
+	}
+
+	t.Run("interrupted load closes open streams without drops", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(endless))
+		defer serv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		rep, err := NewStream(time.Second, http.MethodGet, 10, 2).Load(ctx, serv.URL, nil, nil)
+
+		require.NoError(t, err)
+		require.Equal(t, 2, rep.All)
+		require.Equal(t, 2, rep.Success)
+		require.Equal(t, 0, rep.Connections.Dropped)
+	})
+}
+
+func TestEventReader(t *testing.T) {
+	type testCase struct {
+		name        string
+		contentType string
+		body        string
+		events      int
+	}
+
+	cases := [...]testCase{
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			body:        ": comment\n\nid: 1\ndata: a\ndata: b\n\r\n\n\nevent: x\r\ndata: c\r\n\r\n: ping\n\n",
+			events:      2,
+		},
+		{
+			name:        "unfinished server-sent event is not counted",
+			contentType: "text/event-stream",
+			body:        "data: a\n\ndata: b\n",
+			events:      1,
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			body:        "{\"a\":1}\n\n{\"a\":2}\r\n{\"a\":3}",
+			events:      3,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newEventReader(strings.NewReader(tc.body), tc.contentType)
+
+			var (
+				events int
+				read   int64
+			)
+			for {
+				n, err := r.next()
+				read += n
+				if err != nil {
+					break
+				}
+				events++
+			}
+
+			require.Equal(t, tc.events, events)
+			require.Equal(t, int64(len(tc.body)), read)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package httploader
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Delay возвращает очередную паузу, вызывается из нескольких горутин одновременно
+type Delay func() time.Duration
+
+// ConstantDelay всегда одна и та же пауза d
+func ConstantDelay(d time.Duration) Delay {
+	return func() time.Duration {
+		return d
+	}
+}
+
+// UniformDelay пауза, равномерно распределённая в [min, max]
+func UniformDelay(min, max time.Duration) Delay {
+	return func() time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(rand.Int63n(int64(max-min)+1))
+	}
+}
+
+// ExponentialDelay пауза с экспоненциальным распределением и средним mean, как у времени между действиями пользователей
+func ExponentialDelay(mean time.Duration) Delay {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
+
+// WithThinkTime добавляет паузу между ответом и следующим запросом одного исполнителя
+func WithThinkTime(d Delay) Option {
+	return func(l *consistent) {
+		l.thinkTime = d
+	}
+}
+
+// WithPacing задаёт интервал между началами запросов одного исполнителя
+// если запрос выполнялся дольше интервала, следующий отправляется сразу
+// вместе с WithThinkTime исполнитель ждёт большую из двух пауз
+func WithPacing(interval time.Duration) Option {
+	return func(l *consistent) {
+		l.pacing = interval
+	}
+}
+
+// pause ждёт перед следующим запросом исполнителя, выполнившего s, pacing отсчитывается от start - начала первой попытки
+// с WithBackpressure ждёт не меньше, чем просит сервер, это время учитывается в bp
+// возвращается раньше, если контекст отменён
+func (l *consistent) pause(ctx context.Context, start time.Time, s Sample, bp *backpressure) {
+	var d time.Duration
+	if l.pacing > 0 {
+		d = time.Until(start.Add(l.pacing))
+	}
+	if l.thinkTime != nil {
+		if think := l.thinkTime(); think > d {
+			d = think
+		}
+	}
+	bp.hold(ctx, s, d)
+}
+
+// reserve обещает исполнителю следующий запрос, если left - количество ещё не обещанных запросов - больше нуля
+// исполнитель без обещанного запроса не ждёт think time и pacing, иначе они удлиняли бы нагрузку
+func reserve(left *int64) bool {
+	for {
+		n := atomic.LoadInt64(left)
+		if n <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(left, n, n-1) {
+			return true
+		}
+	}
+}
+
+// sleep ждёт d или отмены контекста и возвращает, сколько прошло времени
+func sleep(ctx context.Context, d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return time.Since(start)
+	case <-timer.C:
+		return d
+	}
+}
@@ -0,0 +1,165 @@
+package httploader
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	require.Equal(t, time.Second, ConstantDelay(time.Second)())
+	require.Equal(t, time.Second, UniformDelay(time.Second, time.Second)())
+
+	var sum time.Duration
+	uniform, exp := UniformDelay(10*time.Millisecond, 20*time.Millisecond), ExponentialDelay(10*time.Millisecond)
+	for i := 0; i < 10000; i++ {
+		d := uniform()
+		require.GreaterOrEqual(t, d, 10*time.Millisecond)
+		require.LessOrEqual(t, d, 20*time.Millisecond)
+
+		d = exp()
+		require.GreaterOrEqual(t, d, time.Duration(0))
+		sum += d
+	}
+	require.InDelta(t, float64(10*time.Millisecond), float64(sum/10000), float64(time.Millisecond))
+}
+
+func TestThinkTimeAndPacing(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer serv.Close()
+
+	type testCase struct {
+		name string
+		c    int
+		opts []Option
+		min  time.Duration
+	}
+
+	cases := [...]testCase{
+		{
+			name: "consistent loader with think time",
+			opts: []Option{WithThinkTime(ConstantDelay(20 * time.Millisecond))},
+			min:  5 * 20 * time.Millisecond,
+		},
+		{
+			name: "consistent loader with pacing",
+			opts: []Option{WithPacing(20 * time.Millisecond)},
+			min:  5 * 20 * time.Millisecond,
+		},
+		{
+			name: "concurrency loader with pacing, each worker sends 3 requests",
+			c:    2,
+			opts: []Option{WithPacing(30 * time.Millisecond)},
+			min:  2 * 30 * time.Millisecond,
+		},
+		{
+			name: "pacing and think time, the longest pause wins",
+			opts: []Option{WithPacing(5 * time.Millisecond), WithThinkTime(ConstantDelay(20 * time.Millisecond))},
+			min:  5 * 20 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			loader := New(time.Second, http.MethodGet, 6, tc.c, tc.opts...)
+
+			start := time.Now()
+			rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+			elapsed := time.Since(start)
+
+			require.NoError(t, err)
+			require.Equal(t, Report{All: 6, Success: 6}, rep)
+			require.GreaterOrEqual(t, elapsed, tc.min)
+			require.Less(t, elapsed, tc.min+time.Second/2, "no pause after the last request")
+		})
+	}
+}
+
+func TestNoPauseAfterLastRequest(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	defer serv.Close()
+
+	cases := []struct {
+		name   string
+		loader Loader
+		min    time.Duration
+	}{
+		{
+			name:   "each concurrency worker sends one request",
+			loader: New(time.Second, http.MethodGet, 4, 4, WithPacing(time.Second)),
+		},
+		{
+			name:   "adaptive loader sends second request after pacing, limit is 1 in first window",
+			loader: New(time.Second, http.MethodGet, 2, 4, WithPacing(300*time.Millisecond), WithLatencyTarget(time.Second)),
+			min:    300 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			rep, err := tc.loader.Load(context.Background(), serv.URL, nil, nil)
+			elapsed := time.Since(start)
+
+			require.NoError(t, err)
+			require.Equal(t, rep.All, rep.Success)
+			require.GreaterOrEqual(t, elapsed, tc.min)
+			require.Less(t, elapsed, tc.min+200*time.Millisecond)
+		})
+	}
+}
+
+func TestPacingWithRetry(t *testing.T) {
+	// каждый нечётный запрос получает 503 и повторяется через 60 мс
+	var received int64
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&received, 1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer serv.Close()
+
+	for _, c := range []int{0, 1} {
+		var (
+			mu     sync.Mutex
+			starts []time.Time
+		)
+		loader := New(time.Second, http.MethodGet, 3, c, WithPacing(100*time.Millisecond), WithRetry(RetryPolicy{
+			MaxAttempts: 2,
+			Statuses:    []int{http.StatusServiceUnavailable},
+			Backoff:     60 * time.Millisecond,
+		}), WithObserver(func(s Sample) {
+			mu.Lock()
+			defer mu.Unlock()
+			if s.Attempt == 1 {
+				starts = append(starts, s.Start)
+			}
+		}))
+
+		rep, err := loader.Load(context.Background(), serv.URL, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, rep.Success)
+		require.Len(t, starts, 3)
+		for i := 1; i < len(starts); i++ {
+			interval := starts[i].Sub(starts[i-1])
+			require.GreaterOrEqual(t, interval, 100*time.Millisecond)
+			require.Less(t, interval, 150*time.Millisecond, "pacing is counted from the first attempt")
+		}
+	}
+}
+
+func TestPauseCancel(t *testing.T) {
+	l := consistent{thinkTime: ConstantDelay(time.Hour)}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	l.pause(ctx, start, Sample{Start: start}, nil)
+
+	require.Less(t, time.Since(start), time.Second)
+}
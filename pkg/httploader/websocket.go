@@ -0,0 +1,442 @@
+package httploader
+
+import (
+	"benchutil/pkg/histogram"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MethodWebSocket значение Sample.Method у сообщений WebSocket
+const MethodWebSocket = "WS"
+
+// WebSocketIDPlaceholder в шаблоне сообщения заменяется на уникальный id сообщения
+const WebSocketIDPlaceholder = "{{id}}"
+
+// errWSDropped сообщение не отправлено, потому что соединение было разорвано
+var errWSDropped = errors.New("websocket connection dropped")
+
+// timeoutError ответ не получен за таймаут у Loader с долгоживущими соединениями, классифицируется как ErrClassTimeout
+type timeoutError string
+
+func (e timeoutError) Error() string { return string(e) }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// errWSTimeout ответ на сообщение WebSocket не получен за таймаут
+const errWSTimeout = timeoutError("websocket response timeout")
+
+// WithIDField поле json ответа WebSocket, по которому ответ сопоставляется с сообщением, по умолчанию "id"
+func WithIDField(field string) Option {
+	return func(l *consistent) {
+		l.idField = field
+	}
+}
+
+// webSocket Loader, отправляющий сообщения по connections соединениям WebSocket
+type webSocket struct {
+	consistent
+	connections int
+}
+
+// NewWebSocket Loader, который открывает c соединений WebSocket (ws:// или wss://) и отправляет по ним requests сообщений
+// тело запроса - шаблон сообщения, WebSocketIDPlaceholder в нём заменяется на уникальный id сообщения,
+// ответ сопоставляется с сообщением по полю id json ответа, если в шаблоне нет WebSocketIDPlaceholder - по порядку
+// без WithPacing следующее сообщение соединения отправляется после ответа на предыдущее (и паузы WithThinkTime),
+// с WithPacing сообщения отправляются с этим интервалом, не дожидаясь ответов
+// timeOut ограничивает установление соединения и ожидание ответа на сообщение
+func NewWebSocket(timeOut time.Duration, requests, c int, opts ...Option) Loader {
+	l := consistent{
+		method:   MethodWebSocket,
+		requests: requests,
+		timeout:  timeOut,
+		idField:  "id",
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+
+	if c < 1 {
+		c = 1
+	}
+
+	return &webSocket{consistent: l, connections: c}
+}
+
+// Load открывает соединения с host и отправляет по ним сообщения из шаблона body
+// при прерывании контекстом перестаёт отправлять сообщения и ждёт ответов на уже отправленные
+func (l *webSocket) Load(ctx context.Context, host string, headers *http.Header, body []byte) (Report, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return Report{}, fmt.Errorf("parse url: %w", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return Report{}, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+
+	st := &connStats{start: time.Now()}
+	wg := sync.WaitGroup{}
+	for w := 0; w < l.connections; w++ {
+		count := l.requests / l.connections
+		if w < l.requests%l.connections {
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(worker, count int) {
+			defer wg.Done()
+			l.session(ctx, host, headers, body, worker, count, st)
+		}(w, count)
+	}
+	wg.Wait()
+
+	return st.report(time.Now()), nil
+}
+
+// session открывает соединение worker и отправляет по нему count сообщений
+// сообщения, которые не удалось отправить из-за ошибки соединения, считаются неуспешными
+func (l *webSocket) session(ctx context.Context, host string, headers *http.Header, template []byte, worker, count int, st *connStats) {
+	finish := func(s Sample) {
+		l.observe(s)
+		st.add(s)
+	}
+	failRest := func(from int, err error) {
+		for i := from; i < count; i++ {
+			finish(Sample{Start: time.Now(), Worker: worker, URL: host, Method: l.method, Err: err})
+		}
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, l.timeout)
+	start := time.Now()
+	conn, err := dialWS(dialCtx, l.dial, host, headers)
+	cancel()
+	if err != nil {
+		st.connected(0, err)
+		failRest(0, &url.Error{Op: "Dial", URL: host, Err: err})
+		return
+	}
+	st.connected(time.Since(start), nil)
+
+	byID := bytes.Contains(template, []byte(WebSocketIDPlaceholder))
+	p := newWSPending(l.idField, byID, finish)
+	readDone := make(chan struct{})
+	go func() {
+		p.read(conn, st)
+		close(readDone)
+	}()
+	// после закрытия ждём читателя, чтобы ни один результат не пришёл после завершения Load
+	defer func() {
+		p.closing()
+		conn.close()
+		<-readDone
+	}()
+
+	var last Sample
+	for i := 0; i < count; i++ {
+		if i > 0 && l.pacing == 0 {
+			p.wait(last.Start.Add(l.timeout))
+			p.expire(time.Now(), l.timeout)
+			l.pause(ctx, last.Start, last, nil)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		if p.dropped() {
+			failRest(i, errWSDropped)
+			break
+		}
+
+		id := strconv.Itoa(worker) + "-" + strconv.Itoa(i)
+		last = Sample{Start: time.Now(), Worker: worker, URL: host, Method: l.method}
+		p.add(id, last)
+		if err = conn.writeMessage(bytes.ReplaceAll(template, []byte(WebSocketIDPlaceholder), []byte(id))); err != nil {
+			p.fail(err, st)
+			continue
+		}
+
+		if l.pacing > 0 {
+			p.expire(time.Now(), l.timeout)
+			sleep(ctx, time.Until(last.Start.Add(l.pacing)))
+		}
+	}
+
+	p.wait(last.Start.Add(l.timeout))
+	p.expire(time.Now(), 0)
+}
+
+// wsPending сообщения соединения, ожидающие ответа
+type wsPending struct {
+	mu sync.Mutex
+
+	idField string
+	byID    bool
+	samples map[string]Sample
+	order   []string
+	err     error
+	stopped bool
+
+	answered chan struct{}
+	finish   func(s Sample)
+}
+
+func newWSPending(idField string, byID bool, finish func(s Sample)) *wsPending {
+	return &wsPending{
+		idField:  idField,
+		byID:     byID,
+		samples:  make(map[string]Sample),
+		answered: make(chan struct{}, 1),
+		finish:   finish,
+	}
+}
+
+func (p *wsPending) add(id string, s Sample) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.samples[id] = s
+	p.order = append(p.order, id)
+}
+
+// read читает ответы, пока соединение не закроется
+func (p *wsPending) read(conn *wsConn, st *connStats) {
+	for {
+		msg, err := conn.readMessage()
+		if err != nil {
+			p.fail(err, st)
+			return
+		}
+		st.received()
+		p.answer(msg)
+	}
+}
+
+// answer завершает сообщение, на которое пришёл ответ msg, сообщения от сервера без запроса пропускаются
+func (p *wsPending) answer(msg []byte) {
+	p.mu.Lock()
+	id, ok := p.match(msg)
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	s := p.samples[id]
+	p.remove(id)
+	p.mu.Unlock()
+
+	s.Latency = time.Since(s.Start)
+	s.Bytes = int64(len(msg))
+	p.finish(s)
+	p.signal()
+}
+
+// match id сообщения, на которое отвечает msg, вызывается под мьютексом
+func (p *wsPending) match(msg []byte) (string, bool) {
+	if !p.byID {
+		if len(p.order) == 0 {
+			return "", false
+		}
+		return p.order[0], true
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(msg, &fields); err != nil {
+		return "", false
+	}
+	id, ok := fields[p.idField].(string)
+	if !ok {
+		return "", false
+	}
+	_, ok = p.samples[id]
+
+	return id, ok
+}
+
+// remove вызывается под мьютексом
+func (p *wsPending) remove(id string) {
+	delete(p.samples, id)
+	for i, pending := range p.order {
+		if pending == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// fail завершает все ожидающие сообщения с ошибкой err, соединение считается разорванным,
+// если его закрыл не сам Loader
+func (p *wsPending) fail(err error, st *connStats) {
+	p.mu.Lock()
+	if p.err != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.err = err
+	if !p.stopped {
+		st.drop()
+	}
+	failed := p.take(func(Sample) bool { return true })
+	p.mu.Unlock()
+
+	for _, s := range failed {
+		s.Latency = time.Since(s.Start)
+		s.Err = err
+		p.finish(s)
+	}
+	p.signal()
+}
+
+// expire завершает с ошибкой таймаута сообщения, которые к моменту now ждут ответа не меньше timeout
+func (p *wsPending) expire(now time.Time, timeout time.Duration) {
+	p.mu.Lock()
+	expired := p.take(func(s Sample) bool { return now.Sub(s.Start) >= timeout })
+	p.mu.Unlock()
+
+	for _, s := range expired {
+		s.Latency = time.Since(s.Start)
+		s.Err = &url.Error{Op: "Read", URL: s.URL, Err: errWSTimeout}
+		p.finish(s)
+	}
+}
+
+// take убирает и возвращает ожидающие сообщения, для которых выполняется cond, вызывается под мьютексом
+func (p *wsPending) take(cond func(s Sample) bool) []Sample {
+	var taken []Sample
+	order := p.order[:0]
+	for _, id := range p.order {
+		if s := p.samples[id]; cond(s) {
+			taken = append(taken, s)
+			delete(p.samples, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	p.order = order
+
+	return taken
+}
+
+// wait ждёт ответов на все отправленные сообщения, но не дольше deadline
+func (p *wsPending) wait(deadline time.Time) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	for {
+		p.mu.Lock()
+		done := len(p.samples) == 0 || p.err != nil
+		p.mu.Unlock()
+		if done {
+			return
+		}
+
+		select {
+		case <-p.answered:
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+func (p *wsPending) signal() {
+	select {
+	case p.answered <- struct{}{}:
+	default:
+	}
+}
+
+// closing отмечает, что соединение закрывает Loader, ошибка чтения после этого не считается разрывом
+func (p *wsPending) closing() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopped = true
+}
+
+func (p *wsPending) dropped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.err != nil
+}
+
+// connStats статистика нагрузки с долгоживущими соединениями (WebSocket, потоковые ответы), безопасна для нескольких горутин
+// gaps - время между сообщениями одного соединения, собирается только у потоковых ответов
+type connStats struct {
+	tally
+
+	mu    sync.Mutex
+	start time.Time
+
+	opened, failed, dropped int
+	connectTime             time.Duration
+	messages                int
+	gaps                    *histogram.Histogram
+}
+
+func (st *connStats) connected(d time.Duration, err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if err != nil {
+		st.failed++
+		return
+	}
+	st.opened++
+	st.connectTime += d
+}
+
+func (st *connStats) drop() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.dropped++
+}
+
+func (st *connStats) received() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.messages++
+}
+
+// gap учитывает время между двумя сообщениями одного соединения
+func (st *connStats) gap(d time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.gaps == nil {
+		st.gaps = histogram.New()
+	}
+	st.gaps.Record(d)
+}
+
+func (st *connStats) report(now time.Time) Report {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	conns := &ConnectionReport{Opened: st.opened, Failed: st.failed, Dropped: st.dropped, Messages: st.messages}
+	if st.opened > 0 {
+		conns.ConnectTime = st.connectTime / time.Duration(st.opened)
+	}
+	if elapsed := now.Sub(st.start); elapsed > 0 {
+		conns.MessageRate = math.Round(float64(st.messages)/elapsed.Seconds()*100) / 100
+	}
+	if st.gaps != nil {
+		conns.InterEventP50 = st.gaps.Quantile(0.5)
+		conns.InterEventP99 = st.gaps.Quantile(0.99)
+	}
+
+	rep := st.tally.report()
+	rep.Connections = conns
+
+	return rep
+}
@@ -0,0 +1,239 @@
+package httploader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wsTestServer WebSocket сервер, reply получает сообщение клиента и отправляет ответы через send
+// если reply возвращает false, сервер разрывает соединение
+func wsTestServer(t *testing.T, reply func(msg []byte, send func(payload []byte)) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		conn, rw, err := writer.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(request.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		rw.Flush()
+
+		var mu sync.Mutex
+		send := func(payload []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			writeWSFrame(conn, wsText, payload, false)
+		}
+		for {
+			_, opcode, payload, err := readWSFrame(rw)
+			if err != nil || opcode == wsClose {
+				return
+			}
+			if !reply(payload, send) {
+				return
+			}
+		}
+	}))
+}
+
+func echo(msg []byte, send func(payload []byte)) bool {
+	send(msg)
+	return true
+}
+
+func wsURL(serv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(serv.URL, "http")
+}
+
+func TestWebSocketLoader(t *testing.T) {
+	type testCase struct {
+		name     string
+		reply    func(msg []byte, send func(payload []byte)) bool
+		closed   bool
+		template string
+		n, c     int
+		opts     []Option
+		expected Report
+	}
+
+	var (
+		mu       sync.Mutex
+		received int
+	)
+	dropAfterThree := func(msg []byte, send func(payload []byte)) bool {
+		mu.Lock()
+		received++
+		last := received > 3
+		mu.Unlock()
+		if last {
+			return false
+		}
+		return echo(msg, send)
+	}
+	pushAndEcho := func(msg []byte, send func(payload []byte)) bool {
+		send([]byte(`{"event":"push"}`))
+		return echo(msg, send)
+	}
+	delayed := func(msg []byte, send func(payload []byte)) bool {
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			send(msg)
+		}()
+		return true
+	}
+
+	cases := [...]testCase{
+		{
+			name:     "responses are matched by id",
+			reply:    pushAndEcho,
+			template: `{"id":"{{id}}","text":"hello"}`,
+			n:        10,
+			c:        2,
+			expected: Report{All: 10, Success: 10, Connections: &ConnectionReport{Opened: 2, Messages: 20}},
+		},
+		{
+			name:     "responses are matched in order without id in template",
+			reply:    echo,
+			template: "ping",
+			n:        5,
+			expected: Report{All: 5, Success: 5, Connections: &ConnectionReport{Opened: 1, Messages: 5}},
+		},
+		{
+			name:     "messages are sent with pacing without waiting for responses",
+			reply:    delayed,
+			template: `{"id":"{{id}}"}`,
+			n:        4,
+			opts:     []Option{WithPacing(5 * time.Millisecond)},
+			expected: Report{All: 4, Success: 4, Connections: &ConnectionReport{Opened: 1, Messages: 4}},
+		},
+		{
+			name:     "no responses",
+			reply:    func([]byte, func([]byte)) bool { return true },
+			template: `{"id":"{{id}}"}`,
+			n:        2,
+			expected: Report{All: 2, Cancelled: 2, Connections: &ConnectionReport{Opened: 1}},
+		},
+		{
+			name:     "server drops connection",
+			reply:    dropAfterThree,
+			template: `{"id":"{{id}}"}`,
+			n:        6,
+			expected: Report{All: 6, Success: 3, Errors: 3, Connections: &ConnectionReport{Opened: 1, Dropped: 1, Messages: 3}},
+		},
+		{
+			name:     "connection refused",
+			reply:    echo,
+			closed:   true,
+			n:        4,
+			c:        2,
+			expected: Report{All: 4, Errors: 4, Connections: &ConnectionReport{Failed: 2}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			serv := wsTestServer(t, tc.reply)
+			defer serv.Close()
+			if tc.closed {
+				serv.Close()
+			}
+
+			var observed []Sample
+			var obsMu sync.Mutex
+			obs := func(s Sample) {
+				obsMu.Lock()
+				observed = append(observed, s)
+				obsMu.Unlock()
+			}
+			opts := append([]Option{WithObserver(obs)}, tc.opts...)
+			loader := NewWebSocket(100*time.Millisecond, tc.n, tc.c, opts...)
+
+			rep, err := loader.Load(context.Background(), wsURL(serv), nil, []byte(tc.template))
+			require.NoError(t, err)
+
+			if rep.Connections.Opened > 0 {
+				require.Greater(t, rep.Connections.ConnectTime, time.Duration(0))
+			}
+			rep.Connections.ConnectTime, rep.Connections.MessageRate = 0, 0
+			require.Equal(t, tc.expected, rep)
+
+			require.Len(t, observed, tc.n)
+			for _, s := range observed {
+				require.Equal(t, MethodWebSocket, s.Method)
+				if s.ErrorClass() == ErrClassNone {
+					require.Greater(t, s.Latency, time.Duration(0))
+					require.Greater(t, s.Bytes, int64(0))
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := NewWebSocket(time.Second, 1, 1).Load(context.Background(), "http://host", nil, nil)
+		require.EqualError(t, err, `unsupported websocket scheme "http"`)
+	})
+}
+
+func TestWSConn(t *testing.T) {
+	t.Run("frames of all length encodings", func(t *testing.T) {
+		for _, size := range []int{0, 125, 126, 70000} {
+			for _, masked := range []bool{true, false} {
+				payload := bytes.Repeat([]byte("a"), size)
+				var buf bytes.Buffer
+
+				require.NoError(t, writeWSFrame(&buf, wsText, payload, masked))
+				fin, opcode, got, err := readWSFrame(&buf)
+
+				require.NoError(t, err)
+				require.True(t, fin)
+				require.Equal(t, byte(wsText), opcode)
+				require.Equal(t, payload, got)
+			}
+		}
+	})
+
+	t.Run("ping is answered and fragments are joined", func(t *testing.T) {
+		client, server := net.Pipe()
+		defer client.Close()
+		defer server.Close()
+		conn := &wsConn{conn: client, br: bufio.NewReader(client)}
+
+		go func() {
+			writeWSFrame(server, wsPing, []byte("p"), false)
+			// первый фрагмент без FIN
+			server.Write([]byte{wsText, 2, 'h', 'e'})
+			writeWSFrame(server, 0, []byte("llo"), false)
+		}()
+		pong := make(chan []byte, 1)
+		go func() {
+			_, opcode, payload, _ := readWSFrame(server)
+			pong <- append([]byte{opcode}, payload...)
+		}()
+
+		msg, err := conn.readMessage()
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(msg))
+		require.Equal(t, []byte{wsPong, 'p'}, <-pong)
+	})
+
+	t.Run("json ids", func(t *testing.T) {
+		p := newWSPending("requestId", true, func(Sample) {})
+		p.add("0-1", Sample{})
+
+		_, ok := p.match([]byte(`{"id":"0-1"}`))
+		require.False(t, ok)
+		msg, _ := json.Marshal(map[string]string{"requestId": "0-1"})
+		id, ok := p.match(msg)
+		require.True(t, ok)
+		require.Equal(t, "0-1", id)
+	})
+}
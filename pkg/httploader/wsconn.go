@@ -0,0 +1,270 @@
+package httploader
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// опкоды кадров WebSocket (RFC 6455)
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsAcceptGUID добавляется к Sec-WebSocket-Key при вычислении Sec-WebSocket-Accept
+const wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsMaxMessage ограничение размера сообщения, чтобы сломанный сервер не исчерпал память
+const wsMaxMessage = 64 << 20
+
+// errWSClosed соединение закрыто сервером кадром close
+var errWSClosed = errors.New("websocket closed by server")
+
+// wsConn клиентское WebSocket соединение
+// readMessage вызывается из одной горутины, writeMessage безопасен для нескольких горутин
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu sync.Mutex
+}
+
+// dialWS устанавливает соединение с rawURL (ws:// или wss://) через dial и выполняет handshake
+func dialWS(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), rawURL string, headers *http.Header) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), map[string]string{"ws": "80", "wss": "443"}[u.Scheme])
+	}
+
+	scheme := "http"
+	if u.Scheme == "wss" {
+		scheme = "https"
+	}
+	conn, err := dial(withProxyScheme(ctx, scheme), "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	ws, err := handshakeWS(conn, u, headers)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	return ws, nil
+}
+
+func handshakeWS(conn net.Conn, u *url.URL, headers *http.Header) (*wsConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	scheme := map[string]string{"ws": "http", "wss": "https"}[u.Scheme]
+	req, err := http.NewRequest(http.MethodGet, scheme+"://"+u.Host+u.RequestURI(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if headers != nil {
+		req.Header = headers.Clone()
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err = req.Write(conn); err != nil {
+		return nil, fmt.Errorf("write handshake: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("read handshake: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("unexpected handshake status %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		return nil, errors.New("invalid Sec-WebSocket-Accept")
+	}
+
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeMessage отправляет текстовое сообщение одним кадром, клиентские кадры маскируются
+func (c *wsConn) writeMessage(payload []byte) error {
+	return c.writeFrame(wsText, payload)
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return writeWSFrame(c.conn, opcode, payload, true)
+}
+
+// readMessage читает следующее сообщение с данными, отвечает на ping и собирает фрагменты
+func (c *wsConn) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := readWSFrame(c.br)
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsPing:
+			if err = c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			return nil, errWSClosed
+		}
+
+		message = append(message, payload...)
+		if len(message) > wsMaxMessage {
+			return nil, fmt.Errorf("message is larger than %d bytes", wsMaxMessage)
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// close отправляет кадр close и закрывает соединение, не дожидаясь ответа сервера
+func (c *wsConn) close() error {
+	c.writeFrame(wsClose, nil)
+	return c.conn.Close()
+}
+
+// writeWSFrame пишет кадр с флагом FIN, masked обязателен для кадров клиента
+func writeWSFrame(w io.Writer, opcode byte, payload []byte, masked bool) error {
+	header := []byte{0x80 | opcode, 0}
+	var maskBit byte
+	if masked {
+		maskBit = 0x80
+	}
+
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = maskBit | byte(n)
+	case n <= 0xFFFF:
+		header[1] = maskBit | 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = maskBit | 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	data := payload
+	if masked {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		header = append(header, mask...)
+		data = make([]byte, len(payload))
+		for i := range payload {
+			data[i] = payload[i] ^ mask[i%4]
+		}
+	}
+
+	if _, err := w.Write(append(header, data...)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// readWSFrame читает один кадр и снимает маску, если она есть
+func readWSFrame(r io.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(r, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0F
+	masked := header[1]&0x80 != 0
+
+	n := uint64(header[1] & 0x7F)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext)
+	}
+	if n > wsMaxMessage {
+		return false, 0, nil, fmt.Errorf("frame is larger than %d bytes", wsMaxMessage)
+	}
+
+	var mask []byte
+	if masked {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(r, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, opcode, payload, nil
+}